@@ -7,14 +7,35 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"ticketmaster/internals/apikeys"
+	"ticketmaster/internals/audit"
+	"ticketmaster/internals/availability"
 	"ticketmaster/internals/bookings"
 	"ticketmaster/internals/cache"
+	"ticketmaster/internals/checkins"
 	database "ticketmaster/internals/db"
+	"ticketmaster/internals/events"
+	"ticketmaster/internals/ga"
+	"ticketmaster/internals/keyring"
+	"ticketmaster/internals/ledger"
+	"ticketmaster/internals/mailer"
 	authMiddleware "ticketmaster/internals/middleware"
 	"ticketmaster/internals/notifications"
+	"ticketmaster/internals/oidc"
+	"ticketmaster/internals/organizations"
+	"ticketmaster/internals/payments"
+	"ticketmaster/internals/pricing"
+	"ticketmaster/internals/promotions"
+	"ticketmaster/internals/refunds"
 	"ticketmaster/internals/seats"
+	"ticketmaster/internals/tickets"
+	"ticketmaster/internals/transfers"
 	"ticketmaster/internals/users"
+	"ticketmaster/internals/validation"
+	"ticketmaster/internals/venues"
 	"time"
 
 	"github.com/go-chi/chi/v5"            // Import Chi
@@ -33,7 +54,12 @@ func main() {
 		os.Getenv("DB_PORT"),
 		os.Getenv("DB_NAME"),
 	)
-	jwtKey := os.Getenv("MY_JWT_KEY")
+	// Signing keys: PEM files in JWT_KEYS_DIR, JWT_ACTIVE_KID picks the one we sign with.
+	// MY_JWT_KEY (the old shared secret) stays valid for verification during the migration.
+	keys, err := keyring.Load(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_ACTIVE_KID"), os.Getenv("MY_JWT_KEY"))
+	if err != nil {
+		log.Fatalf("cannot load JWT keys: %v", err)
+	}
 
 	redisAddr := os.Getenv("REDIS_ADDR")
 	if redisAddr == "" {
@@ -42,11 +68,6 @@ func main() {
 	redisPassword := os.Getenv("REDIS_PASSWORD")
 	hub := notifications.NewHub()
 	go hub.Run()
-	tokenMiddleware, ok := authMiddleware.NewMiddleware(jwtKey)
-
-	if ok != nil {
-		log.Fatal("Error in setting up middleware")
-	}
 	db, err := database.NewDatabase(dsn)
 	if err != nil {
 		log.Fatalf("cannot connect to database: %v", err)
@@ -55,29 +76,117 @@ func main() {
 	redisStore := cache.NewRedisStore(redisAddr, redisPassword)
 	log.Println("✅ Connected to Redis")
 
+	apiKeyRepo := apikeys.NewRepository(db)
+	apiKeyHandler := apikeys.NewHandler(apiKeyRepo)
+	orgRepo := organizations.NewRepository(db)
+	orgHandler := organizations.NewHandler(orgRepo)
+
+	tokenMiddleware, ok := authMiddleware.NewMiddleware(keys, redisStore, apiKeyRepo)
+	if ok != nil {
+		log.Fatal("Error in setting up middleware")
+	}
+
 	// --- Services ---
 	seatRepo := seats.NewRepository(db)
-	seatHandler := seats.NewHandler(seatRepo)
 
-	bookingRepo := bookings.NewRepository(db)
-	bookingHandler := bookings.NewHandler(bookingRepo, redisStore, hub)
+	venueRepo := venues.NewRepository(db)
+	venueHandler := venues.NewHandler(venueRepo, redisStore)
+
+	ledgerRepo := ledger.NewRepository(db)
+	ledgerHandler := ledger.NewHandler(ledgerRepo)
+
+	refundRepo := refunds.NewRepository(db, ledgerRepo)
+	refundProcessor := refunds.NewProcessor(refundRepo, payments.NewSimulatedGateway(), hub)
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go refundProcessor.Run(jobsCtx) // Resumes any refunds left over from a previous run
+
+	availabilityService := availability.NewService(availability.NewRepository(db), redisStore)
+	availabilityHandler := availability.NewHandler(availabilityService)
+	go availabilityService.Run(jobsCtx) // Builds upcoming events' bitmaps, then reconciles them
+
+	eventRepo := events.NewRepository(db, refundRepo)
+	eventHandler := events.NewHandler(eventRepo, refundRepo, refundProcessor, hub, redisStore)
+	seatHandler := seats.NewHandler(seatRepo, redisStore, eventRepo.EventOrganization)
+
+	pricingRepo := pricing.NewRepository(db)
+	pricingHandler := pricing.NewHandler(pricingRepo)
+
+	promoRepo := promotions.NewRepository(db)
+	promoService := promotions.NewService(promoRepo, redisStore)
+	promoHandler := promotions.NewHandler(promoRepo, eventRepo.EventOrganization)
+
+	bookingRepo := bookings.NewRepository(db, ledgerRepo, pricingRepo, promoRepo)
+	bookingHandler := bookings.NewHandler(bookingRepo, redisStore, hub, promoService)
+
+	gaRepo := ga.NewRepository(db, ledgerRepo, pricingRepo, promoRepo)
+	gaHandler := ga.NewHandler(gaRepo, redisStore, hub, promoService)
+
+	ticketSigner, err := loadTicketSigner()
+	if err != nil {
+		log.Fatalf("cannot load ticket keys: %v", err)
+	}
+	ticketRepo := tickets.NewRepository(db, ticketSigner)
+	ticketHandler := tickets.NewHandler(ticketRepo)
+	checkinHandler := checkins.NewHandler(checkins.NewRepository(db), ticketSigner, hub)
 
 	userRepo := users.NewRepository(db)
-	userService := users.NewService(userRepo, jwtKey) // <--- The new layer
+	mail, err := newMailer()
+	if err != nil {
+		log.Fatalf("cannot set up mailer: %v", err)
+	}
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:8080" // Fallback
+	}
+	policy, err := passwordPolicy()
+	if err != nil {
+		log.Fatalf("cannot set up password policy: %v", err)
+	}
+	auditRepo := audit.NewRepository(db)
+	userService, err := users.NewService(userRepo, keys, redisStore, mail, appURL, policy, auditRepo, oidcProviders()) // <--- The new layer
+	if err != nil {
+		log.Fatalf("cannot set up user service: %v", err)
+	}
 	userHandler := users.NewHandler(userService)
 
+	transferHandler := transfers.NewHandler(transfers.NewRepository(db), redisStore, mail, appURL)
+
+	// Only these may tell us the client address (X-Real-IP / X-Forwarded-For), e.g. "10.0.0.0/8"
+	trustedProxies, err := authMiddleware.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("cannot parse TRUSTED_PROXIES: %v", err)
+	}
+
 	// --- Chi Router ---
 	r := chi.NewRouter()
 
 	// 1. Middleware (The reason Chi wins)
-	r.Use(middleware.Logger)    // Log every request automatically
-	r.Use(middleware.Recoverer) // Don't crash if a handler panics
-	r.Post("/admin/seats", seatHandler.CreateSeat)
+	r.Use(authMiddleware.RealIP(trustedProxies)) // nginx sends the client address in X-Real-IP; login throttling keys on it
+	r.Use(middleware.Logger)                     // Log every request automatically
+	r.Use(middleware.Recoverer)                  // Don't crash if a handler panics
 	r.Post("/register", userHandler.Register)
 	r.Post("/login", userHandler.Login)
+	r.Post("/login/mfa", userHandler.LoginMFA)
+	r.Get("/auth/oidc/{provider}/login", userHandler.OIDCLogin)
+	r.Get("/auth/oidc/{provider}/callback", userHandler.OIDCCallback)
+	r.Post("/token/refresh", userHandler.Refresh)
+	r.Post("/verify-email", userHandler.VerifyEmail)
+	r.Post("/password/forgot", userHandler.ForgotPassword)
+	r.Post("/password/reset", userHandler.ResetPassword)
 
 	// 2. Routes (Clean Grouping)
 	r.Get("/seats", seatHandler.GetSeats)
+	r.Get("/seats/{id}/price", pricingHandler.GetSeatPrice)
+	r.Get("/events", eventHandler.GetEvents)
+	r.Get("/events/{id}/tiers", pricingHandler.GetTiers)
+	r.Get("/events/{id}/ga-types", gaHandler.GetTypes)
+	r.Get("/events/{id}/seatmap", venueHandler.GetSeatmap)
+	r.Get("/events/{id}/availability", availabilityHandler.GetAvailability)
+	r.Get("/venues", venueHandler.GetVenues)
+	r.Get("/venues/{id}", venueHandler.GetVenue)
+	r.Get("/.well-known/jwks.json", keys.ServeJWKS)
+	r.Get("/tickets/keys", ticketSigner.ServeKeys)
 	r.Get("/ws", func(w http.ResponseWriter, r *http.Request) {
 		hub.ServeWs(w, r)
 	})
@@ -85,12 +194,84 @@ func main() {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("alive"))
 	})
+	r.Group(func(r chi.Router) {
+		// Back office: API keys only (make the first with cmd/apikey). admin opens every route
+		// for the key's own organization; the narrower scopes are for keys that do one job, such
+		// as a box office's seat import. platform keys are the operator's and reach every organization.
+		r.Use(tokenMiddleware.Auth)
+		r.Use(authMiddleware.MarkOperator(apikeys.ScopePlatform))
+		platform := authMiddleware.RequireAPIKeyScope(apikeys.ScopePlatform)
+		admin := authMiddleware.RequireAPIKeyScope(apikeys.ScopeAdmin)
+		eventsWrite := authMiddleware.RequireAPIKeyScope(apikeys.ScopeEventsWrite, apikeys.ScopeAdmin)
+		seatsWrite := authMiddleware.RequireAPIKeyScope(apikeys.ScopeSeatsWrite, apikeys.ScopeAdmin)
+		refundsRead := authMiddleware.RequireAPIKeyScope(apikeys.ScopeRefundsRead, apikeys.ScopeAdmin)
+		ownEvent := authMiddleware.RequireOwner("id", eventRepo.EventOrganization)
+		ownTier := authMiddleware.RequireOwner("id", pricingRepo.TierOrganization)
+		ownOrg := authMiddleware.RequireOwner("id", organizationItself)
+
+		r.With(platform).Get("/admin/ledger/reconciliation", ledgerHandler.GetReconciliation)
+		r.With(platform).Post("/admin/venues", venueHandler.CreateVenue)
+		r.With(platform).Post("/admin/organizations", orgHandler.CreateOrganization)
+		r.With(platform).Get("/admin/organizations", orgHandler.GetOrganizations)
+		r.With(platform).Put("/admin/events/{id}/organization", eventHandler.SetOrganization)
+
+		r.With(seatsWrite).Post("/admin/seats", seatHandler.CreateSeat)
+		r.With(eventsWrite).Post("/admin/events", eventHandler.CreateEvent)
+		r.With(eventsWrite, ownEvent).Post("/admin/events/{id}/cancel", eventHandler.CancelEvent)
+		r.With(eventsWrite, ownEvent).Post("/admin/events/{id}/tickets/issue", ticketHandler.IssueTickets)
+		r.With(refundsRead, ownEvent).Get("/admin/events/{id}/refunds", eventHandler.GetRefunds)
+		r.With(admin, ownEvent).Post("/admin/events/{id}/refunds/retry", eventHandler.RetryRefunds)
+		r.With(seatsWrite, ownEvent).Post("/admin/events/{id}/seats/import", seatHandler.ImportSeats)
+		r.With(eventsWrite, ownEvent).Post("/admin/events/{id}/tiers", pricingHandler.CreateTier)
+		r.With(eventsWrite, ownEvent).Post("/admin/events/{id}/ga-types", gaHandler.CreateType)
+		r.With(eventsWrite, ownEvent).Put("/admin/events/{id}/sections/{section}/tier", pricingHandler.AssignSection)
+		r.With(eventsWrite, ownTier).Put("/admin/tiers/{id}/seats", pricingHandler.AssignSeats)
+		r.With(eventsWrite).Post("/admin/promo-codes", promoHandler.CreateCode)
+		r.With(eventsWrite).Get("/admin/promo-codes", promoHandler.GetCodes)
+		r.With(admin, ownOrg).Post("/admin/organizations/{id}/api-keys", apiKeyHandler.CreateKey)
+		r.With(admin, ownOrg).Get("/admin/organizations/{id}/api-keys", apiKeyHandler.GetKeys)
+		r.With(admin, ownOrg).Delete("/admin/organizations/{id}/api-keys/{keyID}", apiKeyHandler.RevokeKey)
+	})
+	r.Group(func(r chi.Router) {
+		// Door scanners: API keys with the tickets:scan scope, for their organization's events only
+		r.Use(tokenMiddleware.Auth)
+		r.Use(authMiddleware.RequireAPIKeyScope(apikeys.ScopeTicketsScan))
+		r.Post("/checkin", checkinHandler.Checkin)
+		r.Get("/events/{id}/scanner-manifest", checkinHandler.ExportManifest)
+		r.Post("/events/{id}/scanner-sync", checkinHandler.SyncScans)
+	})
 	r.Group(func(r chi.Router) {
 		// Apply the Bouncer
 		r.Use(tokenMiddleware.Auth)
 
 		// Authenticated users only
 		r.Post("/bookings", bookingHandler.CreateBooking)
+		r.Get("/bookings/{id}/ticket", ticketHandler.GetTicket)
+		r.Get("/bookings/{id}/ticket/qr", ticketHandler.GetQRCode)
+		r.Post("/bookings/{id}/transfer", transferHandler.CreateTransfer)
+		r.Get("/transfers", transferHandler.GetTransfers)
+		r.Post("/transfers/{id}/accept", transferHandler.AcceptTransfer)
+		r.Post("/transfers/{id}/decline", transferHandler.DeclineTransfer)
+		r.Post("/transfers/{id}/cancel", transferHandler.CancelTransfer)
+		r.Post("/events/{id}/best-available", venueHandler.FindBestAvailable)
+		r.Post("/holds/release", venueHandler.ReleaseHolds)
+		r.Post("/ga/{typeID}/holds", gaHandler.CreateHold)
+		r.Post("/ga/holds/{holdID}/confirm", gaHandler.ConfirmHold)
+		r.Delete("/ga/holds/{holdID}", gaHandler.CancelHold)
+		r.Post("/logout", userHandler.Logout)
+		r.Post("/verify-email/resend", userHandler.ResendVerification)
+		r.Get("/mfa", userHandler.GetMFA)
+		r.Post("/mfa/totp/enroll", userHandler.EnrollTOTP)
+		r.Post("/mfa/totp/confirm", userHandler.ConfirmTOTP)
+		r.Post("/mfa/totp/disable", userHandler.DisableMFA)
+		r.Post("/mfa/recovery-codes", userHandler.RegenerateRecoveryCodes)
+		r.Get("/auth/oidc/{provider}/link", userHandler.OIDCLink)
+		r.Get("/auth/oidc/{provider}/link/callback", userHandler.OIDCLinkCallback)
+		r.Get("/api-key", apiKeyHandler.GetCurrentKey)
+		r.Get("/me", userHandler.GetMe)
+		r.Patch("/me", userHandler.UpdateMe)
+		r.Get("/me/export", userHandler.ExportMe)
+		r.Delete("/me", userHandler.DeleteMe)
 	})
 
 	srv := &http.Server{
@@ -118,9 +299,111 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	// Stop background jobs before the pool goes away
+	stopJobs()
+
 	// 9. Now safely close the Database
 	log.Println("🔌 Closing Database Connection")
 	db.Close()
 
 	log.Println("✅ Server exited properly")
 }
+
+// newMailer picks the mail transport from MAILER: "smtp" for real delivery,
+// "file" (the default) writes .eml files to MAIL_DIR for local development.
+func newMailer() (mailer.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@ticketmaster.local"
+	}
+
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return mailer.NewSMTPMailer(os.Getenv("SMTP_HOST"), port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+	case "memory":
+		return mailer.NewMemoryMailer(), nil
+	default:
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return mailer.NewFileMailer(dir, from)
+	}
+}
+
+// loadTicketSigner reads the ticket keys from TICKET_KEYS_DIR, signing with TICKET_ACTIVE_KID.
+// Keys are made with go run ./cmd/keygen -alg ed25519. Without a directory it falls back
+// to a throwaway key, which is only good for development.
+func loadTicketSigner() (*tickets.Signer, error) {
+	dir := os.Getenv("TICKET_KEYS_DIR")
+	if dir == "" {
+		log.Println("⚠️  TICKET_KEYS_DIR not set, signing tickets with an ephemeral key")
+		return tickets.EphemeralSigner()
+	}
+	return tickets.LoadSigner(dir, os.Getenv("TICKET_ACTIVE_KID"))
+}
+
+// passwordPolicy starts from the defaults and applies PASSWORD_MIN_LENGTH,
+// PASSWORD_MIN_CHAR_CLASSES and BREACHED_PASSWORDS_FILE when they are set.
+func passwordPolicy() (validation.PasswordPolicy, error) {
+	policy := validation.DefaultPasswordPolicy()
+
+	if v := os.Getenv("PASSWORD_MIN_LENGTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return policy, fmt.Errorf("PASSWORD_MIN_LENGTH must be a positive number, got %q", v)
+		}
+		policy.MinLength = n
+	}
+	if v := os.Getenv("PASSWORD_MIN_CHAR_CLASSES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 4 {
+			return policy, fmt.Errorf("PASSWORD_MIN_CHAR_CLASSES must be between 0 and 4, got %q", v)
+		}
+		policy.MinCharClasses = n
+	}
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		list, err := validation.LoadBreachedList(path)
+		if err != nil {
+			return policy, err
+		}
+		log.Printf("Loaded %d breached password hashes", list.Len())
+		policy.Breached = list
+	}
+	return policy, nil
+}
+
+// organizationItself is the owner lookup of routes about an organization: it owns itself
+func organizationItself(_ context.Context, orgID int32) (*int32, error) {
+	return &orgID, nil
+}
+
+// oidcProviders reads the comma-separated OIDC_PROVIDERS list; each name N is configured by
+// OIDC_N_ISSUER, OIDC_N_CLIENT_ID, OIDC_N_CLIENT_SECRET (optional) and OIDC_N_REDIRECT_URL.
+func oidcProviders() []*oidc.Provider {
+	var providers []*oidc.Provider
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		cfg := oidc.Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+			log.Fatalf("OIDC provider %q needs %sISSUER, %sCLIENT_ID and %sREDIRECT_URL", name, prefix, prefix, prefix)
+		}
+		providers = append(providers, oidc.NewProvider(cfg))
+		log.Printf("Sign-in with %s enabled (%s)", name, cfg.Issuer)
+	}
+	return providers
+}
@@ -0,0 +1,75 @@
+// apikey creates an API key straight against the database. It is how the first platform key
+// is made, since every /admin route, API key management included, needs one.
+//
+// Usage: go run ./cmd/apikey -org-name Operations -name bootstrap -scopes platform,admin
+//
+//	go run ./cmd/apikey -org 3 -name "north gate" -scopes tickets:scan
+//
+// The database is taken from the same DB_* variables (or .env) as the server.
+// The key is printed once; only its hash is stored.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"ticketmaster/internals/apikeys"
+	database "ticketmaster/internals/db"
+	"ticketmaster/internals/organizations"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	orgID := flag.Int("org", 0, "existing organization ID")
+	orgName := flag.String("org-name", "", "create a new organization with this name instead")
+	name := flag.String("name", "", "key name")
+	scopeList := flag.String("scopes", "", "comma-separated scopes, e.g. admin or tickets:scan")
+	flag.Parse()
+
+	if (*orgID <= 0) == (*orgName == "") {
+		log.Fatal("exactly one of -org and -org-name is required")
+	}
+	if *name == "" || *scopeList == "" {
+		log.Fatal("-name and -scopes are required")
+	}
+	scopes := strings.Split(*scopeList, ",")
+	for i, scope := range scopes {
+		scopes[i] = strings.TrimSpace(scope)
+		if !slices.Contains(apikeys.AllScopes, scopes[i]) {
+			log.Fatalf("unknown scope %q", scopes[i])
+		}
+	}
+
+	_ = godotenv.Load()
+	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_NAME"),
+	)
+	db, err := database.NewDatabase(dsn)
+	if err != nil {
+		log.Fatalf("cannot connect to database: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	if *orgName != "" {
+		org, err := organizations.NewRepository(db).CreateOrganization(ctx, organizations.OrganizationCreationRequest{Name: *orgName})
+		if err != nil {
+			log.Fatal(err)
+		}
+		*orgID = int(org.ID)
+	}
+	key, err := apikeys.NewRepository(db).CreateKey(ctx, int32(*orgID), apikeys.KeyCreationRequest{Name: *name, Scopes: scopes})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Organization %d, key %d (%s)\n%s\n", key.OrganizationID, key.ID, strings.Join(key.Scopes, ", "), key.Key)
+}
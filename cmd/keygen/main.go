@@ -0,0 +1,64 @@
+// keygen creates a JWT signing key for the keyring.
+//
+// Usage: go run ./cmd/keygen -kid 2026-10 -alg ed25519 -dir ./keys
+//
+// The private key is written to <dir>/<kid>.pem, the public key to <dir>/<kid>.pub.pem
+// (that one can be shared with partner services, who can also just read /.well-known/jwks.json).
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	kid := flag.String("kid", "", "key ID (becomes the file name and the JWT kid header)")
+	alg := flag.String("alg", "ed25519", "ed25519 or rsa")
+	dir := flag.String("dir", ".", "output directory (JWT_KEYS_DIR)")
+	flag.Parse()
+
+	if *kid == "" {
+		log.Fatal("-kid is required")
+	}
+
+	var priv crypto.Signer
+	var err error
+	switch *alg {
+	case "ed25519":
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	case "rsa":
+		priv, err = rsa.GenerateKey(rand.Reader, 3072)
+	default:
+		log.Fatalf("unsupported -alg %q", *alg)
+	}
+	if err != nil {
+		log.Fatalf("key generation failed: %v", err)
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	privPath := filepath.Join(*dir, *kid+".pem")
+	pubPath := filepath.Join(*dir, *kid+".pub.pem")
+	if err := os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("✅ Wrote %s and %s. Set JWT_ACTIVE_KID=%s to start signing with it.", privPath, pubPath, *kid)
+}
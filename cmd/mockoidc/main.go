@@ -0,0 +1,36 @@
+// mockoidc runs a local OpenID Connect issuer for trying out "Sign in with ..." without a real provider.
+// Every sign-in succeeds; pass login_hint=<email> on the authorize URL to pick the user.
+//
+// Usage: go run ./cmd/mockoidc -addr :9000 -client-id ticketmaster
+//
+// Then start the server with
+//
+//	OIDC_PROVIDERS=mock
+//	OIDC_MOCK_ISSUER=http://localhost:9000
+//	OIDC_MOCK_CLIENT_ID=ticketmaster
+//	OIDC_MOCK_REDIRECT_URL=http://localhost:8080/auth/oidc/mock/callback
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"ticketmaster/internals/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuerURL := flag.String("issuer", "http://localhost:9000", "issuer URL as clients reach it")
+	clientID := flag.String("client-id", "ticketmaster", "the one client_id this issuer accepts")
+	unverified := flag.Bool("unverified-email", false, "report email_verified=false in ID tokens")
+	flag.Parse()
+
+	issuer, err := oidctest.NewIssuer(*issuerURL, *clientID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	issuer.EmailVerified = !*unverified
+
+	log.Printf("Mock OIDC issuer %s listening on %s", issuer.URL, *addr)
+	log.Fatal(http.ListenAndServe(*addr, issuer.Handler()))
+}
@@ -0,0 +1,78 @@
+// seatimport loads a venue manifest (CSV or JSON) into an event's seat inventory,
+// the same way POST /admin/events/{id}/seats/import does, straight against the database.
+//
+// Usage: go run ./cmd/seatimport -event 12 -file arena.csv [-dry-run]
+//
+// The database is taken from the same DB_* variables (or .env) as the server.
+// Exits with status 1 and one line per problem if the manifest is rejected.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	database "ticketmaster/internals/db"
+	"ticketmaster/internals/seats"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	eventID := flag.Int("event", 0, "event ID to add the seats to")
+	file := flag.String("file", "", "manifest path (.csv or .json)")
+	format := flag.String("format", "", "csv or json (default: from the file extension)")
+	dryRun := flag.Bool("dry-run", false, "validate only, insert nothing")
+	flag.Parse()
+
+	if *eventID <= 0 || *file == "" {
+		log.Fatal("-event and -file are required")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	manifest, err := seats.ParseManifest(f, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_ = godotenv.Load()
+	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_NAME"),
+	)
+	db, err := database.NewDatabase(dsn)
+	if err != nil {
+		log.Fatalf("cannot connect to database: %v", err)
+	}
+	defer db.Close()
+
+	result, err := seats.NewRepository(db).Import(context.Background(), int32(*eventID), manifest, *dryRun)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		for _, e := range result.Errors {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s %s\n", *file, e.Line, e.Field, e.Message)
+		}
+		fmt.Fprintf(os.Stderr, "%d problems, nothing imported\n", len(result.Errors))
+		os.Exit(1)
+	}
+	if *dryRun {
+		fmt.Printf("%d seats OK (dry run)\n", result.Seats)
+		return
+	}
+	fmt.Printf("Imported %d seats into event %d\n", result.Inserted, result.EventID)
+}
@@ -0,0 +1,126 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/validation"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// CreateKey handles POST /admin/organizations/{id}/api-keys
+func (h *Handler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	orgID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid organization id", http.StatusBadRequest)
+		return
+	}
+	var req KeyCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	var errs validation.Errors
+	errs.Required("name", req.Name)
+	errs.MaxLength("name", req.Name, 200)
+	errs.Check(len(req.Scopes) > 0, "scopes", "at least one scope is required")
+	for _, scope := range req.Scopes {
+		errs.Check(slices.Contains(AllScopes, scope), "scopes", "unknown scope "+strconv.Quote(scope))
+		errs.Check(scope != ScopePlatform || middleware.IsOperator(r.Context()), "scopes", "only the platform operator can grant "+ScopePlatform)
+	}
+	errs.Check(req.ExpiresAt == nil || req.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	if errs.Respond(w) {
+		return
+	}
+
+	key, err := h.repo.CreateKey(r.Context(), int32(orgID), req)
+	if err != nil {
+		if errors.Is(err, ErrOrganizationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+// GetKeys handles GET /admin/organizations/{id}/api-keys
+func (h *Handler) GetKeys(w http.ResponseWriter, r *http.Request) {
+	orgID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid organization id", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := h.repo.GetAll(r.Context(), int32(orgID))
+	if err != nil {
+		http.Error(w, "Failed to fetch API keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RevokeKey handles DELETE /admin/organizations/{id}/api-keys/{keyID}
+func (h *Handler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	orgID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid organization id", http.StatusBadRequest)
+		return
+	}
+	keyID, err := strconv.ParseInt(chi.URLParam(r, "keyID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid key id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.Revoke(r.Context(), int32(orgID), keyID); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCurrentKey handles GET /api-key so an integration can check its credentials and scopes
+func (h *Handler) GetCurrentKey(w http.ResponseWriter, r *http.Request) {
+	keyID, ok := r.Context().Value(middleware.APIKeyIDKey).(int64)
+	if !ok {
+		http.Error(w, "Not authenticated with an API key", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.repo.GetByID(r.Context(), keyID)
+	if err != nil {
+		http.Error(w, "Failed to load API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(key)
+}
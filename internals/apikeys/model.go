@@ -0,0 +1,47 @@
+package apikeys
+
+import "time"
+
+// Scopes an API key can carry. A key can only do what its scopes allow;
+// user tokens never pass the routes that need one.
+const (
+	ScopeEventsWrite = "events:write" // Events, their tiers, GA types, promo codes and tickets
+	ScopeSeatsWrite  = "seats:write"  // Creating and importing seats
+	ScopeRefundsRead = "refunds:read" // An event's refund progress
+	ScopeTicketsScan = "tickets:scan" // Door scanners
+	ScopeAdmin       = "admin"        // Everything under /admin for the key's own organization
+	ScopePlatform    = "platform"     // The operator's staff: organizations, venues, the ledger and every organization's events
+)
+
+// AllScopes is what CreateKey accepts
+var AllScopes = []string{ScopeEventsWrite, ScopeSeatsWrite, ScopeRefundsRead, ScopeTicketsScan, ScopeAdmin, ScopePlatform}
+
+// APIKey is a key's metadata. The secret itself is never stored or shown again after creation.
+type APIKey struct {
+	ID             int64      `json:"id"`
+	OrganizationID int32      `json:"organization_id"`
+	Name           string     `json:"name"`
+	Prefix         string     `json:"prefix"` // Example: 'tm_1f2e3d4c', enough to recognise the key
+	Scopes         []string   `json:"scopes"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Active reports whether the key can still authenticate at now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+type KeyCreationRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedKey is returned once, on creation: the only time the full key is visible
+type CreatedKey struct {
+	APIKey
+	Key string `json:"key"`
+}
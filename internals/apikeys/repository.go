@@ -0,0 +1,162 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	database "ticketmaster/internals/db"
+	"ticketmaster/internals/middleware"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrKeyNotFound          = errors.New("API key not found")
+	ErrOrganizationNotFound = errors.New("organization not found")
+)
+
+// Recording every request would be a write per call; once a minute is plenty for "last used"
+const lastUsedResolution = time.Minute
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const keyColumns = `id, organization_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+// CreateKey generates a key for the organization and stores its hash
+func (r *Repository) CreateKey(ctx context.Context, orgID int32, req KeyCreationRequest) (*CreatedKey, error) {
+	prefix, key, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO api_keys (organization_id, name, prefix, key_hash, scopes, expires_at)
+	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + keyColumns
+	rows, err := r.db.Pool.Query(ctx, query, orgID, req.Name, prefix, hashKey(key), req.Scopes, req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[APIKey])
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &CreatedKey{APIKey: created, Key: key}, nil
+}
+
+// GetAll lists the organization's keys, revoked ones included
+func (r *Repository) GetAll(ctx context.Context, orgID int32) ([]APIKey, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT `+keyColumns+` FROM api_keys WHERE organization_id = $1 ORDER BY id`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[APIKey])
+}
+
+// GetByID returns one key's metadata
+func (r *Repository) GetByID(ctx context.Context, keyID int64) (*APIKey, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT `+keyColumns+` FROM api_keys WHERE id = $1`, keyID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[APIKey])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrKeyNotFound
+	}
+	return key, err
+}
+
+// Revoke stops a key from working immediately. Revoking twice is not an error.
+func (r *Repository) Revoke(ctx context.Context, orgID int32, keyID int64) error {
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1 AND organization_id = $2`,
+		keyID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// VerifyAPIKey implements middleware.APIKeyVerifier
+func (r *Repository) VerifyAPIKey(ctx context.Context, raw string) (*middleware.APIKeyPrincipal, error) {
+	prefix, ok := keyPrefix(raw)
+	if !ok {
+		return nil, middleware.ErrInvalidAPIKey
+	}
+
+	var key APIKey
+	var storedHash string
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT id, organization_id, scopes, expires_at, revoked_at, last_used_at, key_hash
+		FROM api_keys WHERE prefix = $1`, prefix,
+	).Scan(&key.ID, &key.OrganizationID, &key.Scopes, &key.ExpiresAt, &key.RevokedAt, &key.LastUsedAt, &storedHash)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, middleware.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(hashKey(raw)), []byte(storedHash)) != 1 || !key.Active(now) {
+		return nil, middleware.ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+		if _, err := r.db.Pool.Exec(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, key.ID); err != nil {
+			return nil, fmt.Errorf("failed to record API key use: %w", err)
+		}
+	}
+	return &middleware.APIKeyPrincipal{KeyID: key.ID, OrganizationID: key.OrganizationID, Scopes: key.Scopes}, nil
+}
+
+// generateKey returns the public prefix and the full key: tm_<8 hex>_<43 url-safe chars>
+func generateKey() (string, string, error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	prefix := middleware.APIKeyPrefix + hex.EncodeToString(id)
+	return prefix, prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// keyPrefix cuts "tm_1f2e3d4c" out of "tm_1f2e3d4c_<secret>"
+func keyPrefix(raw string) (string, bool) {
+	rest, ok := strings.CutPrefix(raw, middleware.APIKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 8 || secret == "" {
+		return "", false
+	}
+	return middleware.APIKeyPrefix + id, true
+}
+
+// hashKey is SHA-256: keys carry 256 random bits, so a slow hash adds nothing but latency to every request
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
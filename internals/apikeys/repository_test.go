@@ -0,0 +1,100 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"ticketmaster/internals/db/dbtest"
+	"ticketmaster/internals/middleware"
+	"time"
+)
+
+func TestKeyFormat(t *testing.T) {
+	prefix, key, err := generateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := keyPrefix(key); !ok || got != prefix || !strings.HasPrefix(key, prefix+"_") || len(key) != len(prefix)+1+43 {
+		t.Errorf("key %q: keyPrefix = %q, %v; want %q", key, got, ok, prefix)
+	}
+	for _, raw := range []string{"", "tm_", "tm_1f2e3d4c", "tm_1f2e3d4c_", "tm_1f2e_secret", "xx_1f2e3d4c_secret"} {
+		if _, ok := keyPrefix(raw); ok {
+			t.Errorf("keyPrefix accepted %q", raw)
+		}
+	}
+
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	tests := []struct {
+		name string
+		key  APIKey
+		want bool
+	}{
+		{"no expiry", APIKey{}, true},
+		{"expires later", APIKey{ExpiresAt: &future}, true},
+		{"expired", APIKey{ExpiresAt: &past}, false},
+		{"revoked", APIKey{RevokedAt: &past, ExpiresAt: &future}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.key.Active(now); got != tt.want {
+			t.Errorf("%s: Active = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyAPIKey(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	repo := NewRepository(db)
+	org := dbtest.ID(t, db, `INSERT INTO organizations (name) VALUES ('Box Office') RETURNING id`)
+	other := dbtest.ID(t, db, `INSERT INTO organizations (name) VALUES ('Other Promoter') RETURNING id`)
+
+	if _, err := repo.CreateKey(ctx, 999999, KeyCreationRequest{Name: "ghost"}); !errors.Is(err, ErrOrganizationNotFound) {
+		t.Errorf("key for a missing organization = %v, want ErrOrganizationNotFound", err)
+	}
+	created, err := repo.CreateKey(ctx, org, KeyCreationRequest{Name: "Gate 1", Scopes: []string{ScopeTicketsScan}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, err := repo.VerifyAPIKey(ctx, created.Key)
+	if err != nil {
+		t.Fatalf("VerifyAPIKey: %v", err)
+	}
+	if principal.OrganizationID != org || !slices.Equal(principal.Scopes, []string{ScopeTicketsScan}) {
+		t.Errorf("principal = %+v", principal)
+	}
+	if key, _ := repo.GetByID(ctx, created.ID); key.LastUsedAt == nil {
+		t.Error("use not recorded")
+	}
+
+	// Right prefix, wrong secret
+	if _, err := repo.VerifyAPIKey(ctx, created.Prefix+"_not-the-secret"); !errors.Is(err, middleware.ErrInvalidAPIKey) {
+		t.Errorf("wrong secret = %v, want ErrInvalidAPIKey", err)
+	}
+
+	// Only the owning organization can revoke, and then the key stops working
+	if err := repo.Revoke(ctx, other, created.ID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("revoke by another organization = %v, want ErrKeyNotFound", err)
+	}
+	if err := repo.Revoke(ctx, org, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Revoke(ctx, org, created.ID); err != nil {
+		t.Errorf("second revoke = %v", err)
+	}
+	if _, err := repo.VerifyAPIKey(ctx, created.Key); !errors.Is(err, middleware.ErrInvalidAPIKey) {
+		t.Errorf("revoked key = %v, want ErrInvalidAPIKey", err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	expired, err := repo.CreateKey(ctx, org, KeyCreationRequest{Name: "Old", ExpiresAt: &past})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.VerifyAPIKey(ctx, expired.Key); !errors.Is(err, middleware.ErrInvalidAPIKey) {
+		t.Errorf("expired key = %v, want ErrInvalidAPIKey", err)
+	}
+}
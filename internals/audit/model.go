@@ -0,0 +1,21 @@
+package audit
+
+import "time"
+
+// Actions
+const (
+	ActionLoginLockout   = "login.lockout"
+	ActionDataExported   = "account.data_exported"
+	ActionAccountDeleted = "account.deleted"
+)
+
+// Entry is one line of the audit trail
+type Entry struct {
+	ID        int64          `json:"id"`
+	Action    string         `json:"action"`
+	UserID    *int           `json:"user_id"`
+	Subject   string         `json:"subject"`
+	IP        string         `json:"ip"`
+	Details   map[string]any `json:"details"`
+	CreatedAt time.Time      `json:"created_at"`
+}
@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	database "ticketmaster/internals/db"
+)
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Record appends an entry to the audit trail
+func (r *Repository) Record(ctx context.Context, entry Entry) error {
+	details := entry.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+	_, err := r.db.Pool.Exec(ctx,
+		`INSERT INTO audit_log (action, user_id, subject, ip, details) VALUES ($1, $2, $3, $4, $5)`,
+		entry.Action, entry.UserID, entry.Subject, entry.IP, details)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
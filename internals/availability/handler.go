@@ -0,0 +1,108 @@
+package availability
+
+import (
+	"encoding/json"
+	"errors"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"ticketmaster/internals/cache"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RunLengths is the ?format=rle body of GET /events/{id}/availability.
+// Runs alternate unavailable and available seats in bit order, starting with unavailable
+// (possibly 0): [2, 3, 1] means the first 2 seats are taken, the next 3 free, the last one taken.
+type RunLengths struct {
+	EventID   int32   `json:"event_id"`
+	Size      int32   `json:"size"`
+	Available int     `json:"available"`
+	Runs      []int32 `json:"runs"`
+}
+
+// SeatIndex is the ?format=index body: the seat behind each bit. It only changes when
+// seats are added to the event, so clients fetch it once and keep polling the bitmap.
+type SeatIndex struct {
+	EventID int32   `json:"event_id"`
+	SeatIDs []int32 `json:"seat_ids"`
+}
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetAvailability handles GET /events/{id}/availability.
+// By default the body is the raw bitmap (bit i, most significant first, is the event's i-th
+// seat by ID); ?format=rle returns run lengths as JSON instead, and ?format=index the seat
+// ID behind each bit.
+func (h *Handler) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid event id", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "bitmap" && format != "rle" && format != "index" {
+		http.Error(w, "format must be bitmap, rle or index", http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.service.Get(r.Context(), int32(id), format == "index")
+	if err != nil {
+		if errors.Is(err, ErrEventNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch availability", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	switch format {
+	case "rle":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(runLengths(int32(id), a))
+		return
+	case "index":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SeatIndex{EventID: int32(id), SeatIDs: a.SeatIDs})
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Seat-Count", strconv.Itoa(int(a.Size)))
+	w.Header().Set("X-Seats-Available", strconv.Itoa(countAvailable(a)))
+	w.Write(a.Bitmap[:(a.Size+7)/8])
+}
+
+func runLengths(eventID int32, a *cache.Availability) RunLengths {
+	out := RunLengths{EventID: eventID, Size: a.Size, Runs: []int32{}}
+	current, run := false, int32(0) // Runs start with unavailable
+	for i := int32(0); i < a.Size; i++ {
+		bit := a.Bitmap[i/8]&(0x80>>(i%8)) != 0
+		if bit {
+			out.Available++
+		}
+		if bit != current {
+			out.Runs = append(out.Runs, run)
+			current, run = bit, 0
+		}
+		run++
+	}
+	if a.Size > 0 {
+		out.Runs = append(out.Runs, run)
+	}
+	return out
+}
+
+func countAvailable(a *cache.Availability) int {
+	n := 0
+	for _, b := range a.Bitmap[:(a.Size+7)/8] {
+		n += bits.OnesCount8(b)
+	}
+	return n
+}
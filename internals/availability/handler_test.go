@@ -0,0 +1,32 @@
+package availability
+
+import (
+	"slices"
+	"testing"
+	"ticketmaster/internals/cache"
+)
+
+func TestRunLengths(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     cache.Availability
+		runs  []int32
+		avail int
+	}{
+		{"empty", cache.Availability{}, []int32{}, 0},
+		{"starts taken", cache.Availability{Size: 6, Bitmap: []byte{0b00111000}}, []int32{2, 3, 1}, 3},
+		{"starts free", cache.Availability{Size: 3, Bitmap: []byte{0b11000000}}, []int32{0, 2, 1}, 2},
+		{"across bytes", cache.Availability{Size: 10, Bitmap: []byte{0xff, 0b01000000}}, []int32{0, 8, 1, 1}, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runLengths(1, &tt.a)
+			if !slices.Equal(got.Runs, tt.runs) || got.Available != tt.avail || got.Size != tt.a.Size {
+				t.Errorf("runLengths = %+v, want runs %v with %d available", got, tt.runs, tt.avail)
+			}
+			if n := countAvailable(&tt.a); n != tt.avail {
+				t.Errorf("countAvailable = %d, want %d", n, tt.avail)
+			}
+		})
+	}
+}
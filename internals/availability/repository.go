@@ -0,0 +1,79 @@
+package availability
+
+import (
+	"context"
+	"errors"
+	"ticketmaster/internals/cache"
+	database "ticketmaster/internals/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrEventNotFound = errors.New("event not found")
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Snapshot builds an event's bitmap from the seats table: bit i is the event's i-th seat
+// by ID, set when the seat is available in Postgres (it may still be locked in Redis).
+// Its Version is the newest seat version it saw, comparable with InventoryVersion.
+func (r *Repository) Snapshot(ctx context.Context, eventID int32) (cache.Availability, error) {
+	var exists bool
+	if err := r.db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM events WHERE id = $1)`, eventID).Scan(&exists); err != nil {
+		return cache.Availability{}, err
+	}
+	if !exists {
+		return cache.Availability{}, ErrEventNotFound
+	}
+
+	rows, err := r.db.Pool.Query(ctx, `SELECT id, status = 'available', version FROM seats WHERE event_id = $1 ORDER BY id`, eventID)
+	if err != nil {
+		return cache.Availability{}, err
+	}
+	type seat struct {
+		ID        int32
+		Available bool
+		Version   int64
+	}
+	seats, err := pgx.CollectRows(rows, pgx.RowToStructByPos[seat])
+	if err != nil {
+		return cache.Availability{}, err
+	}
+
+	a := cache.Availability{
+		Size:    int32(len(seats)),
+		Bitmap:  make([]byte, (len(seats)+7)/8),
+		SeatIDs: make([]int32, len(seats)),
+	}
+	for i, s := range seats {
+		a.SeatIDs[i] = s.ID
+		a.Version = max(a.Version, s.Version)
+		if s.Available {
+			a.Bitmap[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return a, nil
+}
+
+// InventoryVersion changes whenever a seat of the event is inserted or updated (see the
+// seat_inventory_version_seq migration), so the reconciler can skip events that haven't.
+// It is a single index probe.
+func (r *Repository) InventoryVersion(ctx context.Context, eventID int32) (int64, error) {
+	var version int64
+	err := r.db.Pool.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM seats WHERE event_id = $1`, eventID).Scan(&version)
+	return version, err
+}
+
+// UpcomingEvents are the events worth having a bitmap for at startup
+func (r *Repository) UpcomingEvents(ctx context.Context) ([]int32, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT id FROM events WHERE status = 'scheduled' AND starts_at > NOW() ORDER BY starts_at`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int32])
+}
@@ -0,0 +1,122 @@
+package availability
+
+import (
+	"context"
+	"errors"
+	"log"
+	"ticketmaster/internals/cache"
+	"time"
+)
+
+// A lock that expires without being released (crashed request, abandoned hold) leaves its
+// seat cleared in the bitmap; the reconciler puts it back within this interval.
+// One replica reconciles per interval.
+const reconcileInterval = 30 * time.Second
+
+// Service keeps the Redis availability bitmaps in line with Postgres. The lock scripts flip
+// bits as seats are held, booked and released; this sweeps the locks that just expired and
+// rebuilds bitmaps whose seats changed in Postgres behind their back.
+type Service struct {
+	repo       *Repository
+	redisStore *cache.RedisStore
+}
+
+func NewService(repo *Repository, redisStore *cache.RedisStore) *Service {
+	return &Service{repo: repo, redisStore: redisStore}
+}
+
+// Get returns the event's bitmap, building it from Postgres if Redis doesn't have it.
+// withSeats also returns the seat behind each bit.
+func (s *Service) Get(ctx context.Context, eventID int32, withSeats bool) (*cache.Availability, error) {
+	a, err := s.redisStore.GetAvailability(ctx, eventID, withSeats)
+	if err != nil || a != nil {
+		return a, err
+	}
+	if _, err := s.Rebuild(ctx, eventID); err != nil {
+		return nil, err
+	}
+	a, err = s.redisStore.GetAvailability(ctx, eventID, withSeats)
+	if err == nil && a == nil {
+		err = errors.New("availability bitmap vanished after rebuild")
+	}
+	return a, err
+}
+
+// Rebuild rewrites the event's bitmap from Postgres and reports whether it had drifted
+func (s *Service) Rebuild(ctx context.Context, eventID int32) (bool, error) {
+	a, err := s.repo.Snapshot(ctx, eventID)
+	if err != nil {
+		return false, err
+	}
+	return s.redisStore.StoreAvailability(ctx, eventID, a)
+}
+
+// Run builds the bitmaps of upcoming events, then reconciles every built bitmap
+// against Postgres until ctx is cancelled
+func (s *Service) Run(ctx context.Context) {
+	events, err := s.repo.UpcomingEvents(ctx)
+	if err != nil {
+		log.Printf("availability: cannot list upcoming events: %v", err)
+	}
+	for _, id := range events {
+		if _, err := s.Rebuild(ctx, id); err != nil {
+			log.Printf("availability: cannot build bitmap of event %d: %v", id, err)
+		}
+	}
+
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reconcile(ctx)
+		}
+	}
+}
+
+// reconcile restores the seats of expired locks in every bitmap, then rebuilds the ones whose
+// event's seat inventory version moved since they were built. Most events pass with one Redis
+// script and one index probe.
+func (s *Service) reconcile(ctx context.Context) {
+	if claimed, err := s.redisStore.ClaimReconcile(ctx, reconcileInterval-time.Second); err != nil || !claimed {
+		if err != nil {
+			log.Printf("availability: cannot claim the reconcile: %v", err)
+		}
+		return
+	}
+	events, err := s.redisStore.AvailabilityEvents(ctx)
+	if err != nil {
+		log.Printf("availability: cannot list bitmaps: %v", err)
+		return
+	}
+	for _, id := range events {
+		if _, err := s.redisStore.SweepExpiredLocks(ctx, id); err != nil {
+			log.Printf("availability: cannot sweep expired locks of event %d: %v", id, err)
+		}
+		built, err := s.redisStore.AvailabilityVersion(ctx, id)
+		if err != nil {
+			log.Printf("availability: cannot read bitmap version of event %d: %v", id, err)
+			continue
+		}
+		current, err := s.repo.InventoryVersion(ctx, id)
+		if err != nil {
+			log.Printf("availability: cannot read seat inventory version of event %d: %v", id, err)
+			continue
+		}
+		if built == current {
+			continue
+		}
+
+		drifted, err := s.Rebuild(ctx, id)
+		switch {
+		case errors.Is(err, ErrEventNotFound):
+			s.redisStore.DropAvailability(ctx, id)
+		case err != nil:
+			log.Printf("availability: cannot rebuild bitmap of event %d: %v", id, err)
+		case drifted:
+			log.Printf("availability: bitmap of event %d drifted from Postgres, rebuilt", id)
+		}
+	}
+}
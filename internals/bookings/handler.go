@@ -1,22 +1,27 @@
 package bookings
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"ticketmaster/internals/cache"
 	"ticketmaster/internals/middleware"
 	"ticketmaster/internals/notifications"
+	"ticketmaster/internals/promotions"
+	"ticketmaster/internals/validation"
 )
 
 type Handler struct {
 	repo       *Repository
 	hub        *notifications.Hub
 	redisStore *cache.RedisStore
+	promos     *promotions.Service
+	seatInfo   *seatInfoCache
 }
 
-func NewHandler(repo *Repository, redisStore *cache.RedisStore, hub *notifications.Hub) *Handler {
-	return &Handler{repo: repo, hub: hub, redisStore: redisStore}
+func NewHandler(repo *Repository, redisStore *cache.RedisStore, hub *notifications.Hub, promos *promotions.Service) *Handler {
+	return &Handler{repo: repo, hub: hub, redisStore: redisStore, promos: promos, seatInfo: newSeatInfoCache()}
 }
 
 func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
@@ -27,6 +32,12 @@ func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	var errs validation.Errors
+	errs.Check(req.SeatID > 0, "seat_id", "is required")
+	errs.MaxLength("promo_code", req.PromoCode, 64)
+	if errs.Respond(w) {
+		return
+	}
 	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
 	if !ok {
 		// This should never happen if the middleware is running
@@ -34,22 +45,52 @@ func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lockKey := fmt.Sprintf("seat_lock:%d", req.SeatID)
+	info, err := h.seatInfo.get(r.Context(), h.repo, req.SeatID)
+	if err != nil {
+		http.Error(w, "Failed to load seat", http.StatusInternalServerError)
+		return
+	}
 
 	// Attempt to acquire the lock in Redis (Atomic Lua Script)
 	// We set a 60-second expiry just in case the server crashes before DB write
-	if err := h.redisStore.AtomicBook(r.Context(), lockKey, userID, 60); err != nil {
+	release, err := h.acquire(r.Context(), req.SeatID, userID, info)
+	if err != nil {
+		if errors.Is(err, cache.ErrUserQuotaExhausted) {
+			writeError(w, http.StatusConflict, CodePurchaseLimitExceeded, ErrPurchaseLimitExceeded.Error())
+			return
+		}
 		// 🛑 STOP! Redis says the seat is taken.
 		// Return 409 Conflict immediately. Do not touch Postgres.
-		http.Error(w, "Seat is currently reserved or booked", http.StatusConflict)
+		writeError(w, http.StatusConflict, CodeSeatUnavailable, "Seat is currently reserved or booked")
 		return
 	}
 
+	// Take one use of the promo/access code (atomic Redis counters)
+	var promo *promotions.PromoCode
+	if req.PromoCode != "" {
+		promo, err = h.promos.Reserve(r.Context(), req.PromoCode, userID)
+		if err != nil {
+			release(true)
+			writeBookingError(w, err)
+			return
+		}
+	}
+
 	// Call the logic
-	if err := h.repo.CreateBooking(r.Context(), req.SeatID, userID); err != nil {
-		http.Error(w, err.Error(), http.StatusConflict)
+	booking, err := h.repo.CreateBooking(r.Context(), req.SeatID, userID, promo)
+	if err != nil {
+		// Give back what we reserved so the seat and the code use aren't burned for nothing.
+		// A seat Postgres already has as sold stays unavailable in the bitmap.
+		release(!errors.Is(err, ErrSeatUnavailable))
+		if promo != nil {
+			h.promos.Release(r.Context(), promo, userID)
+		}
+		writeBookingError(w, err)
 		return
 	}
+	// The lock now stands for a sold seat until it expires
+	h.redisStore.MarkSeatBooked(r.Context(), cache.SeatLockKey(req.SeatID), userID, seatBit(req.SeatID, info))
+
 	go func() {
 		msg := map[string]interface{}{
 			"type":    "seat_booked",
@@ -60,6 +101,87 @@ func (h *Handler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		h.hub.Broadcast <- jsonMsg
 	}()
 
+	// Return the booking with its price breakdown so checkout can show the total
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	w.Write([]byte("Booked!"))
+	json.NewEncoder(w).Encode(booking)
+}
+
+// acquire takes the seat lock, together with one unit of the user's purchase limit when the
+// event has one. The returned func undoes both if the booking doesn't go through; restore
+// says whether the seat should show as available again.
+func (h *Handler) acquire(ctx context.Context, seatID, userID int32, info seatInfo) (func(restore bool), error) {
+	lockKey := cache.SeatLockKey(seatID)
+	bit := seatBit(seatID, info)
+	restoreBit := func(restore bool) *cache.SeatBit {
+		if restore {
+			return bit
+		}
+		return nil
+	}
+
+	if info.limit == nil {
+		if err := h.redisStore.AtomicBook(ctx, lockKey, userID, 60, bit); err != nil {
+			return nil, err
+		}
+		return func(restore bool) { h.redisStore.ReleaseLock(ctx, lockKey, userID, restoreBit(restore)) }, nil
+	}
+
+	// Seed the counter from Postgres the first time we see this user for this event
+	countKey := PurchaseCountKey(*info.eventID, userID)
+	exists, err := h.redisStore.CounterExists(ctx, countKey)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		held, err := h.repo.CountUserTickets(ctx, *info.eventID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := h.redisStore.SeedCounter(ctx, countKey, held); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.redisStore.AtomicBookWithLimit(ctx, lockKey, countKey, userID, int64(*info.limit), 60, bit); err != nil {
+		return nil, err
+	}
+	return func(restore bool) {
+		h.redisStore.ReleaseBookWithLimit(ctx, lockKey, countKey, userID, restoreBit(restore))
+	}, nil
+}
+
+// seatBit is the availability bit of the seat, nil for seats that belong to no event
+func seatBit(seatID int32, info seatInfo) *cache.SeatBit {
+	if info.eventID == nil {
+		return nil
+	}
+	return &cache.SeatBit{EventID: *info.eventID, SeatID: seatID}
+}
+
+// writeBookingError maps domain errors to status codes; anything else is a conflict
+func writeBookingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, promotions.ErrCodeNotFound),
+		errors.Is(err, promotions.ErrCodeInactive),
+		errors.Is(err, promotions.ErrCodeNotApplicable):
+		writeError(w, http.StatusUnprocessableEntity, CodePromoInvalid, err.Error())
+	case errors.Is(err, promotions.ErrCodeExhausted),
+		errors.Is(err, promotions.ErrUserLimitReached):
+		writeError(w, http.StatusConflict, CodePromoExhausted, err.Error())
+	case errors.Is(err, ErrPresaleCodeRequired):
+		writeError(w, http.StatusForbidden, CodePresaleCodeRequired, err.Error())
+	case errors.Is(err, ErrEmailNotVerified):
+		writeError(w, http.StatusForbidden, CodeEmailNotVerified, err.Error())
+	case errors.Is(err, ErrPurchaseLimitExceeded):
+		writeError(w, http.StatusConflict, CodePurchaseLimitExceeded, err.Error())
+	default:
+		writeError(w, http.StatusConflict, CodeSeatUnavailable, err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Code: code, Message: message})
 }
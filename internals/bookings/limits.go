@@ -0,0 +1,54 @@
+package bookings
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// How long a seat's event/limit lookup is trusted before asking Postgres again
+const seatInfoTTL = time.Minute
+
+type seatInfo struct {
+	eventID  *int32
+	limit    *int32
+	loadedAt time.Time
+}
+
+// seatInfoCache keeps the seat -> (event, per-user limit) mapping in memory,
+// so the Redis gatekeeper can enforce limits without a Postgres round-trip per request.
+type seatInfoCache struct {
+	mu      sync.RWMutex
+	entries map[int32]seatInfo
+}
+
+func newSeatInfoCache() *seatInfoCache {
+	return &seatInfoCache{entries: make(map[int32]seatInfo)}
+}
+
+func (c *seatInfoCache) get(ctx context.Context, repo *Repository, seatID int32) (seatInfo, error) {
+	c.mu.RLock()
+	info, ok := c.entries[seatID]
+	c.mu.RUnlock()
+	if ok && time.Since(info.loadedAt) < seatInfoTTL {
+		return info, nil
+	}
+
+	eventID, limit, err := repo.SeatEventLimit(ctx, seatID)
+	if err != nil {
+		return seatInfo{}, fmt.Errorf("failed to load seat event: %w", err)
+	}
+	info = seatInfo{eventID: eventID, limit: limit, loadedAt: time.Now()}
+
+	c.mu.Lock()
+	c.entries[seatID] = info
+	c.mu.Unlock()
+	return info, nil
+}
+
+// PurchaseCountKey is the Redis counter of the user's tickets for the event, seeded from
+// CountUserTickets. Seat and general-admission sales share it.
+func PurchaseCountKey(eventID, userID int32) string {
+	return fmt.Sprintf("purchase_count:%d:%d", eventID, userID)
+}
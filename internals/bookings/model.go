@@ -1,16 +1,35 @@
 package bookings
 
 import (
+	"ticketmaster/internals/pricing"
 	"time"
 )
 
 type BookingRequest struct {
-	SeatID int32 `json:"seat_id"`
+	SeatID    int32  `json:"seat_id"`
+	PromoCode string `json:"promo_code"` // Optional discount or presale access code
 }
 
 type Booking struct {
-	ID        int32     `json:"id"`
-	SeatID    int32     `json:"seat_id"`
-	UserID    int32     `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int32             `json:"id"`
+	SeatID    int32             `json:"seat_id"`
+	UserID    int32             `json:"user_id"`
+	Status    string            `json:"status"`
+	Price     pricing.Breakdown `json:"price"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Error codes returned in ErrorResponse.Code, so clients don't have to parse messages
+const (
+	CodeSeatUnavailable       = "SEAT_UNAVAILABLE"
+	CodePurchaseLimitExceeded = "PURCHASE_LIMIT_EXCEEDED"
+	CodePresaleCodeRequired   = "PRESALE_CODE_REQUIRED"
+	CodeEmailNotVerified      = "EMAIL_NOT_VERIFIED"
+	CodePromoInvalid          = "PROMO_CODE_INVALID"
+	CodePromoExhausted        = "PROMO_CODE_EXHAUSTED"
+)
+
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
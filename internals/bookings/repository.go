@@ -2,27 +2,43 @@ package bookings
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	database "ticketmaster/internals/db"
+	"ticketmaster/internals/ledger"
+	"ticketmaster/internals/pricing"
+	"ticketmaster/internals/promotions"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
+var (
+	ErrPresaleCodeRequired   = errors.New("this event is in presale; an access code is required")
+	ErrPurchaseLimitExceeded = errors.New("you have reached the ticket limit for this event")
+	ErrEmailNotVerified      = errors.New("please verify your email address before booking this event")
+	ErrSeatUnavailable       = errors.New("seat is not available")
+)
+
 type Repository struct {
-	db *database.DB
+	db      *database.DB
+	ledger  *ledger.Repository
+	pricing *pricing.Repository
+	promos  *promotions.Repository
 }
 
-func NewRepository(db *database.DB) *Repository {
-	return &Repository{db: db}
+func NewRepository(db *database.DB, ledgerRepo *ledger.Repository, pricingRepo *pricing.Repository, promoRepo *promotions.Repository) *Repository {
+	return &Repository{db: db, ledger: ledgerRepo, pricing: pricingRepo, promos: promoRepo}
 }
 
-// CreateBooking attempts to book a seat inside a transaction
-func (r *Repository) CreateBooking(ctx context.Context, seatID, userID int32) error {
+// CreateBooking attempts to book a seat inside a transaction.
+// promo is optional and must already be reserved through promotions.Service.
+func (r *Repository) CreateBooking(ctx context.Context, seatID, userID int32, promo *promotions.PromoCode) (*Booking, error) {
 	// 1. Start a Transaction
 	// This opens a "sandbox" session. Nothing is permanent until we Commit.
 	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	// Safety Net: If anything fails or panics, Rollback changes.
 	defer tx.Rollback(ctx)
@@ -30,36 +46,149 @@ func (r *Repository) CreateBooking(ctx context.Context, seatID, userID int32) er
 	// 2. Lock the Seat (The Secret Sauce 🔒)
 	// "FOR UPDATE" tells Postgres: "Lock this row. Make everyone else wait."
 	var currentStatus string
-	queryCheck := `SELECT status FROM seats WHERE id = $1 FOR UPDATE`
+	var eventID *int32
+	var publicOnsaleAt *time.Time
+	var maxPerUser *int32
+	var requireVerified bool
+	queryCheck := `SELECT s.status, s.event_id, e.public_onsale_at, e.max_tickets_per_user,
+	                      COALESCE(e.require_verified_email, FALSE)
+	               FROM seats s LEFT JOIN events e ON e.id = s.event_id
+	               WHERE s.id = $1 FOR UPDATE OF s`
 
-	err = tx.QueryRow(ctx, queryCheck, seatID).Scan(&currentStatus)
+	err = tx.QueryRow(ctx, queryCheck, seatID).Scan(&currentStatus, &eventID, &publicOnsaleAt, &maxPerUser, &requireVerified)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return fmt.Errorf("seat %d does not exist", seatID)
+			return nil, fmt.Errorf("seat %d does not exist", seatID)
 		}
-		return fmt.Errorf("failed to lock seat: %w", err)
+		return nil, fmt.Errorf("failed to lock seat: %w", err)
 	}
 
 	// 3. The Logic Check
 	if currentStatus != "available" {
-		return fmt.Errorf("seat is already %s", currentStatus)
+		return nil, fmt.Errorf("%w: it is already %s", ErrSeatUnavailable, currentStatus)
+	}
+
+	// Event policy: verified accounts only
+	if requireVerified {
+		var verified bool
+		err := tx.QueryRow(ctx, `SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1`, userID).Scan(&verified)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check email verification: %w", err)
+		}
+		if !verified {
+			return nil, ErrEmailNotVerified
+		}
+	}
+
+	// Presale: only access-code holders get in before the public on-sale
+	if publicOnsaleAt != nil && time.Now().Before(*publicOnsaleAt) {
+		if promo == nil || promo.Kind != promotions.KindAccess {
+			return nil, ErrPresaleCodeRequired
+		}
+	}
+
+	// Purchase limit: the Redis gatekeeper already checked it, this is the authoritative double-check.
+	// The advisory lock serializes only this user's bookings for this event.
+	if maxPerUser != nil {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, *eventID, userID); err != nil {
+			return nil, fmt.Errorf("failed to lock purchase counter: %w", err)
+		}
+		held, err := CountUserTickets(ctx, tx, *eventID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if held >= int64(*maxPerUser) {
+			return nil, ErrPurchaseLimitExceeded
+		}
+	}
+
+	// 4. Price it (tier, discount, fees, tax) while we hold the lock
+	tier, err := r.pricing.TierForSeat(ctx, tx, seatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to price seat: %w", err)
+	}
+	var discount pricing.Discount
+	if promo != nil {
+		if !promo.AppliesTo(eventID, tier.ID) {
+			return nil, promotions.ErrCodeNotApplicable
+		}
+		discount = promo.Discount()
 	}
-	// 4. Update the Seat
+	price := pricing.Quote(tier, discount)
+
+	// 5. Update the Seat
 	_, err = tx.Exec(ctx, `UPDATE seats SET status = 'booked' WHERE id = $1`, seatID)
 	if err != nil {
-		return fmt.Errorf("failed to update seat status: %w", err)
+		return nil, fmt.Errorf("failed to update seat status: %w", err)
 	}
 
-	// 5. Create the Booking Record
-	_, err = tx.Exec(ctx, `INSERT INTO bookings (seat_id, user_id) VALUES ($1, $2)`, seatID, userID)
+	// 6. Create the Booking Record with the breakdown we charged
+	booking := &Booking{SeatID: seatID, UserID: userID, Status: "confirmed", Price: price}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO bookings (seat_id, event_id, user_id, amount, currency, face_value, discount, service_fee, facility_fee, tax)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at`,
+		seatID, eventID, userID, price.Total, price.Currency, price.FaceValue, price.Discount, price.ServiceFee, price.FacilityFee, price.Tax,
+	).Scan(&booking.ID, &booking.CreatedAt)
 	if err != nil {
-		return fmt.Errorf("failed to insert booking: %w", err)
+		return nil, fmt.Errorf("failed to insert booking: %w", err)
+	}
+
+	// The authoritative usage check for the promo code
+	if promo != nil {
+		if err := r.promos.Redeem(ctx, tx, promo, userID, booking.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 7. Record the money in the ledger (same transaction, so no booking without an entry)
+	if price.NetFaceValue()+price.Tax > 0 {
+		entry := ledger.BookingEntry(int64(booking.ID), price.NetFaceValue(), price.Tax, price.Currency)
+		if _, err := r.ledger.Post(ctx, tx, entry); err != nil {
+			return nil, fmt.Errorf("failed to record booking in ledger: %w", err)
+		}
+	}
+	if price.Fees() > 0 {
+		entry := ledger.FeeEntry(int64(booking.ID), price.Fees(), price.Currency)
+		if _, err := r.ledger.Post(ctx, tx, entry); err != nil {
+			return nil, fmt.Errorf("failed to record fees in ledger: %w", err)
+		}
 	}
 
-	// 6. Commit (Make it permanent)
+	// 8. Commit (Make it permanent)
 	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return nil
+	return booking, nil
+}
+
+// SeatEventLimit returns the event a seat belongs to and that event's per-user ticket limit.
+// Both are nil for seats without an event or events without a limit.
+func (r *Repository) SeatEventLimit(ctx context.Context, seatID int32) (*int32, *int32, error) {
+	var eventID, limit *int32
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT s.event_id, e.max_tickets_per_user
+		FROM seats s LEFT JOIN events e ON e.id = s.event_id
+		WHERE s.id = $1`, seatID).Scan(&eventID, &limit)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, nil, err
+	}
+	return eventID, limit, nil
+}
+
+// CountUserTickets returns how many confirmed tickets (seats and GA admissions) the user holds for the event
+func (r *Repository) CountUserTickets(ctx context.Context, eventID, userID int32) (int64, error) {
+	return CountUserTickets(ctx, r.db.Pool, eventID, userID)
+}
+
+// CountUserTickets is the in-transaction form, for checking the per-user limit under the advisory lock
+func CountUserTickets(ctx context.Context, q pricing.Querier, eventID, userID int32) (int64, error) {
+	var held int64
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*) FROM bookings
+		WHERE event_id = $1 AND user_id = $2 AND status = 'confirmed'`, eventID, userID).Scan(&held)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tickets: %w", err)
+	}
+	return held, nil
 }
@@ -0,0 +1,321 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Availability bitmaps: one bit per seat of an event, 1 = can be booked right now
+// (available in Postgres and not locked). Bit i is the event's i-th seat in ID order, most
+// significant bit first, which is how Redis SETBIT/GETBIT number them. The meta hash holds
+// the bitmap's size and each seat's bit ("size" and "<seatID>" fields), so a bitmap is as
+// long as the event has seats, whatever IDs other events' seats took in between.
+const (
+	availabilityEventsKey    = "availability:events"    // Events that have a bitmap, for the reconciler
+	availabilityReconcileKey = "availability:reconcile" // Held by the replica reconciling this period
+)
+
+// Seats per EVAL/MGET when a bitmap is rebuilt, so a large venue doesn't block Redis in one call
+const availabilityBatchSize = 1000
+
+func availabilityKey(eventID int32) string {
+	return fmt.Sprintf("availability:%d", eventID)
+}
+
+func availabilityMetaKey(eventID int32) string {
+	return fmt.Sprintf("availability:%d:meta", eventID)
+}
+
+// availabilityLockedKey is the set of seats whose bit a lock cleared, for SweepExpiredLocks.
+// The scripts derive it from the meta key, so the two must stay in step.
+func availabilityLockedKey(eventID int32) string {
+	return availabilityMetaKey(eventID) + ":locked"
+}
+
+// SeatBit names the bitmap bit a seat lock should keep in step with. Passing nil to the
+// lock functions leaves bitmaps alone.
+type SeatBit struct {
+	EventID int32
+	SeatID  int32
+}
+
+func (b *SeatBit) keys() []string {
+	return []string{availabilityKey(b.EventID), availabilityMetaKey(b.EventID)}
+}
+
+// markSeatLua defines mark(bitmap, meta, seatID, bit). It is prepended to the lock scripts so
+// the bit flips in the same atomic step as the lock. A cleared seat is remembered in the
+// locked set until it is marked again, so its bit comes back if the lock just expires.
+// Seats not in the bitmap are ignored; a missing bitmap is rebuilt from Postgres on the next read.
+const markSeatLua = `
+	local function mark(bitmap, meta, seat, bit)
+		local offset = redis.call("HGET", meta, tostring(seat))
+		if offset then
+			redis.call("SETBIT", bitmap, tonumber(offset), bit)
+			if bit == 0 then
+				redis.call("SADD", meta .. ":locked", seat)
+			else
+				redis.call("SREM", meta .. ":locked", seat)
+			end
+		end
+	end
+`
+
+// Availability is one event's bitmap
+type Availability struct {
+	Size    int32   // Number of bits in use
+	Bitmap  []byte  // Bit i is SeatIDs[i]
+	SeatIDs []int32 // The event's seats in bit order; only filled when asked for
+	Version int64   // Seat inventory version it was built from; only used by StoreAvailability
+}
+
+// GetAvailability reads an event's bitmap; nil if it hasn't been built.
+// withSeats also loads which seat each bit stands for.
+func (r *RedisStore) GetAvailability(ctx context.Context, eventID int32, withSeats bool) (*Availability, error) {
+	var bitmap *redis.StringCmd
+	var meta *redis.MapStringStringCmd
+	var size *redis.StringCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		bitmap = pipe.Get(ctx, availabilityKey(eventID))
+		if withSeats {
+			meta = pipe.HGetAll(ctx, availabilityMetaKey(eventID))
+		} else {
+			size = pipe.HGet(ctx, availabilityMetaKey(eventID), "size")
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("redis execution failed: %w", err)
+	}
+
+	var fields map[string]string
+	if withSeats {
+		fields = meta.Val()
+	} else if size.Err() == nil {
+		fields = map[string]string{"size": size.Val()}
+	}
+	n, err := strconv.ParseInt(fields["size"], 10, 32)
+	if err != nil {
+		return nil, nil // Missing or corrupt: treat as not built so it gets rebuilt
+	}
+
+	a := &Availability{Size: int32(n), Bitmap: []byte(bitmap.Val())}
+	// SETBIT never shrinks the string, but GET of a bitmap that was never written is empty
+	if need := int(a.Size+7) / 8; len(a.Bitmap) < need {
+		a.Bitmap = append(a.Bitmap, make([]byte, need-len(a.Bitmap))...)
+	}
+	if withSeats {
+		a.SeatIDs = make([]int32, a.Size)
+		for field, value := range fields {
+			seatID, err1 := strconv.ParseInt(field, 10, 32)
+			bit, err2 := strconv.ParseInt(value, 10, 32)
+			if err1 == nil && err2 == nil && bit >= 0 && bit < int64(a.Size) {
+				a.SeatIDs[bit] = int32(seatID)
+			}
+		}
+	}
+	return a, nil
+}
+
+// StoreAvailability replaces an event's bitmap with one built from Postgres (a.SeatIDs must
+// be set). Seats locked in Redis are cleared before the swap, and once more afterwards for
+// locks taken while it ran; both passes go in batches of availabilityBatchSize seats.
+// The locked seats start the new locked set, and a.Version is kept for AvailabilityVersion.
+// It reports whether the stored bitmap differed (drift), which is false for a first build.
+func (r *RedisStore) StoreAvailability(ctx context.Context, eventID int32, a Availability) (bool, error) {
+	var available []int32
+	for i, id := range a.SeatIDs {
+		if a.Bitmap[i/8]&(0x80>>(i%8)) != 0 {
+			available = append(available, id)
+		}
+	}
+
+	// 1. Clear the seats that are locked right now
+	bitOf := make(map[int32]int, len(a.SeatIDs))
+	for i, id := range a.SeatIDs {
+		bitOf[id] = i
+	}
+	var locked []interface{}
+	for batch := range slices.Chunk(available, availabilityBatchSize) {
+		held, err := r.HeldSeats(ctx, batch)
+		if err != nil {
+			return false, err
+		}
+		for id := range held {
+			i := bitOf[id]
+			a.Bitmap[i/8] &^= 0x80 >> (i % 8)
+			locked = append(locked, id)
+		}
+	}
+
+	// 2. Write the seat index and locked set under temporary keys, then swap them in with the bitmap
+	staging := availabilityMetaKey(eventID) + ":" + strconv.FormatInt(rand.Int63(), 36)
+	stagingLocked := staging + ":locked"
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, staging, "size", a.Size, "version", a.Version)
+		for batch := range slices.Chunk(a.SeatIDs, availabilityBatchSize) {
+			fields := make([]interface{}, 0, 2*len(batch))
+			for _, id := range batch {
+				fields = append(fields, id, bitOf[id])
+			}
+			pipe.HSet(ctx, staging, fields...)
+		}
+		pipe.Expire(ctx, staging, time.Minute) // Gone on its own if we die before the swap
+		for batch := range slices.Chunk(locked, availabilityBatchSize) {
+			pipe.SAdd(ctx, stagingLocked, batch...)
+		}
+		pipe.Expire(ctx, stagingLocked, time.Minute)
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("redis execution failed: %w", err)
+	}
+
+	script := `
+		local previous = redis.call("GET", KEYS[1])
+		local built = redis.call("EXISTS", KEYS[2]) == 1
+		redis.call("SET", KEYS[1], ARGV[1])
+		redis.call("RENAME", KEYS[4], KEYS[2])
+		redis.call("PERSIST", KEYS[2])
+		if redis.call("EXISTS", KEYS[6]) == 1 then
+			redis.call("RENAME", KEYS[6], KEYS[5])
+			redis.call("PERSIST", KEYS[5])
+		else
+			redis.call("DEL", KEYS[5])
+		end
+		redis.call("SADD", KEYS[3], ARGV[2])
+		if built and previous ~= ARGV[1] then
+			return 1
+		end
+		return 0
+	`
+	keys := []string{availabilityKey(eventID), availabilityMetaKey(eventID), availabilityEventsKey, staging,
+		availabilityLockedKey(eventID), stagingLocked}
+	drift, err := r.client.Eval(ctx, script, keys, a.Bitmap, eventID).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis execution failed: %w", err)
+	}
+
+	// 3. A lock taken between 1 and 2 flipped a bit in the old bitmap; clear it in the new one
+	script = markSeatLua + `
+		for i = 3, #KEYS do
+			if redis.call("EXISTS", KEYS[i]) == 1 then
+				mark(KEYS[1], KEYS[2], ARGV[i - 2], 0)
+			end
+		end
+		return 0
+	`
+	for batch := range slices.Chunk(available, availabilityBatchSize) {
+		keys := []string{availabilityKey(eventID), availabilityMetaKey(eventID)}
+		args := make([]interface{}, 0, len(batch))
+		for _, id := range batch {
+			keys = append(keys, SeatLockKey(id))
+			args = append(args, id)
+		}
+		if err := r.client.Eval(ctx, script, keys, args...).Err(); err != nil {
+			return false, fmt.Errorf("redis execution failed: %w", err)
+		}
+	}
+	return drift == 1, nil
+}
+
+// DropAvailability deletes an event's bitmap so the next read rebuilds it,
+// e.g. after seats were added or the event was cancelled
+func (r *RedisStore) DropAvailability(ctx context.Context, eventID int32) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, availabilityKey(eventID), availabilityMetaKey(eventID), availabilityLockedKey(eventID))
+		pipe.SRem(ctx, availabilityEventsKey, eventID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
+
+// AvailabilityVersion is the seat inventory version the event's bitmap was built from,
+// -1 if it has none (not built, or built before versions were kept)
+func (r *RedisStore) AvailabilityVersion(ctx context.Context, eventID int32) (int64, error) {
+	version, err := r.client.HGet(ctx, availabilityMetaKey(eventID), "version").Int64()
+	if err == redis.Nil {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("redis execution failed: %w", err)
+	}
+	return version, nil
+}
+
+// SweepExpiredLocks sets the bit back for every seat in the event's locked set whose lock is
+// gone without the seat being released or sold: an abandoned hold, a crashed request.
+// It returns how many seats it restored.
+func (r *RedisStore) SweepExpiredLocks(ctx context.Context, eventID int32) (int, error) {
+	script := markSeatLua + `
+		local restored = 0
+		for _, seat in ipairs(redis.call("SMEMBERS", KEYS[3])) do
+			if redis.call("EXISTS", ARGV[1] .. seat) == 0 then
+				mark(KEYS[1], KEYS[2], seat, 1)
+				restored = restored + 1
+			end
+		end
+		return restored
+	`
+	keys := []string{availabilityKey(eventID), availabilityMetaKey(eventID), availabilityLockedKey(eventID)}
+	restored, err := r.client.Eval(ctx, script, keys, seatLockPrefix).Int()
+	if err != nil {
+		return 0, fmt.Errorf("redis execution failed: %w", err)
+	}
+	return restored, nil
+}
+
+// ClaimReconcile lets one replica reconcile the bitmaps per period: it returns true for the
+// first caller, false for everyone else until the claim expires
+func (r *RedisStore) ClaimReconcile(ctx context.Context, period time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(ctx, availabilityReconcileKey, 1, period).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis execution failed: %w", err)
+	}
+	return ok, nil
+}
+
+// AvailabilityEvents lists the events that currently have a bitmap
+func (r *RedisStore) AvailabilityEvents(ctx context.Context) ([]int32, error) {
+	members, err := r.client.SMembers(ctx, availabilityEventsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis execution failed: %w", err)
+	}
+	ids := make([]int32, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 32)
+		if err == nil {
+			ids = append(ids, int32(id))
+		}
+	}
+	return ids, nil
+}
+
+// MarkSeatBooked is called once the booking has committed: the lock now stands for a sold
+// seat, so a later release of the buyer's hold must neither drop it nor flip the bit back.
+func (r *RedisStore) MarkSeatBooked(ctx context.Context, keyName string, value interface{}, bit *SeatBit) error {
+	script := markSeatLua + `
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			redis.call("SET", KEYS[1], "booked", "KEEPTTL")
+		end
+		if KEYS[2] then
+			mark(KEYS[2], KEYS[3], ARGV[2], 0)
+			redis.call("SREM", KEYS[3] .. ":locked", ARGV[2])
+		end
+		return 1
+	`
+	keys, seatID := withBit([]string{keyName}, bit)
+	if err := r.client.Eval(ctx, script, keys, value, seatID).Err(); err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
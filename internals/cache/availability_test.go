@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	return NewRedisStore(mr.Addr(), ""), mr
+}
+
+// snapshot is what the availability repository builds: every seat available in Postgres
+func snapshot(seatIDs ...int32) Availability {
+	a := Availability{Size: int32(len(seatIDs)), Bitmap: make([]byte, (len(seatIDs)+7)/8), SeatIDs: seatIDs}
+	for i := range seatIDs {
+		a.Bitmap[i/8] |= 0x80 >> (i % 8)
+	}
+	return a
+}
+
+func bits(a *Availability) string {
+	out := make([]byte, a.Size)
+	for i := range out {
+		out[i] = '0'
+		if a.Bitmap[i/8]&(0x80>>(i%8)) != 0 {
+			out[i] = '1'
+		}
+	}
+	return string(out)
+}
+
+func TestAvailabilityUsesDenseSeatIndex(t *testing.T) {
+	store, _ := newTestStore(t)
+	ctx := context.Background()
+
+	// Far-apart IDs, as when other events' seats were created in between
+	if _, err := store.StoreAvailability(ctx, 1, snapshot(10, 500, 90000)); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.GetAvailability(ctx, 1, true)
+	if err != nil || a == nil {
+		t.Fatalf("GetAvailability = %v, %v", a, err)
+	}
+	if a.Size != 3 || len(a.Bitmap) != 1 || bits(a) != "111" {
+		t.Errorf("bitmap = %d bits %q in %d bytes, want 3 bits 111 in 1 byte", a.Size, bits(a), len(a.Bitmap))
+	}
+	if len(a.SeatIDs) != 3 || a.SeatIDs[0] != 10 || a.SeatIDs[1] != 500 || a.SeatIDs[2] != 90000 {
+		t.Errorf("seat index = %v, want [10 500 90000]", a.SeatIDs)
+	}
+
+	// Locks flip the seat's own bit
+	if _, err := store.HoldSeats(ctx, 1, []int32{90000}, "user:1", 60); err != nil {
+		t.Fatal(err)
+	}
+	a, _ = store.GetAvailability(ctx, 1, false)
+	if bits(a) != "110" {
+		t.Errorf("after hold = %q, want 110", bits(a))
+	}
+	if a.SeatIDs != nil {
+		t.Errorf("seat index loaded without being asked for")
+	}
+
+	// A seat that isn't in the event's bitmap leaves it alone
+	if err := store.AtomicBook(ctx, SeatLockKey(20), "user:2", 60, &SeatBit{EventID: 1, SeatID: 20}); err != nil {
+		t.Fatal(err)
+	}
+	a, _ = store.GetAvailability(ctx, 1, false)
+	if bits(a) != "110" || len(a.Bitmap) != 1 {
+		t.Errorf("after foreign lock = %q (%d bytes), want 110 in 1 byte", bits(a), len(a.Bitmap))
+	}
+
+	if _, err := store.ReleaseSeats(ctx, 1, []int32{90000}, "user:1"); err != nil {
+		t.Fatal(err)
+	}
+	a, _ = store.GetAvailability(ctx, 1, false)
+	if bits(a) != "111" {
+		t.Errorf("after release = %q, want 111", bits(a))
+	}
+}
+
+func TestStoreAvailabilityKeepsLocksAndReportsDrift(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+
+	// More seats than one batch, with locks in several batches
+	ids := make([]int32, 2*availabilityBatchSize+500)
+	for i := range ids {
+		ids[i] = int32(1000 + 3*i)
+	}
+	locked := []int{0, availabilityBatchSize - 1, availabilityBatchSize, len(ids) - 1}
+	for _, i := range locked {
+		mr.Set(SeatLockKey(ids[i]), "user:9")
+	}
+
+	drifted, err := store.StoreAvailability(ctx, 7, snapshot(ids...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drifted {
+		t.Error("first build reported drift")
+	}
+	a, _ := store.GetAvailability(ctx, 7, false)
+	for _, i := range locked {
+		if a.Bitmap[i/8]&(0x80>>(i%8)) != 0 {
+			t.Errorf("locked seat %d (bit %d) shown available", ids[i], i)
+		}
+	}
+	if a.Bitmap[1/8]&(0x80>>1) == 0 {
+		t.Error("unlocked seat shown taken")
+	}
+
+	// Same state again: no drift
+	if drifted, err := store.StoreAvailability(ctx, 7, snapshot(ids...)); err != nil || drifted {
+		t.Errorf("rebuild of an unchanged bitmap = %v, %v; want no drift", drifted, err)
+	}
+
+	// A lock that expired without release left its bit cleared: the rebuild puts it back
+	mr.Del(SeatLockKey(ids[0]))
+	if drifted, err := store.StoreAvailability(ctx, 7, snapshot(ids...)); err != nil || !drifted {
+		t.Errorf("rebuild after a lost release = %v, %v; want drift", drifted, err)
+	}
+	a, _ = store.GetAvailability(ctx, 7, false)
+	if a.Bitmap[0]&0x80 == 0 {
+		t.Error("seat of the expired lock still shown taken")
+	}
+
+	// No staging keys are left behind
+	for _, key := range mr.Keys() {
+		if key != availabilityLockedKey(7) && len(key) > len(availabilityMetaKey(7)) && key[:len(availabilityMetaKey(7))] == availabilityMetaKey(7) {
+			t.Errorf("staging key %s left over", key)
+		}
+	}
+}
+
+func TestSweepExpiredLocks(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	a := snapshot(1, 2, 3, 4, 5)
+	a.Version = 42
+	mr.Set(SeatLockKey(1), "user:9") // Locked while the bitmap is built
+	if _, err := store.StoreAvailability(ctx, 1, a); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := store.AvailabilityVersion(ctx, 1); err != nil || v != 42 {
+		t.Errorf("version = %d, %v; want 42", v, err)
+	}
+	if v, _ := store.AvailabilityVersion(ctx, 2); v != -1 {
+		t.Errorf("version of an unbuilt bitmap = %d, want -1", v)
+	}
+
+	for _, seat := range []int32{2, 3, 4} {
+		if err := store.AtomicBook(ctx, SeatLockKey(seat), "user:9", 60, &SeatBit{EventID: 1, SeatID: seat}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.MarkSeatBooked(ctx, SeatLockKey(3), "user:9", &SeatBit{EventID: 1, SeatID: 3})
+	store.ReleaseLock(ctx, SeatLockKey(4), "user:9", &SeatBit{EventID: 1, SeatID: 4})
+
+	// Seats 1 and 2 lose their locks without a release; 3 was sold, 4 released, 5 never locked
+	for _, seat := range []int32{1, 2, 3} {
+		mr.Del(SeatLockKey(seat))
+	}
+	restored, err := store.SweepExpiredLocks(ctx, 1)
+	if err != nil || restored != 2 {
+		t.Fatalf("SweepExpiredLocks = %d, %v; want 2", restored, err)
+	}
+	got, _ := store.GetAvailability(ctx, 1, false)
+	if bits(got) != "11011" {
+		t.Errorf("after sweep = %q, want 11011", bits(got))
+	}
+	if restored, _ := store.SweepExpiredLocks(ctx, 1); restored != 0 {
+		t.Errorf("second sweep restored %d", restored)
+	}
+}
+
+func TestGetAvailabilityMissing(t *testing.T) {
+	store, _ := newTestStore(t)
+	a, err := store.GetAvailability(context.Background(), 42, true)
+	if err != nil || a != nil {
+		t.Errorf("GetAvailability of an unbuilt event = %v, %v; want nil, nil", a, err)
+	}
+}
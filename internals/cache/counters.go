@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrQuotaExhausted     = errors.New("usage limit reached")
+	ErrUserQuotaExhausted = errors.New("per-user usage limit reached")
+)
+
+// counterTTL bounds how long a counter lives in Redis.
+// When it expires the caller re-seeds it from Postgres, which also heals any drift.
+const counterTTL = 24 * time.Hour
+
+// SeedCounter initialises a counter from the source of truth unless it already exists.
+// Returns true if the key was set by this call.
+func (r *RedisStore) SeedCounter(ctx context.Context, key string, value int64) (bool, error) {
+	ok, err := r.client.SetNX(ctx, key, value, counterTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis execution failed: %w", err)
+	}
+	return ok, nil
+}
+
+// CounterExists reports whether the counter is present (i.e. does not need seeding)
+func (r *RedisStore) CounterExists(ctx context.Context, key string) (bool, error) {
+	n, err := r.client.Exists(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis execution failed: %w", err)
+	}
+	return n == 1, nil
+}
+
+// ReserveUse atomically increments a global and a per-user counter,
+// unless either one has already reached its limit. A negative limit means unlimited.
+func (r *RedisStore) ReserveUse(ctx context.Context, globalKey, userKey string, globalLimit, userLimit int64) error {
+	// --- THE LUA SCRIPT ---
+	// Both checks and both increments happen in one step, so a 100-use code
+	// can never be redeemed 101 times no matter how many replicas race.
+	script := `
+		local total = tonumber(redis.call("GET", KEYS[1]) or "0")
+		local user = tonumber(redis.call("GET", KEYS[2]) or "0")
+		if tonumber(ARGV[1]) >= 0 and total >= tonumber(ARGV[1]) then
+			return -1
+		end
+		if tonumber(ARGV[2]) >= 0 and user >= tonumber(ARGV[2]) then
+			return -2
+		end
+		redis.call("INCR", KEYS[1])
+		redis.call("INCR", KEYS[2])
+		redis.call("EXPIRE", KEYS[1], ARGV[3])
+		redis.call("EXPIRE", KEYS[2], ARGV[3])
+		return 1
+	`
+
+	result, err := r.client.Eval(ctx, script, []string{globalKey, userKey},
+		globalLimit, userLimit, int(counterTTL.Seconds())).Int()
+	if err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+
+	switch result {
+	case -1:
+		return ErrQuotaExhausted
+	case -2:
+		return ErrUserQuotaExhausted
+	}
+	return nil
+}
+
+// ReleaseUse gives back a reservation made by ReserveUse (e.g. the booking failed)
+func (r *RedisStore) ReleaseUse(ctx context.Context, globalKey, userKey string) error {
+	script := `
+		for _, key in ipairs(KEYS) do
+			if tonumber(redis.call("GET", key) or "0") > 0 then
+				redis.call("DECR", key)
+			end
+		end
+		return 1
+	`
+	if err := r.client.Eval(ctx, script, []string{globalKey, userKey}).Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
+
+// ReserveCount adds n to a counter unless that would take it past limit, in one step.
+// It is the multi-unit form of the check in AtomicBookWithLimit, for sales without a seat lock.
+func (r *RedisStore) ReserveCount(ctx context.Context, key string, n, limit int64) error {
+	script := `
+		if tonumber(redis.call("GET", KEYS[1]) or "0") + tonumber(ARGV[1]) > tonumber(ARGV[2]) then
+			return -1
+		end
+		redis.call("INCRBY", KEYS[1], ARGV[1])
+		redis.call("EXPIRE", KEYS[1], ARGV[3])
+		return 1
+	`
+	result, err := r.client.Eval(ctx, script, []string{key}, n, limit, int(counterTTL.Seconds())).Int()
+	if err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	if result == -1 {
+		return ErrUserQuotaExhausted
+	}
+	return nil
+}
+
+// ReleaseCount gives back n taken by ReserveCount, never going below zero
+func (r *RedisStore) ReleaseCount(ctx context.Context, key string, n int64) error {
+	script := `
+		local value = tonumber(redis.call("GET", KEYS[1]) or "0")
+		if value > 0 then
+			redis.call("DECRBY", KEYS[1], math.min(value, tonumber(ARGV[1])))
+		end
+		return 1
+	`
+	if err := r.client.Eval(ctx, script, []string{key}, n).Err(); err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
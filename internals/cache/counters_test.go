@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReserveCount(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	key := "purchase_count:1:2"
+
+	if _, err := store.SeedCounter(ctx, key, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReserveCount(ctx, key, 3, 4); err != nil {
+		t.Fatalf("ReserveCount up to the limit: %v", err)
+	}
+	if err := store.ReserveCount(ctx, key, 1, 4); !errors.Is(err, ErrUserQuotaExhausted) {
+		t.Fatalf("ReserveCount past the limit = %v, want ErrUserQuotaExhausted", err)
+	}
+	if v, _ := mr.Get(key); v != "4" {
+		t.Errorf("counter = %s after a refused reservation, want 4", v)
+	}
+
+	if err := store.ReleaseCount(ctx, key, 3); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := mr.Get(key); v != "1" {
+		t.Errorf("counter = %s after release, want 1", v)
+	}
+	// Never below zero, e.g. when the counter was reseeded in between
+	store.ReleaseCount(ctx, key, 5)
+	if v, _ := mr.Get(key); v != "0" {
+		t.Errorf("counter = %s, want 0", v)
+	}
+}
+
+func TestReserveUse(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	global, alice, bob := "promo_uses:1", "promo_user_uses:1:1", "promo_user_uses:1:2"
+
+	// Three uses in all, two per user
+	for i := 0; i < 2; i++ {
+		if err := store.ReserveUse(ctx, global, alice, 3, 2); err != nil {
+			t.Fatalf("use %d: %v", i+1, err)
+		}
+	}
+	if err := store.ReserveUse(ctx, global, alice, 3, 2); !errors.Is(err, ErrUserQuotaExhausted) {
+		t.Fatalf("third use by the same user = %v, want ErrUserQuotaExhausted", err)
+	}
+	if err := store.ReserveUse(ctx, global, bob, 3, 2); err != nil {
+		t.Fatalf("another user's use: %v", err)
+	}
+	if err := store.ReserveUse(ctx, global, "promo_user_uses:1:3", 3, 2); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("fourth use = %v, want ErrQuotaExhausted", err)
+	}
+	if v, _ := mr.Get(global); v != "3" {
+		t.Errorf("global counter = %s after refusals, want 3", v)
+	}
+
+	// A failed booking gives its use back
+	if err := store.ReleaseUse(ctx, global, bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReserveUse(ctx, global, "promo_user_uses:1:3", 3, 2); err != nil {
+		t.Errorf("use after a release: %v", err)
+	}
+
+	// Unlimited on both counts
+	for i := 0; i < 5; i++ {
+		if err := store.ReserveUse(ctx, "promo_uses:2", "promo_user_uses:2:1", -1, -1); err != nil {
+			t.Fatalf("unlimited code refused: %v", err)
+		}
+	}
+
+	// Releasing counters that expired meanwhile doesn't go negative
+	mr.Del(global)
+	store.ReleaseUse(ctx, global, alice)
+	if mr.Exists(global) {
+		t.Errorf("release created the expired counter")
+	}
+	if v, _ := mr.Get(alice); v != "1" {
+		t.Errorf("user counter = %s, want 1", v)
+	}
+}
@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func denylistKey(jti string) string {
+	return "jwt_denylist:" + jti
+}
+
+// DenyToken revokes an access token by its jti until it would have expired anyway
+func (r *RedisStore) DenyToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil // Already expired, nothing to deny
+	}
+	if err := r.client.Set(ctx, denylistKey(jti), 1, ttl).Err(); err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
+
+// IsTokenDenied reports whether the access token was revoked
+func (r *RedisStore) IsTokenDenied(ctx context.Context, jti string) (bool, error) {
+	n, err := r.client.Exists(ctx, denylistKey(jti)).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis execution failed: %w", err)
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// General-admission inventory. Per ticket type:
+//
+//	ga:<type>:available  how many can still be held (capacity - sold - active holds), never below 0
+//	ga:<type>:holds      sorted set of hold IDs by expiry (unix seconds)
+//	ga:<type>:hold_info  hash of hold ID -> "<owner>:<quantity>"
+//
+// Expired holds are swept back into the counter by the next script that touches the type,
+// so abandoned checkouts return their tickets without a background job.
+var (
+	ErrGANotSeeded    = errors.New("general admission counter is not seeded")
+	ErrGASoldOut      = errors.New("not enough tickets left")
+	ErrGAHoldNotFound = errors.New("hold not found or expired")
+)
+
+func gaKeys(typeID int32) []string {
+	return []string{
+		fmt.Sprintf("ga:%d:available", typeID),
+		fmt.Sprintf("ga:%d:holds", typeID),
+		fmt.Sprintf("ga:%d:hold_info", typeID),
+	}
+}
+
+// sweepGALua defines sweep(now): give the quantity of every expired hold back to the counter
+const sweepGALua = `
+	local function quantity(info)
+		return tonumber(string.match(info, ":(%d+)$"))
+	end
+	local function sweep(now)
+		local expired = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", now)
+		for _, id in ipairs(expired) do
+			local info = redis.call("HGET", KEYS[3], id)
+			if info and redis.call("EXISTS", KEYS[1]) == 1 then
+				redis.call("INCRBY", KEYS[1], quantity(info))
+			end
+			redis.call("HDEL", KEYS[3], id)
+			redis.call("ZREM", KEYS[2], id)
+		end
+	end
+`
+
+// SeedGA initialises the counter from Postgres (capacity - sold) unless it exists.
+// Holds that are still active are subtracted, so reseeding after the counter expired
+// doesn't hand out their tickets twice.
+func (r *RedisStore) SeedGA(ctx context.Context, typeID int32, remaining int64) error {
+	script := sweepGALua + `
+		if redis.call("EXISTS", KEYS[1]) == 1 then
+			return 0
+		end
+		sweep(ARGV[2])
+		local held = 0
+		for _, info in ipairs(redis.call("HVALS", KEYS[3])) do
+			held = held + quantity(info)
+		end
+		redis.call("SET", KEYS[1], math.max(tonumber(ARGV[1]) - held, 0), "EX", ARGV[3])
+		return 1
+	`
+	err := r.client.Eval(ctx, script, gaKeys(typeID), remaining, time.Now().Unix(), int(counterTTL.Seconds())).Err()
+	if err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
+
+// ReserveGA takes quantity tickets off the counter, never letting it go below zero, and
+// records them as a hold for owner until expiresAt. Returns ErrGANotSeeded if the counter
+// needs seeding first, ErrGASoldOut if there aren't enough.
+func (r *RedisStore) ReserveGA(ctx context.Context, typeID int32, holdID string, owner int32, quantity int, expiresAt time.Time) error {
+	script := sweepGALua + `
+		if redis.call("EXISTS", KEYS[1]) == 0 then
+			return -2
+		end
+		sweep(ARGV[4])
+		if tonumber(redis.call("GET", KEYS[1])) < tonumber(ARGV[1]) then
+			return -1
+		end
+		redis.call("DECRBY", KEYS[1], ARGV[1])
+		redis.call("ZADD", KEYS[2], ARGV[5], ARGV[3])
+		redis.call("HSET", KEYS[3], ARGV[3], ARGV[2] .. ":" .. ARGV[1])
+		return 1
+	`
+	result, err := r.client.Eval(ctx, script, gaKeys(typeID),
+		quantity, owner, holdID, time.Now().Unix(), expiresAt.Unix()).Int()
+	if err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	switch result {
+	case -2:
+		return ErrGANotSeeded
+	case -1:
+		return ErrGASoldOut
+	}
+	return nil
+}
+
+// TakeGAHold removes the owner's live hold and returns its quantity. The tickets stay off the
+// counter: the caller either sells them or gives them back with ReturnGA.
+func (r *RedisStore) TakeGAHold(ctx context.Context, typeID int32, holdID string, owner int32) (int, error) {
+	return r.endGAHold(ctx, typeID, holdID, owner, false)
+}
+
+// CancelGAHold drops the owner's hold and puts its tickets back on the counter
+func (r *RedisStore) CancelGAHold(ctx context.Context, typeID int32, holdID string, owner int32) (int, error) {
+	return r.endGAHold(ctx, typeID, holdID, owner, true)
+}
+
+func (r *RedisStore) endGAHold(ctx context.Context, typeID int32, holdID string, owner int32, giveBack bool) (int, error) {
+	script := sweepGALua + `
+		sweep(ARGV[3])
+		local info = redis.call("HGET", KEYS[3], ARGV[1])
+		if not info or info ~= ARGV[2] .. ":" .. quantity(info) then
+			return 0
+		end
+		redis.call("HDEL", KEYS[3], ARGV[1])
+		redis.call("ZREM", KEYS[2], ARGV[1])
+		if ARGV[4] == "1" and redis.call("EXISTS", KEYS[1]) == 1 then
+			redis.call("INCRBY", KEYS[1], quantity(info))
+		end
+		return quantity(info)
+	`
+	flag := "0"
+	if giveBack {
+		flag = "1"
+	}
+	quantity, err := r.client.Eval(ctx, script, gaKeys(typeID), holdID, owner, time.Now().Unix(), flag).Int()
+	if err != nil {
+		return 0, fmt.Errorf("redis execution failed: %w", err)
+	}
+	if quantity == 0 {
+		return 0, ErrGAHoldNotFound
+	}
+	return quantity, nil
+}
+
+// ReturnGA gives tickets taken with TakeGAHold back when the sale didn't go through.
+// A counter that has expired in the meantime is left alone: its reseed reads Postgres.
+func (r *RedisStore) ReturnGA(ctx context.Context, typeID int32, quantity int) error {
+	script := `
+		if redis.call("EXISTS", KEYS[1]) == 1 then
+			redis.call("INCRBY", KEYS[1], ARGV[1])
+		end
+		return 1
+	`
+	if err := r.client.Eval(ctx, script, gaKeys(typeID)[:1], quantity).Err(); err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
+
+// GAAvailable reads the counter; ok is false when it isn't seeded
+func (r *RedisStore) GAAvailable(ctx context.Context, typeID int32) (int64, bool, error) {
+	n, err := r.client.Get(ctx, gaKeys(typeID)[0]).Int64()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("redis execution failed: %w", err)
+	}
+	return n, true, nil
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Failed logins are counted per account and per client IP. Each key pairs a
+// failure counter with a lock whose TTL is the time the caller must wait.
+
+func loginFailKey(scope, id string) string {
+	return "login_fail:" + scope + ":" + id
+}
+
+func loginLockKey(scope, id string) string {
+	return "login_lock:" + scope + ":" + id
+}
+
+// LoginBackoff describes how failures on one key turn into waiting time
+type LoginBackoff struct {
+	Window       time.Duration // Failures older than this are forgotten
+	FreeAttempts int64         // Failures allowed before any delay kicks in
+	BaseDelay    time.Duration // Delay after the first failure past FreeAttempts, doubled for each one after
+	MaxDelay     time.Duration // Cap on the delay; reaching it is a lockout
+}
+
+// LoginLockedFor returns how long the caller must still wait, the longest of all given scopes.
+// ids maps scope ("account", "ip") to the identifier in that scope.
+func (r *RedisStore) LoginLockedFor(ctx context.Context, ids map[string]string) (time.Duration, error) {
+	var longest time.Duration
+	for scope, id := range ids {
+		ttl, err := r.client.PTTL(ctx, loginLockKey(scope, id)).Result()
+		if err != nil {
+			return 0, fmt.Errorf("redis execution failed: %w", err)
+		}
+		if ttl > longest { // Missing keys come back negative
+			longest = ttl
+		}
+	}
+	return longest, nil
+}
+
+// RecordLoginFailure counts a failed attempt and, past the free attempts, locks the
+// key for an exponentially growing delay. Returns the failure count and the delay set (0 for none).
+func (r *RedisStore) RecordLoginFailure(ctx context.Context, scope, id string, b LoginBackoff) (int64, time.Duration, error) {
+	// --- THE LUA SCRIPT ---
+	// Count and lock in one step so parallel guesses can't slip between them.
+	script := `
+		local n = redis.call("INCR", KEYS[1])
+		redis.call("PEXPIRE", KEYS[1], ARGV[1])
+		local over = n - tonumber(ARGV[2])
+		if over <= 0 then
+			return {n, 0}
+		end
+		local delay = tonumber(ARGV[3]) * 2 ^ (over - 1)
+		if delay > tonumber(ARGV[4]) then
+			delay = tonumber(ARGV[4])
+		end
+		redis.call("SET", KEYS[2], n, "PX", math.floor(delay))
+		return {n, math.floor(delay)}
+	`
+
+	result, err := r.client.Eval(ctx, script, []string{loginFailKey(scope, id), loginLockKey(scope, id)},
+		b.Window.Milliseconds(), b.FreeAttempts, b.BaseDelay.Milliseconds(), b.MaxDelay.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, fmt.Errorf("redis execution failed: %w", err)
+	}
+	return result[0], time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// ClearLoginFailures forgets the failures and any lock for one key, e.g. after a successful login
+func (r *RedisStore) ClearLoginFailures(ctx context.Context, scope, id string) error {
+	if err := r.client.Del(ctx, loginFailKey(scope, id), loginLockKey(scope, id)).Err(); err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
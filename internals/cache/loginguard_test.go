@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	b := LoginBackoff{Window: 15 * time.Minute, FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	// Free attempts, then 1s, 2s, 4s and the 5s cap
+	want := []time.Duration{0, 0, 0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		n, delay, err := store.RecordLoginFailure(ctx, "account", "bob@example.com", b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(i+1) || delay != w {
+			t.Errorf("failure %d: count %d, delay %s; want %s", i+1, n, delay, w)
+		}
+	}
+
+	ids := map[string]string{"account": "bob@example.com", "ip": "203.0.113.9"}
+	if wait, err := store.LoginLockedFor(ctx, ids); err != nil || wait <= 4*time.Second || wait > 5*time.Second {
+		t.Errorf("LoginLockedFor = %s, %v; want the account's 5s", wait, err)
+	}
+
+	// The lock lapses on its own; the count only after the window
+	mr.FastForward(5 * time.Second)
+	if wait, _ := store.LoginLockedFor(ctx, ids); wait != 0 {
+		t.Errorf("still locked for %s after the delay", wait)
+	}
+	if n, _, _ := store.RecordLoginFailure(ctx, "account", "bob@example.com", b); n != 9 {
+		t.Errorf("count = %d inside the window, want 9", n)
+	}
+	mr.FastForward(16 * time.Minute)
+	if n, delay, _ := store.RecordLoginFailure(ctx, "account", "bob@example.com", b); n != 1 || delay != 0 {
+		t.Errorf("after the window: count %d, delay %s; want a fresh start", n, delay)
+	}
+
+	// Scopes are counted apart, and a successful login clears its own
+	for i := 0; i < 4; i++ {
+		store.RecordLoginFailure(ctx, "ip", "203.0.113.9", b)
+	}
+	if err := store.ClearLoginFailures(ctx, "account", "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := store.LoginLockedFor(ctx, ids); wait <= 0 {
+		t.Error("clearing the account lifted the IP's lock")
+	}
+	if mr.Exists(loginFailKey("account", "bob@example.com")) {
+		t.Error("account failures survived ClearLoginFailures")
+	}
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrChallengeInvalid = errors.New("mfa challenge is invalid or expired")
+
+func mfaChallengeKey(tokenHash string) string {
+	return "mfa_challenge:" + tokenHash
+}
+
+// StoreMFAChallenge remembers which user passed the password step, until ttl runs out
+func (r *RedisStore) StoreMFAChallenge(ctx context.Context, tokenHash string, userID int, ttl time.Duration) error {
+	key := mfaChallengeKey(tokenHash)
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", userID, "attempts", 0)
+	pipe.PExpire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
+
+// MFAChallengeUser spends one attempt on the challenge and returns its user.
+// After maxAttempts the challenge is deleted, so a stolen challenge token
+// can't be used to walk through the code space.
+func (r *RedisStore) MFAChallengeUser(ctx context.Context, tokenHash string, maxAttempts int) (int, error) {
+	script := `
+		local user = redis.call("HGET", KEYS[1], "user_id")
+		if not user then
+			return -1
+		end
+		if redis.call("HINCRBY", KEYS[1], "attempts", 1) > tonumber(ARGV[1]) then
+			redis.call("DEL", KEYS[1])
+			return -1
+		end
+		return tonumber(user)
+	`
+
+	userID, err := r.client.Eval(ctx, script, []string{mfaChallengeKey(tokenHash)}, maxAttempts).Int()
+	if err != nil {
+		return 0, fmt.Errorf("redis execution failed: %w", err)
+	}
+	if userID < 0 {
+		return 0, ErrChallengeInvalid
+	}
+	return userID, nil
+}
+
+// DeleteMFAChallenge makes the challenge single-use once it has been passed
+func (r *RedisStore) DeleteMFAChallenge(ctx context.Context, tokenHash string) error {
+	if err := r.client.Del(ctx, mfaChallengeKey(tokenHash)).Err(); err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
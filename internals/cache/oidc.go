@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrOIDCStateInvalid = errors.New("sign-in request is invalid or expired")
+
+func oidcStateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+// SaveOIDCState keeps what we need to finish a sign-in (nonce, PKCE verifier...) until the provider redirects back
+func (r *RedisStore) SaveOIDCState(ctx context.Context, state string, payload []byte, ttl time.Duration) error {
+	if err := r.client.Set(ctx, oidcStateKey(state), payload, ttl).Err(); err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
+
+// TakeOIDCState returns and deletes the saved state, so a callback URL can only be used once
+func (r *RedisStore) TakeOIDCState(ctx context.Context, state string) ([]byte, error) {
+	payload, err := r.client.GetDel(ctx, oidcStateKey(state)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrOIDCStateInvalid
+		}
+		return nil, fmt.Errorf("redis execution failed: %w", err)
+	}
+	return payload, nil
+}
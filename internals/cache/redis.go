@@ -23,18 +23,25 @@ func NewRedisStore(addr string, password string) *RedisStore {
 
 // AtomicBook attempts to lock a resource using a Lua Script.
 // We make the key generic so it can be used for things other than just seats if needed.
-func (r *RedisStore) AtomicBook(ctx context.Context, keyName string, value interface{}, expirySeconds int) error {
+// A lock already held with the same value (e.g. the user's own seat hold) is taken over.
+// With a bit, the seat is also cleared in its event's availability bitmap.
+func (r *RedisStore) AtomicBook(ctx context.Context, keyName string, value interface{}, expirySeconds int, bit *SeatBit) error {
 	// --- THE LUA SCRIPT ---
-	script := `
-		if redis.call("EXISTS", KEYS[1]) == 1 then
+	script := markSeatLua + `
+		local owner = redis.call("GET", KEYS[1])
+		if owner and owner ~= ARGV[1] then
 			return 0
 		end
 		redis.call("SET", KEYS[1], ARGV[1], "EX", ARGV[2])
+		if KEYS[2] then
+			mark(KEYS[2], KEYS[3], ARGV[3], 0)
+		end
 		return 1
 	`
 
 	// Execute
-	result, err := r.client.Eval(ctx, script, []string{keyName}, value, expirySeconds).Int()
+	keys, seatID := withBit([]string{keyName}, bit)
+	result, err := r.client.Eval(ctx, script, keys, value, expirySeconds, seatID).Int()
 
 	if err != nil {
 		return fmt.Errorf("redis execution failed: %w", err)
@@ -46,3 +53,90 @@ func (r *RedisStore) AtomicBook(ctx context.Context, keyName string, value inter
 
 	return nil
 }
+
+// AtomicBookWithLimit is AtomicBook plus a per-user purchase counter, checked and bumped in the same script.
+// Either the seat is locked AND the counter incremented, or nothing changes.
+func (r *RedisStore) AtomicBookWithLimit(ctx context.Context, keyName string, counterKey string, value interface{}, limit int64, expirySeconds int, bit *SeatBit) error {
+	script := markSeatLua + `
+		local owner = redis.call("GET", KEYS[1])
+		if owner and owner ~= ARGV[1] then
+			return 0
+		end
+		if tonumber(redis.call("GET", KEYS[2]) or "0") >= tonumber(ARGV[3]) then
+			return -1
+		end
+		redis.call("SET", KEYS[1], ARGV[1], "EX", ARGV[2])
+		redis.call("INCR", KEYS[2])
+		redis.call("EXPIRE", KEYS[2], ARGV[4])
+		if KEYS[3] then
+			mark(KEYS[3], KEYS[4], ARGV[5], 0)
+		end
+		return 1
+	`
+
+	keys, seatID := withBit([]string{keyName, counterKey}, bit)
+	result, err := r.client.Eval(ctx, script, keys,
+		value, expirySeconds, limit, int(counterTTL.Seconds()), seatID).Int()
+	if err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+
+	switch result {
+	case 0:
+		return errors.New("resource locked by another process")
+	case -1:
+		return ErrUserQuotaExhausted
+	}
+	return nil
+}
+
+// ReleaseBookWithLimit undoes AtomicBookWithLimit: drops our lock and gives the purchase back.
+// With a bit, the seat is marked available again; pass nil when the seat turned out to be sold.
+func (r *RedisStore) ReleaseBookWithLimit(ctx context.Context, keyName string, counterKey string, value interface{}, bit *SeatBit) error {
+	// The counter is always given back; the lock only if it is still ours.
+	script := markSeatLua + `
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			redis.call("DEL", KEYS[1])
+			if KEYS[3] then
+				mark(KEYS[3], KEYS[4], ARGV[2], 1)
+			end
+		end
+		if tonumber(redis.call("GET", KEYS[2]) or "0") > 0 then
+			redis.call("DECR", KEYS[2])
+		end
+		return 1
+	`
+	keys, seatID := withBit([]string{keyName, counterKey}, bit)
+	if err := r.client.Eval(ctx, script, keys, value, seatID).Err(); err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
+
+// ReleaseLock deletes a lock taken with AtomicBook, but only if we still own it.
+// Another request may have taken the key after our TTL expired.
+// With a bit, the seat is marked available again; pass nil when the seat turned out to be sold.
+func (r *RedisStore) ReleaseLock(ctx context.Context, keyName string, value interface{}, bit *SeatBit) error {
+	script := markSeatLua + `
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			if KEYS[2] then
+				mark(KEYS[2], KEYS[3], ARGV[2], 1)
+			end
+			return redis.call("DEL", KEYS[1])
+		end
+		return 0
+	`
+	keys, seatID := withBit([]string{keyName}, bit)
+	if err := r.client.Eval(ctx, script, keys, value, seatID).Err(); err != nil {
+		return fmt.Errorf("redis execution failed: %w", err)
+	}
+	return nil
+}
+
+// withBit appends the bitmap keys of the bit, if any, and returns the seat ID for ARGV
+func withBit(keys []string, bit *SeatBit) ([]string, int32) {
+	if bit == nil {
+		return keys, 0
+	}
+	return append(keys, bit.keys()...), bit.SeatID
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAtomicBookWithLimit(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	counter := "purchase_count:1:7"
+
+	// Two tickets per user
+	for _, seat := range []string{"seat_lock:1", "seat_lock:2"} {
+		if err := store.AtomicBookWithLimit(ctx, seat, counter, 7, 2, 60, nil); err != nil {
+			t.Fatalf("%s: %v", seat, err)
+		}
+	}
+	if err := store.AtomicBookWithLimit(ctx, "seat_lock:3", counter, 7, 2, 60, nil); !errors.Is(err, ErrUserQuotaExhausted) {
+		t.Fatalf("third seat = %v, want ErrUserQuotaExhausted", err)
+	}
+	if mr.Exists("seat_lock:3") {
+		t.Error("seat locked although the limit was reached")
+	}
+
+	// A seat someone else holds leaves our counter alone
+	if err := store.AtomicBookWithLimit(ctx, "seat_lock:1", "purchase_count:1:8", 8, 2, 60, nil); err == nil || errors.Is(err, ErrUserQuotaExhausted) {
+		t.Fatalf("taken seat = %v, want a lock error", err)
+	}
+	if mr.Exists("purchase_count:1:8") {
+		t.Error("counter bumped for a seat that was not locked")
+	}
+
+	// Giving one back frees both the seat and the purchase
+	if err := store.ReleaseBookWithLimit(ctx, "seat_lock:2", counter, 7, nil); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("seat_lock:2") {
+		t.Error("lock still held after release")
+	}
+	if v, _ := mr.Get(counter); v != "1" {
+		t.Errorf("counter = %s after release, want 1", v)
+	}
+	if err := store.AtomicBookWithLimit(ctx, "seat_lock:3", counter, 7, 2, 60, nil); err != nil {
+		t.Errorf("seat after a release: %v", err)
+	}
+
+	// Releasing a lock that expired and went to someone else still returns our purchase
+	mr.Set("seat_lock:1", "8")
+	store.ReleaseBookWithLimit(ctx, "seat_lock:1", counter, 7, nil)
+	if v, _ := mr.Get("seat_lock:1"); v != "8" {
+		t.Errorf("released another user's lock: %q", v)
+	}
+	if v, _ := mr.Get(counter); v != "1" {
+		t.Errorf("counter = %s, want 1", v)
+	}
+}
+
+func TestHoldSeatsWithLimit(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	counter := "purchase_count:1:7"
+	mr.Set(counter, "1") // One ticket already bought, four allowed
+
+	if _, err := store.HoldSeatsWithLimit(ctx, 1, []int32{1, 2}, 7, 60, counter, 4); err != nil {
+		t.Fatal(err)
+	}
+	// Holding the same seats again doesn't count them twice
+	if _, err := store.HoldSeatsWithLimit(ctx, 1, []int32{2, 1}, 7, 60, counter, 4); err != nil {
+		t.Errorf("re-holding own seats: %v", err)
+	}
+	if _, err := store.HoldSeatsWithLimit(ctx, 1, []int32{3, 4}, 7, 60, counter, 4); !errors.Is(err, ErrUserQuotaExhausted) {
+		t.Fatalf("past the limit = %v, want ErrUserQuotaExhausted", err)
+	}
+	if mr.Exists("seat_lock:3") || mr.Exists("seat_lock:4") {
+		t.Error("seats locked although the limit was reached")
+	}
+
+	// Another buyer has a limit of their own, and can't take our seats
+	if _, err := store.HoldSeatsWithLimit(ctx, 1, []int32{3, 4}, 8, 60, "purchase_count:1:8", 4); err != nil {
+		t.Errorf("other buyer: %v", err)
+	}
+	if seat, err := store.HoldSeatsWithLimit(ctx, 1, []int32{5, 1}, 8, 60, "purchase_count:1:8", 4); !errors.Is(err, ErrSeatTaken) || seat != 1 {
+		t.Errorf("taken seat = %d, %v, want 1, ErrSeatTaken", seat, err)
+	}
+
+	// A released hold frees its place; a booked one counts through the purchase counter only
+	if _, err := store.ReleaseSeats(ctx, 1, []int32{1}, 7); err != nil {
+		t.Fatal(err)
+	}
+	mr.Set("seat_lock:2", "booked")
+	mr.Set(counter, "2")
+	if _, err := store.HoldSeatsWithLimit(ctx, 1, []int32{6, 7}, 7, 60, counter, 4); err != nil {
+		t.Errorf("after release and booking: %v", err)
+	}
+	if members, _ := mr.Members(seatHoldsKey(1, 7)); len(members) != 2 {
+		t.Errorf("holds = %v, want the two live ones", members)
+	}
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrSeatTaken = errors.New("seat is held or being booked by someone else")
+
+const seatLockPrefix = "seat_lock:"
+
+// SeatLockKey is the key AtomicBook takes while a booking for the seat is in flight
+func SeatLockKey(seatID int32) string {
+	return fmt.Sprintf("%s%d", seatLockPrefix, seatID)
+}
+
+// seatHoldsKey is the set of seats the owner has held for the event through HoldSeatsWithLimit
+func seatHoldsKey(eventID int32, owner interface{}) string {
+	return fmt.Sprintf("seat_holds:%d:%v", eventID, owner)
+}
+
+// HeldSeats reports which of the seats are currently locked by an in-flight booking
+func (r *RedisStore) HeldSeats(ctx context.Context, seatIDs []int32) (map[int32]bool, error) {
+	held := make(map[int32]bool)
+	if len(seatIDs) == 0 {
+		return held, nil
+	}
+
+	keys := make([]string, len(seatIDs))
+	for i, id := range seatIDs {
+		keys[i] = SeatLockKey(id)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis execution failed: %w", err)
+	}
+	for i, v := range values {
+		if v != nil {
+			held[seatIDs[i]] = true
+		}
+	}
+	return held, nil
+}
+
+// HoldSeats locks all of the event's seats for owner, or none of them, and clears them in
+// the availability bitmap. A seat the owner already holds counts as free. If one is taken,
+// its ID is returned with ErrSeatTaken.
+func (r *RedisStore) HoldSeats(ctx context.Context, eventID int32, seatIDs []int32, owner interface{}, expirySeconds int) (int32, error) {
+	script := markSeatLua + `
+		for i = 3, #KEYS do
+			local current = redis.call("GET", KEYS[i])
+			if current and current ~= ARGV[1] then
+				return i - 2
+			end
+		end
+		for i = 3, #KEYS do
+			redis.call("SET", KEYS[i], ARGV[1], "EX", ARGV[2])
+			mark(KEYS[1], KEYS[2], ARGV[i], 0)
+		end
+		return 0
+	`
+
+	keys, args := seatLockArgs(eventID, seatIDs, owner, expirySeconds)
+	taken, err := r.client.Eval(ctx, script, keys, args...).Int()
+	if err != nil {
+		return 0, fmt.Errorf("redis execution failed: %w", err)
+	}
+	if taken > 0 {
+		return seatIDs[taken-1], ErrSeatTaken
+	}
+	return 0, nil
+}
+
+// HoldSeatsWithLimit is HoldSeats for an event with a per-user limit: the owner's purchases
+// (counterKey, see AtomicBookWithLimit), their other live holds and the new seats together
+// must stay within limit, or nothing is held and ErrUserQuotaExhausted is returned.
+// A held seat stops counting as a hold once its lock is released, expires or is booked.
+func (r *RedisStore) HoldSeatsWithLimit(ctx context.Context, eventID int32, seatIDs []int32, owner interface{}, expirySeconds int, counterKey string, limit int64) (int32, error) {
+	// KEYS is [bitmap, meta, counter, holds, lock...] and ARGV [owner, ttl, limit, lock prefix, seatID...]
+	script := markSeatLua + `
+		local requested = {}
+		for i = 5, #KEYS do
+			local current = redis.call("GET", KEYS[i])
+			if current and current ~= ARGV[1] then
+				return i - 4
+			end
+			requested[ARGV[i]] = true
+		end
+		local held = 0
+		for _, seat in ipairs(redis.call("SMEMBERS", KEYS[4])) do
+			if redis.call("GET", ARGV[4] .. seat) ~= ARGV[1] then
+				redis.call("SREM", KEYS[4], seat)
+			elseif not requested[seat] then
+				held = held + 1
+			end
+		end
+		if tonumber(redis.call("GET", KEYS[3]) or "0") + held + #KEYS - 4 > tonumber(ARGV[3]) then
+			return -1
+		end
+		for i = 5, #KEYS do
+			redis.call("SET", KEYS[i], ARGV[1], "EX", ARGV[2])
+			redis.call("SADD", KEYS[4], ARGV[i])
+			mark(KEYS[1], KEYS[2], ARGV[i], 0)
+		end
+		redis.call("EXPIRE", KEYS[4], ARGV[2])
+		return 0
+	`
+
+	keys := []string{availabilityKey(eventID), availabilityMetaKey(eventID), counterKey, seatHoldsKey(eventID, owner)}
+	args := []interface{}{owner, expirySeconds, limit, seatLockPrefix}
+	for _, id := range seatIDs {
+		keys = append(keys, SeatLockKey(id))
+		args = append(args, id)
+	}
+	result, err := r.client.Eval(ctx, script, keys, args...).Int()
+	if err != nil {
+		return 0, fmt.Errorf("redis execution failed: %w", err)
+	}
+	switch {
+	case result == -1:
+		return 0, ErrUserQuotaExhausted
+	case result > 0:
+		return seatIDs[result-1], ErrSeatTaken
+	}
+	return 0, nil
+}
+
+// ReleaseSeats drops the owner's holds on the event's seats, marks them available again and
+// leaves everyone else's alone. It returns how many were released.
+func (r *RedisStore) ReleaseSeats(ctx context.Context, eventID int32, seatIDs []int32, owner interface{}) (int, error) {
+	script := markSeatLua + `
+		local released = 0
+		for i = 3, #KEYS do
+			if redis.call("GET", KEYS[i]) == ARGV[1] then
+				redis.call("DEL", KEYS[i])
+				mark(KEYS[1], KEYS[2], ARGV[i], 1)
+				released = released + 1
+			end
+		end
+		return released
+	`
+
+	keys, args := seatLockArgs(eventID, seatIDs, owner, 0)
+	released, err := r.client.Eval(ctx, script, keys, args...).Int()
+	if err != nil {
+		return 0, fmt.Errorf("redis execution failed: %w", err)
+	}
+	return released, nil
+}
+
+// seatLockArgs lays out KEYS as [bitmap, meta, lock...] and ARGV as [owner, ttl, seatID...],
+// so KEYS[i] and ARGV[i] refer to the same seat from 3 on
+func seatLockArgs(eventID int32, seatIDs []int32, owner interface{}, expirySeconds int) ([]string, []interface{}) {
+	keys := []string{availabilityKey(eventID), availabilityMetaKey(eventID)}
+	args := []interface{}{owner, expirySeconds}
+	for _, id := range seatIDs {
+		keys = append(keys, SeatLockKey(id))
+		args = append(args, id)
+	}
+	return keys, args
+}
@@ -0,0 +1,270 @@
+package checkins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/notifications"
+	"ticketmaster/internals/tickets"
+	"ticketmaster/internals/validation"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// clockSkew is how far in the future an offline scan's time may be before we call it bogus
+const clockSkew = 5 * time.Minute
+
+type Handler struct {
+	repo   *Repository
+	signer *tickets.Signer
+	keys   tickets.PublicKeys
+	hub    *notifications.Hub
+}
+
+func NewHandler(repo *Repository, signer *tickets.Signer, hub *notifications.Hub) *Handler {
+	return &Handler{repo: repo, signer: signer, keys: signer.PublicKeys(), hub: hub}
+}
+
+// Checkin handles POST /checkin from a door scanner.
+// 201 admitted, 409 already scanned, 422 not a ticket we can let in; the body always says why.
+func (h *Handler) Checkin(w http.ResponseWriter, r *http.Request) {
+	var req CheckinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Gate = strings.TrimSpace(req.Gate)
+	var errs validation.Errors
+	errs.Required("token", req.Token)
+	errs.Required("gate", req.Gate)
+	errs.MaxLength("gate", req.Gate, 100)
+	errs.MaxLength("device_id", req.DeviceID, 100)
+	if errs.Respond(w) {
+		return
+	}
+
+	// 1. The signature, same check the scanner can do offline
+	token, code := tickets.ParseBarcode(req.Token)
+	payload, err := tickets.Verify(token, h.keys)
+	if err != nil {
+		reject(w, err.Error())
+		return
+	}
+	if req.EventID != 0 && payload.EventID != req.EventID {
+		reject(w, fmt.Sprintf("ticket is for event %d", payload.EventID))
+		return
+	}
+	if err := h.ownsEvent(r, payload.EventID); err != nil {
+		if errors.Is(err, ErrNotYourEvent) || errors.Is(err, ErrEventNotFound) {
+			reject(w, err.Error())
+			return
+		}
+		http.Error(w, "Failed to check in", http.StatusInternalServerError)
+		return
+	}
+
+	// 2. Admit once
+	checkin, err := h.repo.Record(r.Context(), payload, token, code, req.Gate, req.DeviceID)
+	switch {
+	case errors.Is(err, ErrAlreadyCheckedIn):
+		writeResult(w, http.StatusConflict, CheckinResponse{
+			Result:  ResultDuplicate,
+			Message: fmt.Sprintf("already scanned at gate %s at %s", checkin.Gate, checkin.ScannedAt.Format("15:04")),
+			Checkin: checkin,
+		})
+		return
+	case isRejection(err):
+		reject(w, err.Error())
+		return
+	case err != nil:
+		http.Error(w, "Failed to check in", http.StatusInternalServerError)
+		return
+	}
+
+	resp := CheckinResponse{Result: ResultAdmitted, Message: "admitted", Checkin: checkin}
+	if n, ok := h.broadcastAttendance(r, checkin.EventID); ok {
+		resp.Attendance = &n
+	}
+	writeResult(w, http.StatusCreated, resp)
+}
+
+// ownsEvent checks that the scanner's API key belongs to the organization running the event
+func (h *Handler) ownsEvent(r *http.Request, eventID int32) error {
+	orgID, ok := r.Context().Value(middleware.OrganizationIDKey).(int32)
+	if !ok {
+		return ErrNotYourEvent
+	}
+	owner, err := h.repo.EventOrganization(r.Context(), eventID)
+	if err != nil {
+		return err
+	}
+	if owner == nil || *owner != orgID {
+		return ErrNotYourEvent
+	}
+	return nil
+}
+
+// writeOwnershipError answers a failed ownsEvent for the event-scoped scanner routes
+func writeOwnershipError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrEventNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrNotYourEvent):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, "Failed to load event", http.StatusInternalServerError)
+	}
+}
+
+func reject(w http.ResponseWriter, message string) {
+	writeResult(w, http.StatusUnprocessableEntity, CheckinResponse{Result: ResultRejected, Message: message})
+}
+
+func writeResult(w http.ResponseWriter, status int, resp CheckinResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ExportManifest handles GET /events/{id}/scanner-manifest: the signed list of tickets a
+// scanner admits while offline. It only reads: bookings without a ticket yet are left out
+// until POST /admin/events/{id}/tickets/issue has issued them.
+func (h *Handler) ExportManifest(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid event id", http.StatusBadRequest)
+		return
+	}
+	if err := h.ownsEvent(r, int32(eventID)); err != nil {
+		writeOwnershipError(w, err)
+		return
+	}
+
+	m, err := h.repo.Manifest(r.Context(), int32(eventID))
+	if err != nil {
+		if errors.Is(err, ErrEventNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to export manifest", http.StatusInternalServerError)
+		return
+	}
+	token, err := h.signer.SignManifest(*m)
+	if err != nil {
+		http.Error(w, "Failed to sign manifest", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(ManifestResponse{
+		EventID:     m.EventID,
+		GeneratedAt: m.GeneratedAt,
+		Tickets:     len(m.Valid),
+		CheckedIn:   len(m.CheckedIn),
+		Manifest:    token,
+	})
+}
+
+// SyncScans handles POST /events/{id}/scanner-sync: a scanner uploading what it admitted offline.
+// Bad scans don't fail the batch; they come back in Rejected, double admissions in Conflicts.
+func (h *Handler) SyncScans(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid event id", http.StatusBadRequest)
+		return
+	}
+	if err := h.ownsEvent(r, int32(eventID)); err != nil {
+		writeOwnershipError(w, err)
+		return
+	}
+	var req SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var errs validation.Errors
+	errs.Required("device_id", req.DeviceID)
+	errs.MaxLength("device_id", req.DeviceID, 100)
+	errs.Check(len(req.Scans) > 0, "scans", "is required")
+	errs.Check(len(req.Scans) <= MaxSyncBatch, "scans", "at most "+strconv.Itoa(MaxSyncBatch)+" per upload")
+	if errs.Respond(w) {
+		return
+	}
+
+	// 1. Everything the server doesn't need to be asked about
+	var rejected []SyncRejection
+	var scans []verifiedScan
+	latest := time.Now().Add(clockSkew)
+	for i, scan := range req.Scans {
+		gate := strings.TrimSpace(scan.Gate)
+		var reason string
+		token, code := tickets.ParseBarcode(scan.Token)
+		payload, err := tickets.Verify(token, h.keys)
+		switch {
+		case err != nil:
+			reason = err.Error()
+		case payload.EventID != int32(eventID):
+			reason = fmt.Sprintf("ticket is for event %d", payload.EventID)
+		case gate == "" || len(gate) > 100:
+			reason = "gate is required and at most 100 characters"
+		case scan.ScannedAt.IsZero() || scan.ScannedAt.After(latest):
+			reason = "scanned_at is missing or in the future"
+		}
+		if reason != "" {
+			rejected = append(rejected, SyncRejection{Index: i, Reason: reason})
+			continue
+		}
+		scans = append(scans, verifiedScan{
+			index:     i,
+			payload:   payload,
+			token:     token,
+			code:      code,
+			gate:      gate,
+			scannedAt: scan.ScannedAt.UTC().Truncate(time.Microsecond), // What Postgres keeps
+		})
+	}
+
+	// 2. Merge the rest
+	resp, err := h.repo.Merge(r.Context(), req.DeviceID, scans)
+	if err != nil {
+		http.Error(w, "Failed to sync scans", http.StatusInternalServerError)
+		return
+	}
+	resp.Rejected = append(rejected, resp.Rejected...)
+	sort.Slice(resp.Rejected, func(i, j int) bool { return resp.Rejected[i].Index < resp.Rejected[j].Index })
+	if resp.Accepted > 0 || len(resp.Conflicts) > 0 {
+		resp.Attendance, _ = h.broadcastAttendance(r, int32(eventID))
+	} else {
+		resp.Attendance, _ = h.repo.Attendance(r.Context(), int32(eventID))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// broadcastAttendance pushes the event's check-in count to the Hub and returns it
+func (h *Handler) broadcastAttendance(r *http.Request, eventID int32) (int64, bool) {
+	n, err := h.repo.Attendance(r.Context(), eventID)
+	if err != nil {
+		log.Printf("cannot count attendance of event %d: %v", eventID, err)
+		return 0, false
+	}
+	go func() {
+		msg := map[string]interface{}{
+			"type":       "attendance",
+			"event_id":   eventID,
+			"checked_in": n,
+		}
+		jsonMsg, _ := json.Marshal(msg)
+		h.hub.Broadcast <- jsonMsg
+	}()
+	return n, true
+}
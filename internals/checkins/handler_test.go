@@ -0,0 +1,79 @@
+package checkins
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"ticketmaster/internals/db/dbtest"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/notifications"
+	"ticketmaster/internals/tickets"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestScannerRoutesCheckTheEventsOrganization(t *testing.T) {
+	db := dbtest.New(t)
+	signer, err := tickets.EphemeralSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(NewRepository(db), signer, notifications.NewHub())
+
+	ours := dbtest.ID(t, db, `INSERT INTO organizations (name) VALUES ('Promoter') RETURNING id`)
+	theirs := dbtest.ID(t, db, `INSERT INTO organizations (name) VALUES ('Rival') RETURNING id`)
+	eventID := dbtest.ID(t, db, `INSERT INTO events (name, starts_at, organization_id) VALUES ('Gig', NOW(), $1) RETURNING id`, ours)
+	orphan := dbtest.ID(t, db, `INSERT INTO events (name, starts_at) VALUES ('Old gig', NOW()) RETURNING id`)
+
+	router := chi.NewRouter()
+	router.Get("/events/{id}/scanner-manifest", h.ExportManifest)
+	router.Post("/events/{id}/scanner-sync", h.SyncScans)
+	router.Post("/checkin", h.Checkin)
+	call := func(method, path, body string, orgID int32) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.OrganizationIDKey, orgID))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	path := func(id int32, suffix string) string {
+		return "/events/" + strconv.Itoa(int(id)) + suffix
+	}
+	sync := `{"device_id": "gate-1", "scans": [{"token": "x", "gate": "A", "scanned_at": "2026-01-01T00:00:00Z"}]}`
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		org    int32
+		want   int
+	}{
+		{"manifest of own event", http.MethodGet, path(eventID, "/scanner-manifest"), "", ours, http.StatusOK},
+		{"manifest of another org's event", http.MethodGet, path(eventID, "/scanner-manifest"), "", theirs, http.StatusForbidden},
+		{"manifest of an event without org", http.MethodGet, path(orphan, "/scanner-manifest"), "", ours, http.StatusForbidden},
+		{"manifest of a missing event", http.MethodGet, path(orphan+100, "/scanner-manifest"), "", ours, http.StatusNotFound},
+		{"sync to own event", http.MethodPost, path(eventID, "/scanner-sync"), sync, ours, http.StatusOK},
+		{"sync to another org's event", http.MethodPost, path(eventID, "/scanner-sync"), sync, theirs, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := call(tt.method, tt.path, tt.body, tt.org); got != tt.want {
+				t.Errorf("%s %s as org %d = %d, want %d", tt.method, tt.path, tt.org, got, tt.want)
+			}
+		})
+	}
+
+	// A validly signed ticket of our event is turned away at the other org's door
+	token, err := signer.Sign(tickets.Payload{TicketID: 1, BookingID: 1, EventID: eventID, IssuedAt: time.Now().Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := call(http.MethodPost, "/checkin", `{"token": "`+token+`", "gate": "A"}`, theirs); got != http.StatusUnprocessableEntity {
+		t.Errorf("checkin at another org's scanner = %d, want 422", got)
+	}
+}
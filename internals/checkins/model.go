@@ -0,0 +1,96 @@
+package checkins
+
+import "time"
+
+// BarcodeSkew is how many rotating-code windows either side of the scan time are accepted,
+// for phones whose clock is a little off
+const BarcodeSkew = 1
+
+type CheckinRequest struct {
+	Token    string `json:"token"`     // What the QR code says, "<token>" or "<token>~<code>"
+	Gate     string `json:"gate"`      // Example: 'North 3'
+	DeviceID string `json:"device_id"` // Optional, for tracing a scanner
+	EventID  int32  `json:"event_id"`  // Optional: the event this scanner admits to
+}
+
+type Checkin struct {
+	ID        int32     `json:"id"`
+	BookingID int32     `json:"booking_id"`
+	TicketID  int32     `json:"ticket_id"`
+	EventID   int32     `json:"event_id"`
+	Gate      string    `json:"gate"`
+	DeviceID  *string   `json:"device_id,omitempty"`
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// Results of a scan, in CheckinResponse.Result
+const (
+	ResultAdmitted  = "admitted"
+	ResultDuplicate = "duplicate"
+	ResultRejected  = "rejected"
+)
+
+// CheckinResponse is what the scanner shows: a verdict, a line of text for the gate staff,
+// and for admitted or duplicate scans the check-in on record.
+type CheckinResponse struct {
+	Result     string   `json:"result"`
+	Message    string   `json:"message"`
+	Checkin    *Checkin `json:"checkin,omitempty"`
+	Attendance *int64   `json:"attendance,omitempty"` // Checked in so far for the event
+}
+
+// MaxSyncBatch caps how many offline scans one upload may carry
+const MaxSyncBatch = 5000
+
+// ManifestResponse is the body of GET /events/{id}/scanner-manifest.
+// Manifest is the signed token; the other fields are for display and logging.
+type ManifestResponse struct {
+	EventID     int32     `json:"event_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Tickets     int       `json:"tickets"`
+	CheckedIn   int       `json:"checked_in"`
+	Manifest    string    `json:"manifest"`
+}
+
+// OfflineScan is one admission a scanner made while it had no connection
+type OfflineScan struct {
+	Token     string    `json:"token"` // As in CheckinRequest
+	Gate      string    `json:"gate"`
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+type SyncRequest struct {
+	DeviceID string        `json:"device_id"`
+	Scans    []OfflineScan `json:"scans"`
+}
+
+// Scan is one side of a conflict
+type Scan struct {
+	TicketID  int32     `json:"ticket_id"`
+	Gate      string    `json:"gate"`
+	DeviceID  *string   `json:"device_id,omitempty"`
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// SyncConflict reports a booking admitted more than once, by two devices or one device twice.
+// The earliest scan is kept as the check-in on record.
+type SyncConflict struct {
+	Index     int   `json:"index"` // Position in SyncRequest.Scans
+	BookingID int32 `json:"booking_id"`
+	Kept      Scan  `json:"kept"`
+	Discarded Scan  `json:"discarded"`
+}
+
+// SyncRejection is a scan that couldn't be recorded, e.g. a forged or reissued ticket
+type SyncRejection struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+type SyncResponse struct {
+	Accepted      int             `json:"accepted"`
+	AlreadySynced int             `json:"already_synced"` // Uploaded before; re-sending a batch is safe
+	Conflicts     []SyncConflict  `json:"conflicts"`
+	Rejected      []SyncRejection `json:"rejected"`
+	Attendance    int64           `json:"attendance"`
+}
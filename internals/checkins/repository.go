@@ -0,0 +1,251 @@
+package checkins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	database "ticketmaster/internals/db"
+	"ticketmaster/internals/pricing"
+	"ticketmaster/internals/tickets"
+	"ticketmaster/internals/totp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrTicketNotFound   = errors.New("ticket not recognised")
+	ErrTicketRevoked    = errors.New("ticket has been reissued; this copy is void")
+	ErrBookingInactive  = errors.New("booking is cancelled or refunded")
+	ErrAlreadyCheckedIn = errors.New("ticket already scanned")
+	ErrEventNotFound    = errors.New("event not found")
+	ErrNotYourEvent     = errors.New("event is run by another organization")
+	ErrCodeRequired     = errors.New("ticket needs its live barcode; a screenshot or printout won't do")
+	ErrCodeInvalid      = errors.New("barcode has expired; ask for the live ticket")
+)
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Record admits the ticket's booking. The first scan inserts the check-in; any later one
+// gets ErrAlreadyCheckedIn together with the check-in on record.
+func (r *Repository) Record(ctx context.Context, p *tickets.Payload, token, code, gate, deviceID string) (*Checkin, error) {
+	// 1. The ticket must be the live one we issued, for a booking that still stands
+	if err := checkTicket(ctx, r.db.Pool, p, token, code, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// 2. First scan wins: the unique booking_id makes this atomic across gates
+	var device *string
+	if deviceID != "" {
+		device = &deviceID
+	}
+	c := Checkin{BookingID: p.BookingID, TicketID: p.TicketID, EventID: p.EventID, Gate: gate, DeviceID: device}
+	err := r.db.Pool.QueryRow(ctx, `
+		INSERT INTO checkins (booking_id, ticket_id, event_id, gate, device_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (booking_id) DO NOTHING
+		RETURNING id, scanned_at`,
+		c.BookingID, c.TicketID, c.EventID, c.Gate, c.DeviceID).Scan(&c.ID, &c.ScannedAt)
+	if err == nil {
+		return &c, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to record check-in: %w", err)
+	}
+
+	// 3. Someone got there first
+	err = r.db.Pool.QueryRow(ctx, `
+		SELECT id, ticket_id, event_id, gate, device_id, scanned_at
+		FROM checkins WHERE booking_id = $1`, p.BookingID).
+		Scan(&c.ID, &c.TicketID, &c.EventID, &c.Gate, &c.DeviceID, &c.ScannedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load check-in: %w", err)
+	}
+	return &c, ErrAlreadyCheckedIn
+}
+
+// checkTicket refuses anything but the live ticket we issued for a booking that still stands.
+// Comparing the token too means a validly signed ticket we have no record of is refused.
+// Tickets with a barcode secret also need the rotating code of the window the scan was made in.
+func checkTicket(ctx context.Context, q pricing.Querier, p *tickets.Payload, token, code string, scannedAt time.Time) error {
+	var revoked bool
+	var status string
+	var secret *string
+	err := q.QueryRow(ctx, `
+		SELECT t.revoked_at IS NOT NULL, b.status, t.barcode_secret
+		FROM tickets t JOIN bookings b ON b.id = t.booking_id
+		WHERE t.id = $1 AND t.booking_id = $2 AND t.token = $3`,
+		p.TicketID, p.BookingID, token).Scan(&revoked, &status, &secret)
+	if err == pgx.ErrNoRows {
+		return ErrTicketNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load ticket: %w", err)
+	}
+	if revoked {
+		return ErrTicketRevoked
+	}
+	if status != "confirmed" {
+		return ErrBookingInactive
+	}
+	if secret != nil {
+		if code == "" {
+			return ErrCodeRequired
+		}
+		if _, ok := totp.Validate(*secret, code, scannedAt, BarcodeSkew); !ok {
+			return ErrCodeInvalid
+		}
+	}
+	return nil
+}
+
+// EventOrganization returns the organization running the event, nil if it has none yet
+func (r *Repository) EventOrganization(ctx context.Context, eventID int32) (*int32, error) {
+	var orgID *int32
+	err := r.db.Pool.QueryRow(ctx, `SELECT organization_id FROM events WHERE id = $1`, eventID).Scan(&orgID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load event: %w", err)
+	}
+	return orgID, nil
+}
+
+// Attendance is how many bookings of the event have been checked in
+func (r *Repository) Attendance(ctx context.Context, eventID int32) (int64, error) {
+	var n int64
+	err := r.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM checkins WHERE event_id = $1`, eventID).Scan(&n)
+	return n, err
+}
+
+// Manifest lists the event's live tickets and which of them are already checked in
+func (r *Repository) Manifest(ctx context.Context, eventID int32) (*tickets.Manifest, error) {
+	var exists bool
+	if err := r.db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM events WHERE id = $1)`, eventID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to load event: %w", err)
+	}
+	if !exists {
+		return nil, ErrEventNotFound
+	}
+
+	m := tickets.Manifest{EventID: eventID, GeneratedAt: time.Now().UTC().Truncate(time.Second)}
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT t.id, c.id IS NOT NULL
+		FROM tickets t
+		JOIN bookings b ON b.id = t.booking_id
+		LEFT JOIN checkins c ON c.booking_id = t.booking_id
+		WHERE b.event_id = $1 AND b.status = 'confirmed' AND t.revoked_at IS NULL
+		ORDER BY t.id`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tickets: %w", err)
+	}
+	var ticketID int32
+	var checkedIn bool
+	_, err = pgx.ForEachRow(rows, []any{&ticketID, &checkedIn}, func() error {
+		m.Valid = append(m.Valid, ticketID)
+		if checkedIn {
+			m.CheckedIn = append(m.CheckedIn, ticketID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tickets: %w", err)
+	}
+	return &m, nil
+}
+
+// verifiedScan is an offline scan whose signature the handler already checked
+type verifiedScan struct {
+	index     int
+	payload   *tickets.Payload
+	token     string
+	code      string
+	gate      string
+	scannedAt time.Time
+}
+
+// Merge records offline scans. Each booking keeps its earliest scan, wherever it came from;
+// every later one is reported as a conflict. Scans already uploaded are recognised and skipped.
+func (r *Repository) Merge(ctx context.Context, deviceID string, scans []verifiedScan) (*SyncResponse, error) {
+	resp := &SyncResponse{Conflicts: []SyncConflict{}, Rejected: []SyncRejection{}}
+	for _, scan := range scans {
+		if err := r.mergeScan(ctx, deviceID, scan, resp); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (r *Repository) mergeScan(ctx context.Context, deviceID string, scan verifiedScan, resp *SyncResponse) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Same checks as a live scan
+	if err := checkTicket(ctx, tx, scan.payload, scan.token, scan.code, scan.scannedAt); err != nil {
+		if isRejection(err) {
+			resp.Rejected = append(resp.Rejected, SyncRejection{Index: scan.index, Reason: err.Error()})
+			return nil
+		}
+		return err
+	}
+
+	// 2. First time we hear of this booking
+	incoming := Scan{TicketID: scan.payload.TicketID, Gate: scan.gate, DeviceID: &deviceID, ScannedAt: scan.scannedAt}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO checkins (booking_id, ticket_id, event_id, gate, device_id, scanned_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (booking_id) DO NOTHING`,
+		scan.payload.BookingID, incoming.TicketID, scan.payload.EventID, incoming.Gate, incoming.DeviceID, incoming.ScannedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record check-in: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		resp.Accepted++
+		return tx.Commit(ctx)
+	}
+
+	// 3. Already checked in: the same scan uploaded again, or a conflict
+	var id int32
+	var existing Scan
+	err = tx.QueryRow(ctx, `
+		SELECT id, ticket_id, gate, device_id, scanned_at
+		FROM checkins WHERE booking_id = $1 FOR UPDATE`, scan.payload.BookingID).
+		Scan(&id, &existing.TicketID, &existing.Gate, &existing.DeviceID, &existing.ScannedAt)
+	if err != nil {
+		return fmt.Errorf("failed to load check-in: %w", err)
+	}
+	if existing.DeviceID != nil && *existing.DeviceID == deviceID &&
+		existing.TicketID == incoming.TicketID && existing.ScannedAt.Equal(incoming.ScannedAt) {
+		resp.AlreadySynced++
+		return nil
+	}
+
+	conflict := SyncConflict{Index: scan.index, BookingID: scan.payload.BookingID, Kept: existing, Discarded: incoming}
+	if incoming.ScannedAt.Before(existing.ScannedAt) {
+		conflict.Kept, conflict.Discarded = incoming, existing
+		_, err = tx.Exec(ctx, `
+			UPDATE checkins SET ticket_id = $2, gate = $3, device_id = $4, scanned_at = $5 WHERE id = $1`,
+			id, incoming.TicketID, incoming.Gate, incoming.DeviceID, incoming.ScannedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update check-in: %w", err)
+		}
+	}
+	resp.Conflicts = append(resp.Conflicts, conflict)
+	return tx.Commit(ctx)
+}
+
+// isRejection tells the ways a ticket can be turned away from a server error
+func isRejection(err error) bool {
+	return errors.Is(err, ErrTicketNotFound) || errors.Is(err, ErrTicketRevoked) ||
+		errors.Is(err, ErrBookingInactive) || errors.Is(err, ErrCodeRequired) || errors.Is(err, ErrCodeInvalid)
+}
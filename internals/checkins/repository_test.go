@@ -0,0 +1,100 @@
+package checkins
+
+import (
+	"context"
+	"testing"
+	"ticketmaster/internals/db/dbtest"
+	"ticketmaster/internals/tickets"
+	"time"
+)
+
+func TestMergeKeepsTheEarliestScan(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	signer, err := tickets.EphemeralSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository(db)
+
+	eventID := dbtest.ID(t, db, `INSERT INTO events (name, starts_at) VALUES ('Gig', NOW()) RETURNING id`)
+	userID := dbtest.ID(t, db, `INSERT INTO users (email, password_hash) VALUES ('fan@example.com', 'x') RETURNING id`)
+	seatID := dbtest.ID(t, db, `INSERT INTO seats (row_number, seat_number, price, event_id, section) VALUES ('A', 1, 40, $1, 'Floor') RETURNING id`, eventID)
+	bookingID := dbtest.ID(t, db, `
+		INSERT INTO bookings (seat_id, event_id, user_id, status, amount, currency)
+		VALUES ($1, $2, $3, 'confirmed', 4000, 'USD') RETURNING id`, seatID, eventID, userID)
+	ticket, err := tickets.NewRepository(db, signer).ForBooking(ctx, bookingID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := tickets.Verify(ticket.Token, signer.PublicKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doors := time.Date(2026, 6, 1, 19, 0, 0, 0, time.UTC)
+	scan := func(gate string, at time.Time) verifiedScan {
+		return verifiedScan{payload: payload, token: ticket.Token, gate: gate, scannedAt: at}
+	}
+	merge := func(device string, s verifiedScan) *SyncResponse {
+		t.Helper()
+		resp, err := repo.Merge(ctx, device, []verifiedScan{s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	onRecord := func() (device string, at time.Time) {
+		t.Helper()
+		err := db.Pool.QueryRow(ctx, `SELECT device_id, scanned_at FROM checkins WHERE booking_id = $1`, bookingID).Scan(&device, &at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return device, at
+	}
+
+	// First upload is accepted
+	if resp := merge("north", scan("N1", doors.Add(5*time.Minute))); resp.Accepted != 1 || len(resp.Conflicts) != 0 {
+		t.Fatalf("first upload = %+v, want accepted", resp)
+	}
+
+	// The same device sending the same scan again is a re-upload, not a conflict
+	if resp := merge("north", scan("N1", doors.Add(5*time.Minute))); resp.AlreadySynced != 1 || resp.Accepted != 0 || len(resp.Conflicts) != 0 {
+		t.Errorf("re-upload = %+v, want already synced", resp)
+	}
+
+	// An earlier scan from another door wins and replaces the record
+	resp := merge("south", scan("S2", doors))
+	if len(resp.Conflicts) != 1 {
+		t.Fatalf("earlier scan = %+v, want one conflict", resp)
+	}
+	c := resp.Conflicts[0]
+	if *c.Kept.DeviceID != "south" || !c.Kept.ScannedAt.Equal(doors) || *c.Discarded.DeviceID != "north" {
+		t.Errorf("conflict kept %s at %s, discarded %s; want south's earlier scan kept", *c.Kept.DeviceID, c.Kept.ScannedAt, *c.Discarded.DeviceID)
+	}
+	if device, at := onRecord(); device != "south" || !at.Equal(doors) {
+		t.Errorf("check-in on record = %s at %s, want south at %s", device, at, doors)
+	}
+
+	// A later scan is a conflict that leaves the record alone, also from the device that lost
+	for _, device := range []string{"east", "north"} {
+		resp := merge(device, scan("X", doors.Add(10*time.Minute)))
+		if len(resp.Conflicts) != 1 || *resp.Conflicts[0].Kept.DeviceID != "south" || resp.AlreadySynced != 0 {
+			t.Errorf("later scan from %s = %+v, want a conflict keeping south's scan", device, resp)
+		}
+	}
+	if device, _ := onRecord(); device != "south" {
+		t.Errorf("check-in on record moved to %s", device)
+	}
+
+	// The same instant from the other device is not a re-upload
+	if resp := merge("north", scan("S2", doors)); len(resp.Conflicts) != 1 || resp.AlreadySynced != 0 {
+		t.Errorf("same time, other device = %+v, want a conflict", resp)
+	}
+
+	// A revoked ticket is rejected, not merged
+	dbtest.Exec(t, db, `UPDATE tickets SET revoked_at = NOW() WHERE id = $1`, ticket.ID)
+	if resp := merge("west", scan("W1", doors.Add(-time.Minute))); len(resp.Rejected) != 1 || len(resp.Conflicts) != 0 {
+		t.Errorf("revoked ticket = %+v, want rejected", resp)
+	}
+}
@@ -0,0 +1,59 @@
+-- Chart of accounts. Codes are referenced from Go (see internals/ledger/model.go).
+CREATE TABLE ledger_accounts (
+    id SERIAL PRIMARY KEY,
+    code TEXT UNIQUE NOT NULL,               -- Example: 'customer_funds'
+    name TEXT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('asset', 'liability', 'revenue', 'expense', 'equity'))
+);
+
+INSERT INTO ledger_accounts (code, name, type) VALUES
+    ('customer_funds',    'Funds collected from customers', 'asset'),
+    ('organizer_payable', 'Owed to event organizers',       'liability'),
+    ('fee_revenue',       'Platform fee revenue',           'revenue'),
+    ('tax_payable',       'Sales tax collected',            'liability');
+
+-- One row per business event (booking, refund, fee...).
+CREATE TABLE ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,                      -- Example: 'booking', 'refund', 'fee'
+    reference_type TEXT NOT NULL,            -- Example: 'booking'
+    reference_id BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_ledger_entries_reference ON ledger_entries (reference_type, reference_id);
+
+-- The debit/credit legs of an entry. Amounts are always positive minor units (cents).
+CREATE TABLE ledger_postings (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id BIGINT NOT NULL REFERENCES ledger_entries(id),
+    account_id INT NOT NULL REFERENCES ledger_accounts(id),
+    direction TEXT NOT NULL CHECK (direction IN ('debit', 'credit')),
+    amount BIGINT NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX idx_ledger_postings_entry ON ledger_postings (entry_id);
+CREATE INDEX idx_ledger_postings_account ON ledger_postings (account_id);
+
+-- Fail-safe: every entry must balance by the time its transaction commits.
+CREATE FUNCTION ledger_check_entry_balanced() RETURNS trigger AS $$
+DECLARE
+    imbalance BIGINT;
+BEGIN
+    SELECT COALESCE(SUM(CASE WHEN direction = 'debit' THEN amount ELSE -amount END), 0)
+      INTO imbalance
+      FROM ledger_postings
+     WHERE entry_id = NEW.entry_id;
+
+    IF imbalance <> 0 THEN
+        RAISE EXCEPTION 'ledger entry % is unbalanced by %', NEW.entry_id, imbalance;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER ledger_postings_balanced
+    AFTER INSERT ON ledger_postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_check_entry_balanced();
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS refunds;
+ALTER TABLE bookings DROP COLUMN IF EXISTS currency, DROP COLUMN IF EXISTS amount, DROP COLUMN IF EXISTS status;
+ALTER TABLE seats DROP COLUMN IF EXISTS event_id;
+DROP TABLE IF EXISTS events;
@@ -0,0 +1,38 @@
+CREATE TABLE events (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL DEFAULT 'scheduled', -- 'scheduled' or 'cancelled'
+    cancelled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Seats created before events existed stay unattached (NULL)
+ALTER TABLE seats ADD COLUMN event_id INT REFERENCES events(id);
+CREATE INDEX idx_seats_event ON seats (event_id);
+
+-- What the customer was charged, so refunds know how much to give back
+ALTER TABLE bookings
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'confirmed', -- 'confirmed' or 'cancelled'
+    ADD COLUMN amount BIGINT NOT NULL DEFAULT 0,         -- Minor units (cents)
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+UPDATE bookings b SET amount = s.price * 100 FROM seats s WHERE s.id = b.seat_id;
+
+-- One refund per booking. The UNIQUE constraint makes re-running a cancellation safe.
+CREATE TABLE refunds (
+    id BIGSERIAL PRIMARY KEY,
+    booking_id INT UNIQUE NOT NULL REFERENCES bookings(id),
+    event_id INT NOT NULL REFERENCES events(id),
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending', -- 'pending', 'processing', 'succeeded', 'failed'
+    attempts INT NOT NULL DEFAULT 0,
+    provider_ref TEXT,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refunds_status ON refunds (status, id);
+CREATE INDEX idx_refunds_event ON refunds (event_id);
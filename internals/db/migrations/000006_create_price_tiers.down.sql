@@ -0,0 +1,8 @@
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS tax,
+    DROP COLUMN IF EXISTS facility_fee,
+    DROP COLUMN IF EXISTS service_fee,
+    DROP COLUMN IF EXISTS face_value;
+DROP TABLE IF EXISTS section_price_tiers;
+ALTER TABLE seats DROP COLUMN IF EXISTS tier_id, DROP COLUMN IF EXISTS section;
+DROP TABLE IF EXISTS price_tiers;
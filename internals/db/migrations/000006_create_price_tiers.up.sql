@@ -0,0 +1,35 @@
+-- All amounts are minor units (cents) in the tier's currency. Percentages are basis points (1% = 100).
+CREATE TABLE price_tiers (
+    id SERIAL PRIMARY KEY,
+    event_id INT NOT NULL REFERENCES events(id),
+    name TEXT NOT NULL,                       -- Example: 'Floor', 'Balcony'
+    currency CHAR(3) NOT NULL,
+    face_value BIGINT NOT NULL CHECK (face_value >= 0),
+    service_fee_bps INT NOT NULL DEFAULT 0 CHECK (service_fee_bps BETWEEN 0 AND 10000),
+    service_fee_fixed BIGINT NOT NULL DEFAULT 0 CHECK (service_fee_fixed >= 0),
+    facility_fee BIGINT NOT NULL DEFAULT 0 CHECK (facility_fee >= 0),
+    tax_bps INT NOT NULL DEFAULT 0 CHECK (tax_bps BETWEEN 0 AND 10000),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (event_id, name)
+);
+
+-- A seat's tier comes from seats.tier_id first, then from its section's tier
+ALTER TABLE seats
+    ADD COLUMN section TEXT NOT NULL DEFAULT '',
+    ADD COLUMN tier_id INT REFERENCES price_tiers(id);
+
+CREATE TABLE section_price_tiers (
+    event_id INT NOT NULL REFERENCES events(id),
+    section TEXT NOT NULL,
+    tier_id INT NOT NULL REFERENCES price_tiers(id),
+    PRIMARY KEY (event_id, section)
+);
+
+-- The breakdown charged at booking time. bookings.amount stays the total.
+ALTER TABLE bookings
+    ADD COLUMN face_value BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN service_fee BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN facility_fee BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax BIGINT NOT NULL DEFAULT 0;
+
+UPDATE bookings SET face_value = amount;
@@ -0,0 +1,4 @@
+ALTER TABLE bookings DROP COLUMN IF EXISTS discount;
+DROP TABLE IF EXISTS promo_redemptions;
+DROP TABLE IF EXISTS promo_codes;
+ALTER TABLE events DROP COLUMN IF EXISTS public_onsale_at;
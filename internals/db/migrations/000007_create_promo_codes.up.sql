@@ -0,0 +1,31 @@
+-- Before public_onsale_at only holders of an access code can book (NULL = on sale now)
+ALTER TABLE events ADD COLUMN public_onsale_at TIMESTAMP;
+
+CREATE TABLE promo_codes (
+    id SERIAL PRIMARY KEY,
+    code TEXT UNIQUE NOT NULL,                 -- Stored upper-case, Example: 'SPONSOR10'
+    kind TEXT NOT NULL CHECK (kind IN ('discount', 'access')),
+    discount_type TEXT CHECK (discount_type IN ('percent', 'fixed')),
+    discount_value BIGINT NOT NULL DEFAULT 0,  -- Basis points for 'percent', minor units for 'fixed'
+    max_uses INT,                              -- NULL = unlimited
+    per_user_limit INT,                        -- NULL = unlimited
+    valid_from TIMESTAMP,
+    valid_until TIMESTAMP,
+    event_id INT REFERENCES events(id),        -- NULL = any event
+    tier_id INT REFERENCES price_tiers(id),    -- NULL = any tier
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (kind = 'access' OR discount_type IS NOT NULL)
+);
+
+-- Source of truth for usage; Redis counters only gate the hot path
+CREATE TABLE promo_redemptions (
+    id SERIAL PRIMARY KEY,
+    promo_code_id INT NOT NULL REFERENCES promo_codes(id),
+    user_id INT NOT NULL,
+    booking_id INT UNIQUE NOT NULL REFERENCES bookings(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_promo_redemptions_code_user ON promo_redemptions (promo_code_id, user_id);
+
+ALTER TABLE bookings ADD COLUMN discount BIGINT NOT NULL DEFAULT 0;
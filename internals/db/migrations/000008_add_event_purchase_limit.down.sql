@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_bookings_user;
+ALTER TABLE events DROP COLUMN IF EXISTS max_tickets_per_user;
@@ -0,0 +1,4 @@
+-- Maximum confirmed tickets one account may hold for the event (NULL = no limit)
+ALTER TABLE events ADD COLUMN max_tickets_per_user INT CHECK (max_tickets_per_user > 0);
+
+CREATE INDEX idx_bookings_user ON bookings (user_id);
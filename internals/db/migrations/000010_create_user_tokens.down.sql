@@ -0,0 +1,3 @@
+ALTER TABLE events DROP COLUMN IF EXISTS require_verified_email;
+DROP TABLE IF EXISTS user_tokens;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
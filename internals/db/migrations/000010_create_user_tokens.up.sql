@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Single-use tokens mailed to the user. Only the SHA-256 is stored.
+CREATE TABLE user_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    purpose TEXT NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_tokens_user ON user_tokens (user_id, purpose);
+
+-- Event policy: only verified accounts may book
+ALTER TABLE events ADD COLUMN require_verified_email BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+-- Emails are stored trimmed and lower-cased from now on; bring old rows in line.
+-- If two existing accounts differ only by case this fails and they must be merged by hand first.
+UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email));
+
+-- Belt and braces: the unique index catches any writer that forgets to normalize.
+CREATE UNIQUE INDEX users_email_lower_key ON users (LOWER(email));
@@ -0,0 +1,14 @@
+-- Append-only record of security-relevant events (lockouts, key changes, ...).
+-- user_id is nullable: a lockout can hit an email that has no account.
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    action TEXT NOT NULL,          -- Example: 'login.lockout'
+    user_id INT REFERENCES users(id),
+    subject TEXT NOT NULL DEFAULT '', -- What the action was about, e.g. the email or IP that got locked
+    ip TEXT NOT NULL DEFAULT '',
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_action ON audit_log (action, created_at);
+CREATE INDEX idx_audit_log_user ON audit_log (user_id);
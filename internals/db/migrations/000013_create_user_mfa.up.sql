@@ -0,0 +1,19 @@
+-- TOTP second factor. The secret is pending until the user proves their app works
+-- by confirming a first code; only then is enabled_at set and login asks for a code.
+CREATE TABLE user_mfa (
+    user_id INT PRIMARY KEY REFERENCES users(id),
+    totp_secret TEXT NOT NULL,              -- Base32, as shown to the authenticator app
+    enabled_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0, -- Time step of the last accepted code; older ones are replays
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Single-use recovery codes for a lost device. Only the SHA-256 is stored.
+CREATE TABLE mfa_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE users ALTER COLUMN password_hash DROP DEFAULT;
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,16 @@
+-- External OpenID Connect identities linked to local accounts.
+-- (provider, subject) is the stable key; the email is only a snapshot for support.
+CREATE TABLE user_identities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    provider TEXT NOT NULL,   -- Our name for the issuer, Example: 'google'
+    subject TEXT NOT NULL,    -- The ID token's sub claim
+    email TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMP,
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+-- Accounts created through a provider have no password until they set one via reset
+ALTER TABLE users ALTER COLUMN password_hash SET DEFAULT '';
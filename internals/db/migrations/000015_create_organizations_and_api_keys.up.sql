@@ -0,0 +1,23 @@
+-- Partners, resellers and our own box office. API keys belong to an organization, not a user.
+CREATE TABLE organizations (
+    id SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Keys look like tm_<prefix>_<secret>. The prefix is stored in clear so a key can be
+-- found (and recognised in logs) without the secret; only the SHA-256 of the whole key is kept.
+CREATE TABLE api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    organization_id INT NOT NULL REFERENCES organizations(id),
+    name TEXT NOT NULL,                    -- Example: 'Box office terminal 3'
+    prefix TEXT UNIQUE NOT NULL,
+    key_hash TEXT NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',   -- Example: {'events:read','bookings:read'}
+    expires_at TIMESTAMP,                  -- NULL never expires
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_keys_organization ON api_keys (organization_id);
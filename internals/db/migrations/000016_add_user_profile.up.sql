@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN phone TEXT,
+    -- Set when the account is deleted. The row stays, anonymized, so bookings,
+    -- refunds and ledger entries that point at it remain intact.
+    ADD COLUMN deleted_at TIMESTAMP;
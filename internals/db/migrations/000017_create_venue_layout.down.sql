@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS seats_event_row_number_key;
+ALTER TABLE seats
+    DROP COLUMN IF EXISTS attributes,
+    DROP COLUMN IF EXISTS y,
+    DROP COLUMN IF EXISTS x,
+    DROP COLUMN IF EXISTS row_id;
+ALTER TABLE seats ALTER COLUMN row_number TYPE CHAR(1);
+ALTER TABLE events DROP COLUMN IF EXISTS venue_id;
+DROP TABLE IF EXISTS venue_rows;
+DROP TABLE IF EXISTS venue_sections;
+DROP TABLE IF EXISTS venues;
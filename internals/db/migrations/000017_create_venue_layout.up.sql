@@ -0,0 +1,38 @@
+-- Venue layout: venues -> sections -> rows. Seats (per-event inventory) point at a row
+-- and carry their own drawing position, so the seat map can be rendered from the database.
+CREATE TABLE venues (
+    id SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE venue_sections (
+    id SERIAL PRIMARY KEY,
+    venue_id INT NOT NULL REFERENCES venues(id),
+    name TEXT NOT NULL,                 -- Example: 'Floor', '101'
+    level TEXT NOT NULL DEFAULT '',     -- Example: 'Lower Bowl', 'Balcony'
+    sort_order INT NOT NULL DEFAULT 0,
+    UNIQUE (venue_id, name)
+);
+
+CREATE TABLE venue_rows (
+    id SERIAL PRIMARY KEY,
+    section_id INT NOT NULL REFERENCES venue_sections(id),
+    label TEXT NOT NULL,                -- Example: 'A', 'AA', '12'
+    sort_order INT NOT NULL DEFAULT 0,
+    UNIQUE (section_id, label)
+);
+
+ALTER TABLE events ADD COLUMN venue_id INT REFERENCES venues(id);
+
+-- CHAR(1) could not hold row 'AA'
+ALTER TABLE seats ALTER COLUMN row_number TYPE TEXT;
+
+ALTER TABLE seats
+    ADD COLUMN row_id INT REFERENCES venue_rows(id),
+    ADD COLUMN x DOUBLE PRECISION,                  -- Drawing position in the venue's coordinate space
+    ADD COLUMN y DOUBLE PRECISION,
+    ADD COLUMN attributes TEXT[] NOT NULL DEFAULT '{}'; -- Example: {'aisle','wheelchair'}
+
+-- A physical seat can only be sold once per event
+CREATE UNIQUE INDEX seats_event_row_number_key ON seats (event_id, row_id, seat_number) WHERE row_id IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE venue_sections DROP COLUMN IF EXISTS quality;
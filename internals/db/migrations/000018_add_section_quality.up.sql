@@ -0,0 +1,2 @@
+-- How good a section is, 0 (worst) to 100 (best). Drives the best-available seat finder.
+ALTER TABLE venue_sections ADD COLUMN quality INT NOT NULL DEFAULT 50 CHECK (quality BETWEEN 0 AND 100);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_seats_listing;
+DROP INDEX IF EXISTS idx_seats_version;
+DROP INDEX IF EXISTS idx_seats_event_version;
+DROP TRIGGER IF EXISTS seats_bump_version ON seats;
+DROP FUNCTION IF EXISTS bump_seat_version();
+ALTER TABLE seats DROP COLUMN IF EXISTS version;
+DROP SEQUENCE IF EXISTS seat_inventory_version_seq;
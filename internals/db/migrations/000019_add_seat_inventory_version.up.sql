@@ -0,0 +1,23 @@
+-- Every seat write takes a new number from one sequence, so MAX(version) over an event's
+-- seats changes whenever its inventory does. GET /seats derives its ETag from it.
+-- A sequence (unlike a counter row) never blocks concurrent bookings.
+CREATE SEQUENCE seat_inventory_version_seq;
+
+ALTER TABLE seats ADD COLUMN version BIGINT NOT NULL DEFAULT nextval('seat_inventory_version_seq');
+
+CREATE FUNCTION bump_seat_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := nextval('seat_inventory_version_seq');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER seats_bump_version
+    BEFORE UPDATE ON seats
+    FOR EACH ROW EXECUTE FUNCTION bump_seat_version();
+
+CREATE INDEX idx_seats_event_version ON seats (event_id, version);
+CREATE INDEX idx_seats_version ON seats (version);
+
+-- Keyset pagination order of GET /seats
+CREATE INDEX idx_seats_listing ON seats (event_id, row_number, seat_number, id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_bookings_event_user;
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_seat_or_ga;
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS event_id,
+    DROP COLUMN IF EXISTS ga_ticket_type_id,
+    ALTER COLUMN seat_id SET NOT NULL;
+DROP TABLE IF EXISTS ga_ticket_types;
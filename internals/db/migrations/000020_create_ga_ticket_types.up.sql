@@ -0,0 +1,26 @@
+-- General admission: a ticket type is a capacity, not a set of seats.
+-- sold is the source of truth; Redis only fronts it for holds.
+CREATE TABLE ga_ticket_types (
+    id SERIAL PRIMARY KEY,
+    event_id INT NOT NULL REFERENCES events(id),
+    name TEXT NOT NULL,                  -- Example: 'Standing', 'Early Entry'
+    tier_id INT NOT NULL REFERENCES price_tiers(id),
+    capacity INT NOT NULL CHECK (capacity >= 0),
+    sold INT NOT NULL DEFAULT 0 CHECK (sold >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (event_id, name),
+    CHECK (sold <= capacity)
+);
+
+-- A booking is now either a seat or one GA admission. event_id saves every
+-- per-event query from having to look at both.
+ALTER TABLE bookings
+    ALTER COLUMN seat_id DROP NOT NULL,
+    ADD COLUMN ga_ticket_type_id INT REFERENCES ga_ticket_types(id),
+    ADD COLUMN event_id INT REFERENCES events(id);
+
+UPDATE bookings b SET event_id = s.event_id FROM seats s WHERE s.id = b.seat_id;
+
+ALTER TABLE bookings ADD CONSTRAINT bookings_seat_or_ga CHECK ((seat_id IS NULL) <> (ga_ticket_type_id IS NULL));
+
+CREATE INDEX idx_bookings_event_user ON bookings (event_id, user_id);
@@ -0,0 +1,12 @@
+-- The ticket an attendee shows at the door: a signed token for one booking.
+-- A booking has at most one live ticket; revoked ones stay for the record.
+CREATE TABLE tickets (
+    id SERIAL PRIMARY KEY,
+    booking_id INT NOT NULL REFERENCES bookings(id),
+    holder_id INT NOT NULL REFERENCES users(id),
+    token TEXT NOT NULL,
+    issued_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX tickets_live_booking_key ON tickets (booking_id) WHERE revoked_at IS NULL;
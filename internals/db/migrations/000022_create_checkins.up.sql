@@ -0,0 +1,13 @@
+-- One row per admitted booking: the first scan wins, any later one is a duplicate.
+-- Keyed by booking, not ticket, so a reissued ticket can't get the same booking in twice.
+CREATE TABLE checkins (
+    id SERIAL PRIMARY KEY,
+    booking_id INT NOT NULL UNIQUE REFERENCES bookings(id),
+    ticket_id INT NOT NULL REFERENCES tickets(id),
+    event_id INT NOT NULL REFERENCES events(id),
+    gate TEXT NOT NULL,
+    device_id TEXT,
+    scanned_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_checkins_event ON checkins (event_id);
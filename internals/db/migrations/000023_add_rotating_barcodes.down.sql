@@ -0,0 +1,2 @@
+ALTER TABLE tickets DROP COLUMN IF EXISTS barcode_secret;
+ALTER TABLE events DROP COLUMN IF EXISTS rotating_barcodes;
@@ -0,0 +1,4 @@
+-- Rotating barcodes: tickets of such events get a secret, and the barcode carries a
+-- code derived from it and the current 30 second window, so a screenshot soon goes stale.
+ALTER TABLE events ADD COLUMN rotating_barcodes BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE tickets ADD COLUMN barcode_secret TEXT;
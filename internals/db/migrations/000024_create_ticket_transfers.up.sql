@@ -0,0 +1,16 @@
+-- Ticket transfers between accounts, kept as history: who handed which booking to whom and
+-- what came of it. A booking has at most one transfer awaiting an answer.
+CREATE TABLE ticket_transfers (
+    id SERIAL PRIMARY KEY,
+    booking_id INT NOT NULL REFERENCES bookings(id),
+    from_user_id INT NOT NULL REFERENCES users(id),
+    to_user_id INT NOT NULL REFERENCES users(id),
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, accepted, declined, cancelled
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMP,
+    CHECK (from_user_id <> to_user_id)
+);
+
+CREATE UNIQUE INDEX ticket_transfers_pending_booking_key ON ticket_transfers (booking_id) WHERE status = 'pending';
+CREATE INDEX idx_ticket_transfers_from_user ON ticket_transfers (from_user_id);
+CREATE INDEX idx_ticket_transfers_to_user ON ticket_transfers (to_user_id);
@@ -0,0 +1,3 @@
+-- The organization running the event: only its API keys may scan the event's tickets.
+-- Existing events have none until one is set, so no scanner admits them before that.
+ALTER TABLE events ADD COLUMN organization_id INT REFERENCES organizations(id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_ticket_transfers_pending_to_email;
+DELETE FROM ticket_transfers WHERE to_user_id IS NULL;
+ALTER TABLE ticket_transfers ALTER COLUMN to_user_id SET NOT NULL;
+ALTER TABLE ticket_transfers DROP COLUMN IF EXISTS to_email;
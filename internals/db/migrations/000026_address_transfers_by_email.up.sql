@@ -0,0 +1,8 @@
+-- Transfers go to an email address, so offering a ticket doesn't tell the sender whether the
+-- address has an account. The recipient account is only recorded once someone answers.
+ALTER TABLE ticket_transfers ADD COLUMN to_email TEXT;
+UPDATE ticket_transfers tt SET to_email = u.email FROM users u WHERE u.id = tt.to_user_id;
+ALTER TABLE ticket_transfers ALTER COLUMN to_email SET NOT NULL;
+ALTER TABLE ticket_transfers ALTER COLUMN to_user_id DROP NOT NULL;
+
+CREATE INDEX idx_ticket_transfers_pending_to_email ON ticket_transfers (to_email) WHERE status = 'pending';
@@ -0,0 +1,197 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"ticketmaster/internals/cache"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/notifications"
+	"ticketmaster/internals/refunds"
+	"ticketmaster/internals/validation"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	repo       *Repository
+	refundRepo *refunds.Repository
+	processor  *refunds.Processor
+	hub        *notifications.Hub
+	redisStore *cache.RedisStore
+}
+
+func NewHandler(repo *Repository, refundRepo *refunds.Repository, processor *refunds.Processor, hub *notifications.Hub, redisStore *cache.RedisStore) *Handler {
+	return &Handler{repo: repo, refundRepo: refundRepo, processor: processor, hub: hub, redisStore: redisStore}
+}
+
+// CreateEvent handles POST /admin/events
+func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	var req EventCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var errs validation.Errors
+	errs.Required("name", req.Name)
+	errs.MaxLength("name", req.Name, 255)
+	errs.Check(!req.StartsAt.IsZero(), "starts_at", "is required")
+	errs.Check(req.MaxTicketsPerUser == nil || *req.MaxTicketsPerUser >= 1, "max_tickets_per_user", "must be at least 1")
+	if errs.Respond(w) {
+		return
+	}
+
+	if req.OrganizationID == nil {
+		if orgID, ok := r.Context().Value(middleware.OrganizationIDKey).(int32); ok {
+			req.OrganizationID = &orgID
+		}
+	}
+	if !middleware.ActsFor(r.Context(), req.OrganizationID) {
+		http.Error(w, "Only the platform operator can create events for another organization", http.StatusForbidden)
+		return
+	}
+
+	event, err := h.repo.CreateEvent(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, ErrVenueNotFound) || errors.Is(err, ErrOrgNotFound) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, "Failed to create event", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(event)
+}
+
+// SetOrganization handles PUT /admin/events/{id}/organization
+func (h *Handler) SetOrganization(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := eventIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req OrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var errs validation.Errors
+	errs.Check(req.OrganizationID > 0, "organization_id", "is required")
+	if errs.Respond(w) {
+		return
+	}
+
+	event, err := h.repo.SetOrganization(r.Context(), eventID, req.OrganizationID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrEventNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrOrgNotFound):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, "Failed to update event", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+// GetEvents handles GET /events
+func (h *Handler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := h.repo.GetAll(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// CancelEvent handles POST /admin/events/{id}/cancel
+func (h *Handler) CancelEvent(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := eventIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.repo.Cancel(r.Context(), eventID)
+	if err != nil {
+		if errors.Is(err, ErrEventNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to cancel event", http.StatusInternalServerError)
+		return
+	}
+
+	// Every seat is gone: the next availability read rebuilds an empty bitmap
+	if err := h.redisStore.DropAvailability(r.Context(), eventID); err != nil {
+		log.Printf("cannot drop availability bitmap of event %d: %v", eventID, err)
+	}
+
+	// Refunds run in the background; the admin polls GET /admin/events/{id}/refunds
+	h.processor.Wake()
+	go func() {
+		msg := map[string]interface{}{
+			"type":     "event_cancelled",
+			"event_id": eventID,
+		}
+		jsonMsg, _ := json.Marshal(msg)
+		h.hub.Broadcast <- jsonMsg
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetRefunds handles GET /admin/events/{id}/refunds
+func (h *Handler) GetRefunds(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := eventIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	summary, err := h.refundRepo.Summary(r.Context(), eventID)
+	if err != nil {
+		http.Error(w, "Failed to fetch refund status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// RetryRefunds handles POST /admin/events/{id}/refunds/retry
+func (h *Handler) RetryRefunds(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := eventIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	requeued, err := h.refundRepo.RetryFailed(r.Context(), eventID)
+	if err != nil {
+		http.Error(w, "Failed to retry refunds", http.StatusInternalServerError)
+		return
+	}
+	h.processor.Wake()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"requeued": requeued})
+}
+
+func eventIDParam(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid event id", http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
@@ -0,0 +1,47 @@
+package events
+
+import "time"
+
+// Event statuses
+const (
+	StatusScheduled = "scheduled"
+	StatusCancelled = "cancelled"
+)
+
+type Event struct {
+	ID                   int32      `json:"id"`
+	Name                 string     `json:"name"`
+	StartsAt             time.Time  `json:"starts_at"`
+	Status               string     `json:"status"`
+	PublicOnsaleAt       *time.Time `json:"public_onsale_at"` // Before this, only access-code holders can book
+	MaxTicketsPerUser    *int32     `json:"max_tickets_per_user"`
+	RequireVerifiedEmail bool       `json:"require_verified_email"`
+	RotatingBarcodes     bool       `json:"rotating_barcodes"` // Tickets show a time-based code instead of a static one
+	CancelledAt          *time.Time `json:"cancelled_at"`
+	VenueID              *int32     `json:"venue_id"`
+	OrganizationID       *int32     `json:"organization_id"` // Whose scanners admit its tickets
+	CreatedAt            time.Time  `json:"created_at"`
+}
+
+type EventCreationRequest struct {
+	Name                 string     `json:"name"`
+	StartsAt             time.Time  `json:"starts_at"`
+	PublicOnsaleAt       *time.Time `json:"public_onsale_at"`
+	MaxTicketsPerUser    *int32     `json:"max_tickets_per_user"`
+	RequireVerifiedEmail bool       `json:"require_verified_email"`
+	RotatingBarcodes     bool       `json:"rotating_barcodes"`
+	VenueID              *int32     `json:"venue_id"`
+	OrganizationID       *int32     `json:"organization_id"` // Defaults to the organization of the creating API key
+}
+
+// OrganizationRequest is the body of PUT /admin/events/{id}/organization
+type OrganizationRequest struct {
+	OrganizationID int32 `json:"organization_id"`
+}
+
+// CancellationResponse is returned by POST /admin/events/{id}/cancel
+type CancellationResponse struct {
+	EventID           int32 `json:"event_id"`
+	BookingsCancelled int64 `json:"bookings_cancelled"`
+	RefundsQueued     int64 `json:"refunds_queued"`
+}
@@ -0,0 +1,145 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	database "ticketmaster/internals/db"
+	"ticketmaster/internals/refunds"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrEventNotFound = errors.New("event not found")
+	ErrVenueNotFound = errors.New("venue not found")
+	ErrOrgNotFound   = errors.New("organization not found")
+)
+
+type Repository struct {
+	db      *database.DB
+	refunds *refunds.Repository
+}
+
+func NewRepository(db *database.DB, refundRepo *refunds.Repository) *Repository {
+	return &Repository{db: db, refunds: refundRepo}
+}
+
+const eventColumns = `id, name, starts_at, status, public_onsale_at, max_tickets_per_user, require_verified_email, rotating_barcodes, cancelled_at, venue_id, organization_id, created_at`
+
+func (r *Repository) CreateEvent(ctx context.Context, req EventCreationRequest) (*Event, error) {
+	query := `INSERT INTO events (name, starts_at, public_onsale_at, max_tickets_per_user, require_verified_email, rotating_barcodes, venue_id, organization_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ` + eventColumns
+	rows, err := r.db.Pool.Query(ctx, query, req.Name, req.StartsAt, req.PublicOnsaleAt, req.MaxTicketsPerUser, req.RequireVerifiedEmail, req.RotatingBarcodes, req.VenueID, req.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	event, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[Event])
+	return event, foreignKeyError(err)
+}
+
+// SetOrganization hands the event to another organization, e.g. for events created
+// before events had one
+func (r *Repository) SetOrganization(ctx context.Context, eventID, orgID int32) (*Event, error) {
+	rows, err := r.db.Pool.Query(ctx, `UPDATE events SET organization_id = $2 WHERE id = $1 RETURNING `+eventColumns, eventID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	event, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[Event])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEventNotFound
+	}
+	return event, foreignKeyError(err)
+}
+
+// EventOrganization returns the organization running the event, nil if it has none or doesn't exist
+func (r *Repository) EventOrganization(ctx context.Context, eventID int32) (*int32, error) {
+	var orgID *int32
+	err := r.db.Pool.QueryRow(ctx, `SELECT organization_id FROM events WHERE id = $1`, eventID).Scan(&orgID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load event: %w", err)
+	}
+	return orgID, nil
+}
+
+// foreignKeyError names the missing venue or organization of a failed insert or update
+func foreignKeyError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		if pgErr.ConstraintName == "events_organization_id_fkey" {
+			return ErrOrgNotFound
+		}
+		return ErrVenueNotFound
+	}
+	return err
+}
+
+func (r *Repository) GetAll(ctx context.Context) ([]Event, error) {
+	query := `SELECT ` + eventColumns + ` FROM events ORDER BY starts_at ASC`
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Event])
+}
+
+// Cancel marks the event, its seats and its bookings cancelled and queues a refund per booking.
+// Everything happens in one transaction; calling it again only queues refunds that are missing.
+func (r *Repository) Cancel(ctx context.Context, eventID int32) (*CancellationResponse, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Lock the event row so two admins can't cancel concurrently
+	var locked int32
+	err = tx.QueryRow(ctx, `SELECT id FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&locked)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to lock event: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE events SET status = 'cancelled', cancelled_at = COALESCE(cancelled_at, NOW()) WHERE id = $1`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel event: %w", err)
+	}
+
+	// 2. Seats first: this waits for in-flight bookings holding a seat lock,
+	// so the bookings update below sees them once they commit.
+	_, err = tx.Exec(ctx, `UPDATE seats SET status = 'cancelled' WHERE event_id = $1`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel seats: %w", err)
+	}
+
+	// 3. Bookings
+	tag, err := tx.Exec(ctx, `
+		UPDATE bookings SET status = 'cancelled'
+		WHERE status = 'confirmed' AND event_id = $1`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel bookings: %w", err)
+	}
+
+	// 4. Queue the refunds
+	queued, err := r.refunds.EnqueueForEvent(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &CancellationResponse{
+		EventID:           eventID,
+		BookingsCancelled: tag.RowsAffected(),
+		RefundsQueued:     queued,
+	}, nil
+}
@@ -0,0 +1,359 @@
+package ga
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"ticketmaster/internals/bookings"
+	"ticketmaster/internals/cache"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/notifications"
+	"ticketmaster/internals/promotions"
+	"ticketmaster/internals/validation"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	repo       *Repository
+	redisStore *cache.RedisStore
+	hub        *notifications.Hub
+	promos     *promotions.Service
+}
+
+func NewHandler(repo *Repository, redisStore *cache.RedisStore, hub *notifications.Hub, promos *promotions.Service) *Handler {
+	return &Handler{repo: repo, redisStore: redisStore, hub: hub, promos: promos}
+}
+
+// CreateType handles POST /admin/events/{id}/ga-types
+func (h *Handler) CreateType(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := int32Param(w, r, "id", "Invalid event id")
+	if !ok {
+		return
+	}
+	var req TicketTypeCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	var errs validation.Errors
+	errs.Required("name", req.Name)
+	errs.MaxLength("name", req.Name, 100)
+	errs.Check(req.TierID > 0, "tier_id", "is required")
+	errs.Check(req.Capacity >= 0, "capacity", "cannot be negative")
+	if errs.Respond(w) {
+		return
+	}
+
+	t, err := h.repo.CreateType(r.Context(), eventID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrEventNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrTierNotFound):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(err, ErrNameTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to create ticket type", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+// GetTypes handles GET /events/{id}/ga-types
+func (h *Handler) GetTypes(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := int32Param(w, r, "id", "Invalid event id")
+	if !ok {
+		return
+	}
+	types, err := h.repo.GetTypes(r.Context(), eventID)
+	if err != nil {
+		http.Error(w, "Failed to fetch ticket types", http.StatusInternalServerError)
+		return
+	}
+
+	// Live availability where the counter is warm; Postgres' capacity - sold otherwise
+	for i := range types {
+		available, ok, err := h.redisStore.GAAvailable(r.Context(), types[i].ID)
+		if err != nil || !ok {
+			available = int64(types[i].Capacity - types[i].Sold)
+		}
+		types[i].Available = &available
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types)
+}
+
+// CreateHold handles POST /ga/{typeID}/holds: reserve admissions for HoldTTL
+func (h *Handler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	typeID, ok := int32Param(w, r, "typeID", "Invalid ticket type id")
+	if !ok {
+		return
+	}
+	userID, ok := ctx.Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var req HoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var errs validation.Errors
+	errs.Check(req.Quantity >= 1 && req.Quantity <= MaxHoldQuantity, "quantity",
+		"must be between 1 and "+strconv.Itoa(MaxHoldQuantity))
+	if errs.Respond(w) {
+		return
+	}
+
+	hold := Hold{ID: newHoldID(typeID), TicketTypeID: typeID, Quantity: req.Quantity, ExpiresAt: time.Now().Add(HoldTTL).UTC()}
+
+	// Redis decides; Postgres is only read the first time the counter is needed
+	err := h.redisStore.ReserveGA(ctx, typeID, hold.ID, userID, req.Quantity, hold.ExpiresAt)
+	if errors.Is(err, cache.ErrGANotSeeded) {
+		remaining, seedErr := h.repo.Remaining(ctx, typeID)
+		if seedErr != nil {
+			writeSaleError(w, seedErr)
+			return
+		}
+		if seedErr := h.redisStore.SeedGA(ctx, typeID, remaining); seedErr != nil {
+			http.Error(w, "Failed to hold tickets", http.StatusServiceUnavailable)
+			return
+		}
+		err = h.redisStore.ReserveGA(ctx, typeID, hold.ID, userID, req.Quantity, hold.ExpiresAt)
+	}
+	if err != nil {
+		if errors.Is(err, cache.ErrGASoldOut) {
+			writeSaleError(w, ErrSoldOut)
+			return
+		}
+		http.Error(w, "Failed to hold tickets", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hold)
+}
+
+// ConfirmHold handles POST /ga/holds/{holdID}/confirm: turn the hold into bookings
+func (h *Handler) ConfirmHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := ctx.Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var req ConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var errs validation.Errors
+	errs.MaxLength("promo_code", req.PromoCode, 64)
+	if errs.Respond(w) {
+		return
+	}
+	holdID := chi.URLParam(r, "holdID")
+	typeID, ok := holdType(holdID)
+	if !ok {
+		http.Error(w, cache.ErrGAHoldNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	// 1. Take the hold; from here its tickets are ours to sell or give back
+	quantity, err := h.redisStore.TakeGAHold(ctx, typeID, holdID, userID)
+	if err != nil {
+		if errors.Is(err, cache.ErrGAHoldNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to confirm hold", http.StatusServiceUnavailable)
+		return
+	}
+
+	// 2. The user's purchase limit, on the same Redis counter as seats
+	releaseLimit, err := h.reservePurchases(ctx, typeID, userID, quantity)
+	if err != nil {
+		h.redisStore.ReturnGA(ctx, typeID, quantity)
+		if errors.Is(err, cache.ErrUserQuotaExhausted) {
+			err = bookings.ErrPurchaseLimitExceeded
+		}
+		writeSaleError(w, err)
+		return
+	}
+
+	// 3. One use of the promo/access code
+	var promo *promotions.PromoCode
+	if req.PromoCode != "" {
+		promo, err = h.promos.Reserve(ctx, req.PromoCode, userID)
+		if err != nil {
+			releaseLimit()
+			h.redisStore.ReturnGA(ctx, typeID, quantity)
+			writeSaleError(w, err)
+			return
+		}
+	}
+
+	// 4. Sell them in Postgres
+	sales, err := h.repo.Confirm(ctx, typeID, userID, quantity, promo)
+	if err != nil {
+		releaseLimit()
+		if promo != nil {
+			h.promos.Release(ctx, promo, userID)
+		}
+		h.redisStore.ReturnGA(ctx, typeID, quantity)
+		writeSaleError(w, err)
+		return
+	}
+
+	go func() {
+		msg := map[string]interface{}{
+			"type":           "ga_sold",
+			"ticket_type_id": typeID,
+			"quantity":       quantity,
+		}
+		jsonMsg, _ := json.Marshal(msg)
+		h.hub.Broadcast <- jsonMsg
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sales)
+}
+
+// reservePurchases takes quantity units of the user's per-event limit, like acquire does for
+// a seat: seed the counter from Postgres the first time, then check-and-add in one step.
+// The returned func gives them back if the sale doesn't go through.
+func (h *Handler) reservePurchases(ctx context.Context, typeID, userID int32, quantity int) (func(), error) {
+	eventID, limit, err := h.repo.EventLimit(ctx, typeID)
+	if err != nil {
+		return nil, err
+	}
+	if limit == nil {
+		return func() {}, nil
+	}
+
+	countKey := bookings.PurchaseCountKey(eventID, userID)
+	exists, err := h.redisStore.CounterExists(ctx, countKey)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		held, err := h.repo.CountUserTickets(ctx, eventID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := h.redisStore.SeedCounter(ctx, countKey, held); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.redisStore.ReserveCount(ctx, countKey, int64(quantity), int64(*limit)); err != nil {
+		return nil, err
+	}
+	return func() { h.redisStore.ReleaseCount(ctx, countKey, int64(quantity)) }, nil
+}
+
+// CancelHold handles DELETE /ga/holds/{holdID}: give the tickets back before the hold expires
+func (h *Handler) CancelHold(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	holdID := chi.URLParam(r, "holdID")
+	typeID, ok := holdType(holdID)
+	if !ok {
+		http.Error(w, cache.ErrGAHoldNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	quantity, err := h.redisStore.CancelGAHold(r.Context(), typeID, holdID, userID)
+	if err != nil {
+		if errors.Is(err, cache.ErrGAHoldNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to cancel hold", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"released": quantity})
+}
+
+// newHoldID is "<type>-<random>", so confirm and cancel know which counter the hold is on
+func newHoldID(typeID int32) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return strconv.Itoa(int(typeID)) + "-" + hex.EncodeToString(b)
+}
+
+func holdType(holdID string) (int32, bool) {
+	prefix, _, found := strings.Cut(holdID, "-")
+	if !found {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(prefix, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// writeSaleError maps domain errors to status codes, with the same JSON error codes as seat bookings
+func writeSaleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrTypeNotFound):
+		writeError(w, http.StatusNotFound, CodeTicketTypeNotFound, err.Error())
+	case errors.Is(err, promotions.ErrCodeNotFound), errors.Is(err, promotions.ErrCodeInactive),
+		errors.Is(err, promotions.ErrCodeNotApplicable):
+		writeError(w, http.StatusUnprocessableEntity, bookings.CodePromoInvalid, err.Error())
+	case errors.Is(err, promotions.ErrCodeExhausted), errors.Is(err, promotions.ErrUserLimitReached):
+		writeError(w, http.StatusConflict, bookings.CodePromoExhausted, err.Error())
+	case errors.Is(err, ErrSoldOut):
+		writeError(w, http.StatusConflict, CodeSoldOut, err.Error())
+	case errors.Is(err, ErrNotOnSale):
+		writeError(w, http.StatusConflict, CodeNotOnSale, err.Error())
+	case errors.Is(err, bookings.ErrPurchaseLimitExceeded):
+		writeError(w, http.StatusConflict, bookings.CodePurchaseLimitExceeded, err.Error())
+	case errors.Is(err, bookings.ErrPresaleCodeRequired):
+		writeError(w, http.StatusForbidden, bookings.CodePresaleCodeRequired, err.Error())
+	case errors.Is(err, bookings.ErrEmailNotVerified):
+		writeError(w, http.StatusForbidden, bookings.CodeEmailNotVerified, err.Error())
+	default:
+		http.Error(w, "Failed to sell tickets", http.StatusInternalServerError)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(bookings.ErrorResponse{Code: code, Message: message})
+}
+
+func int32Param(w http.ResponseWriter, r *http.Request, name, message string) (int32, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, name), 10, 32)
+	if err != nil {
+		http.Error(w, message, http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
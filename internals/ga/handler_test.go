@@ -0,0 +1,48 @@
+package ga
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"ticketmaster/internals/bookings"
+	"ticketmaster/internals/promotions"
+)
+
+func TestWriteSaleErrorUsesBookingCodes(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("confirm: %w", bookings.ErrPurchaseLimitExceeded), http.StatusConflict, bookings.CodePurchaseLimitExceeded},
+		{bookings.ErrPresaleCodeRequired, http.StatusForbidden, bookings.CodePresaleCodeRequired},
+		{bookings.ErrEmailNotVerified, http.StatusForbidden, bookings.CodeEmailNotVerified},
+		{promotions.ErrCodeNotFound, http.StatusUnprocessableEntity, bookings.CodePromoInvalid},
+		{promotions.ErrUserLimitReached, http.StatusConflict, bookings.CodePromoExhausted},
+		{ErrSoldOut, http.StatusConflict, CodeSoldOut},
+		{ErrNotOnSale, http.StatusConflict, CodeNotOnSale},
+		{ErrTypeNotFound, http.StatusNotFound, CodeTicketTypeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeSaleError(rec, tt.err)
+			var body bookings.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("body is not JSON: %v", err)
+			}
+			if rec.Code != tt.status || body.Code != tt.code || body.Message != tt.err.Error() {
+				t.Errorf("got %d %+v, want %d %s", rec.Code, body, tt.status, tt.code)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	writeSaleError(rec, errors.New("connection reset"))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("unexpected error: status %d", rec.Code)
+	}
+}
@@ -0,0 +1,64 @@
+package ga
+
+import (
+	"ticketmaster/internals/pricing"
+	"time"
+)
+
+const (
+	MaxHoldQuantity = 10
+	HoldTTL         = 5 * time.Minute // Same checkout window as held seats
+)
+
+// Error codes of general-admission sales, next to the bookings.Code* ones they share
+const (
+	CodeTicketTypeNotFound = "TICKET_TYPE_NOT_FOUND"
+	CodeSoldOut            = "SOLD_OUT"
+	CodeNotOnSale          = "NOT_ON_SALE"
+)
+
+// TicketType is a general-admission (standing) allocation of an event
+type TicketType struct {
+	ID        int32     `json:"id"`
+	EventID   int32     `json:"event_id"`
+	Name      string    `json:"name"`
+	TierID    int32     `json:"tier_id"`
+	Capacity  int32     `json:"capacity"`
+	Sold      int32     `json:"sold"`
+	Available *int64    `json:"available,omitempty" db:"-"` // Live count from Redis, net of holds
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TicketTypeCreationRequest struct {
+	Name     string `json:"name"`
+	TierID   int32  `json:"tier_id"`
+	Capacity int32  `json:"capacity"`
+}
+
+type HoldRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// ConfirmRequest is the optional body of POST /ga/holds/{holdID}/confirm
+type ConfirmRequest struct {
+	PromoCode string `json:"promo_code"` // Discount or presale access code, as for seats
+}
+
+// Hold is returned by POST /ga/{typeID}/holds. Confirm it before ExpiresAt.
+type Hold struct {
+	ID           string    `json:"id"`
+	TicketTypeID int32     `json:"ticket_type_id"`
+	Quantity     int       `json:"quantity"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Booking is one admission; confirming a hold of N creates N of them
+type Booking struct {
+	ID           int32             `json:"id"`
+	TicketTypeID int32             `json:"ticket_type_id"`
+	EventID      int32             `json:"event_id"`
+	UserID       int32             `json:"user_id"`
+	Status       string            `json:"status"`
+	Price        pricing.Breakdown `json:"price"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
@@ -0,0 +1,236 @@
+package ga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ticketmaster/internals/bookings"
+	database "ticketmaster/internals/db"
+	"ticketmaster/internals/ledger"
+	"ticketmaster/internals/pricing"
+	"ticketmaster/internals/promotions"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrTypeNotFound  = errors.New("ticket type not found")
+	ErrTierNotFound  = errors.New("price tier not found for this event")
+	ErrEventNotFound = errors.New("event not found")
+	ErrNameTaken     = errors.New("the event already has a ticket type with this name")
+	ErrNotOnSale     = errors.New("event is not on sale")
+	ErrSoldOut       = errors.New("not enough tickets left")
+)
+
+type Repository struct {
+	db      *database.DB
+	ledger  *ledger.Repository
+	pricing *pricing.Repository
+	promos  *promotions.Repository
+}
+
+func NewRepository(db *database.DB, ledgerRepo *ledger.Repository, pricingRepo *pricing.Repository, promoRepo *promotions.Repository) *Repository {
+	return &Repository{db: db, ledger: ledgerRepo, pricing: pricingRepo, promos: promoRepo}
+}
+
+const typeColumns = `id, event_id, name, tier_id, capacity, sold, created_at`
+
+func (r *Repository) CreateType(ctx context.Context, eventID int32, req TicketTypeCreationRequest) (*TicketType, error) {
+	var exists bool
+	if err := r.db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM events WHERE id = $1)`, eventID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrEventNotFound
+	}
+
+	// The tier must belong to the same event
+	rows, err := r.db.Pool.Query(ctx, `
+		INSERT INTO ga_ticket_types (event_id, name, tier_id, capacity)
+		SELECT $1, $2, id, $4 FROM price_tiers WHERE id = $3 AND event_id = $1
+		RETURNING `+typeColumns, eventID, req.Name, req.TierID, req.Capacity)
+	if err != nil {
+		return nil, err
+	}
+	t, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[TicketType])
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, ErrTierNotFound
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return nil, ErrNameTaken
+	}
+	return t, err
+}
+
+func (r *Repository) GetTypes(ctx context.Context, eventID int32) ([]TicketType, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT `+typeColumns+` FROM ga_ticket_types WHERE event_id = $1 ORDER BY id`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[TicketType])
+}
+
+// Remaining is what the Redis counter is seeded with: capacity minus sold, for a type
+// whose event is still on sale
+func (r *Repository) Remaining(ctx context.Context, typeID int32) (int64, error) {
+	var remaining int64
+	var status string
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT t.capacity - t.sold, e.status
+		FROM ga_ticket_types t JOIN events e ON e.id = t.event_id
+		WHERE t.id = $1`, typeID).Scan(&remaining, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrTypeNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status == "cancelled" {
+		return 0, ErrNotOnSale
+	}
+	return remaining, nil
+}
+
+// EventLimit returns the event of a ticket type and that event's per-user ticket limit (nil if none)
+func (r *Repository) EventLimit(ctx context.Context, typeID int32) (int32, *int32, error) {
+	var eventID int32
+	var limit *int32
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT t.event_id, e.max_tickets_per_user
+		FROM ga_ticket_types t JOIN events e ON e.id = t.event_id
+		WHERE t.id = $1`, typeID).Scan(&eventID, &limit)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil, ErrTypeNotFound
+	}
+	return eventID, limit, err
+}
+
+// CountUserTickets seeds the shared purchase counter, see bookings.PurchaseCountKey
+func (r *Repository) CountUserTickets(ctx context.Context, eventID, userID int32) (int64, error) {
+	return bookings.CountUserTickets(ctx, r.db.Pool, eventID, userID)
+}
+
+// Confirm sells quantity admissions of the type: the authoritative capacity, presale, email
+// and purchase-limit checks, one booking and its ledger entries per admission, all in one
+// transaction. promo is optional and must already be reserved through promotions.Service;
+// it is one use, so it opens the presale for the whole hold but discounts one admission.
+func (r *Repository) Confirm(ctx context.Context, typeID, userID int32, quantity int, promo *promotions.PromoCode) ([]Booking, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Lock the ticket type: concurrent confirms of the same type queue here
+	var eventID, tierID, capacity, sold int32
+	var status string
+	var publicOnsaleAt *time.Time
+	var maxPerUser *int32
+	var requireVerified bool
+	err = tx.QueryRow(ctx, `
+		SELECT t.event_id, t.tier_id, t.capacity, t.sold, e.status, e.public_onsale_at,
+		       e.max_tickets_per_user, e.require_verified_email
+		FROM ga_ticket_types t JOIN events e ON e.id = t.event_id
+		WHERE t.id = $1 FOR UPDATE OF t`, typeID).Scan(
+		&eventID, &tierID, &capacity, &sold, &status, &publicOnsaleAt, &maxPerUser, &requireVerified)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTypeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock ticket type: %w", err)
+	}
+
+	// 2. The same event policies as reserved seats
+	if status == "cancelled" {
+		return nil, ErrNotOnSale
+	}
+	if publicOnsaleAt != nil && time.Now().Before(*publicOnsaleAt) {
+		if promo == nil || promo.Kind != promotions.KindAccess {
+			return nil, bookings.ErrPresaleCodeRequired
+		}
+	}
+	if requireVerified {
+		var verified bool
+		err := tx.QueryRow(ctx, `SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1`, userID).Scan(&verified)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check email verification: %w", err)
+		}
+		if !verified {
+			return nil, bookings.ErrEmailNotVerified
+		}
+	}
+	if maxPerUser != nil {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, eventID, userID); err != nil {
+			return nil, fmt.Errorf("failed to lock purchase counter: %w", err)
+		}
+		held, err := bookings.CountUserTickets(ctx, tx, eventID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if held+int64(quantity) > int64(*maxPerUser) {
+			return nil, bookings.ErrPurchaseLimitExceeded
+		}
+	}
+
+	// 3. Capacity: Redis is only the gatekeeper, this is the truth
+	if sold+int32(quantity) > capacity {
+		return nil, ErrSoldOut
+	}
+	if _, err := tx.Exec(ctx, `UPDATE ga_ticket_types SET sold = sold + $2 WHERE id = $1`, typeID, quantity); err != nil {
+		return nil, fmt.Errorf("failed to update sold count: %w", err)
+	}
+
+	// 4. One booking per admission, priced and recorded like a seat
+	tier, err := r.pricing.TierByID(ctx, tx, tierID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to price ticket type: %w", err)
+	}
+	if promo != nil && !promo.AppliesTo(&eventID, tierID) {
+		return nil, promotions.ErrCodeNotApplicable
+	}
+
+	sales := make([]Booking, 0, quantity)
+	for i := range quantity {
+		var discount pricing.Discount
+		if promo != nil && i == 0 {
+			discount = promo.Discount()
+		}
+		price := pricing.Quote(tier, discount)
+		b := Booking{TicketTypeID: typeID, EventID: eventID, UserID: userID, Status: "confirmed", Price: price}
+		err := tx.QueryRow(ctx,
+			`INSERT INTO bookings (ga_ticket_type_id, event_id, user_id, amount, currency, face_value, discount, service_fee, facility_fee, tax)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at`,
+			typeID, eventID, userID, price.Total, price.Currency, price.FaceValue, price.Discount, price.ServiceFee, price.FacilityFee, price.Tax,
+		).Scan(&b.ID, &b.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert booking: %w", err)
+		}
+		if promo != nil && i == 0 {
+			if err := r.promos.Redeem(ctx, tx, promo, userID, b.ID); err != nil {
+				return nil, err
+			}
+		}
+
+		if price.NetFaceValue()+price.Tax > 0 {
+			entry := ledger.BookingEntry(int64(b.ID), price.NetFaceValue(), price.Tax, price.Currency)
+			if _, err := r.ledger.Post(ctx, tx, entry); err != nil {
+				return nil, fmt.Errorf("failed to record booking in ledger: %w", err)
+			}
+		}
+		if price.Fees() > 0 {
+			entry := ledger.FeeEntry(int64(b.ID), price.Fees(), price.Currency)
+			if _, err := r.ledger.Post(ctx, tx, entry); err != nil {
+				return nil, fmt.Errorf("failed to record fees in ledger: %w", err)
+			}
+		}
+		sales = append(sales, b)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return sales, nil
+}
@@ -0,0 +1,66 @@
+package ga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"ticketmaster/internals/bookings"
+	"ticketmaster/internals/db/dbtest"
+	"ticketmaster/internals/ledger"
+	"ticketmaster/internals/pricing"
+	"ticketmaster/internals/promotions"
+)
+
+func TestConfirmPresaleAccessCode(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	promoRepo := promotions.NewRepository(db)
+	repo := NewRepository(db, ledger.NewRepository(db), pricing.NewRepository(db), promoRepo)
+
+	eventID := dbtest.ID(t, db, `
+		INSERT INTO events (name, starts_at, public_onsale_at, max_tickets_per_user)
+		VALUES ('Gig', NOW() + INTERVAL '30 days', NOW() + INTERVAL '7 days', 3) RETURNING id`)
+	tierID := dbtest.ID(t, db, `INSERT INTO price_tiers (event_id, name, currency, face_value) VALUES ($1, 'GA', 'USD', 5000) RETURNING id`, eventID)
+	typeID := dbtest.ID(t, db, `INSERT INTO ga_ticket_types (event_id, name, tier_id, capacity) VALUES ($1, 'Standing', $2, 100) RETURNING id`, eventID, tierID)
+	dbtest.Exec(t, db, `INSERT INTO promo_codes (code, kind, event_id) VALUES ('FANCLUB', 'access', $1)`, eventID)
+	dbtest.Exec(t, db, `INSERT INTO promo_codes (code, kind, discount_type, discount_value) VALUES ('TENOFF', 'discount', 'fixed', 1000)`)
+	userID := dbtest.ID(t, db, `INSERT INTO users (email, password_hash) VALUES ('fan@example.com', 'x') RETURNING id`)
+
+	// Presale without a code
+	if _, err := repo.Confirm(ctx, typeID, userID, 2, nil); !errors.Is(err, bookings.ErrPresaleCodeRequired) {
+		t.Fatalf("Confirm without a code = %v, want ErrPresaleCodeRequired", err)
+	}
+	discount, _ := promoRepo.GetByCode(ctx, "TENOFF")
+	if _, err := repo.Confirm(ctx, typeID, userID, 2, discount); !errors.Is(err, bookings.ErrPresaleCodeRequired) {
+		t.Fatalf("Confirm with a discount code = %v, want ErrPresaleCodeRequired", err)
+	}
+
+	// An access code opens it for the whole hold, one redemption
+	access, err := promoRepo.GetByCode(ctx, "FANCLUB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sales, err := repo.Confirm(ctx, typeID, userID, 2, access)
+	if err != nil {
+		t.Fatalf("Confirm with an access code: %v", err)
+	}
+	if len(sales) != 2 {
+		t.Fatalf("sold %d admissions, want 2", len(sales))
+	}
+	total, byUser, err := promoRepo.CountUses(ctx, access.ID, userID)
+	if err != nil || total != 1 || byUser != 1 {
+		t.Errorf("redemptions = %d total, %d by user (%v); want 1", total, byUser, err)
+	}
+
+	// The per-user limit counts what was already sold
+	if _, err := repo.Confirm(ctx, typeID, userID, 2, access); !errors.Is(err, bookings.ErrPurchaseLimitExceeded) {
+		t.Errorf("Confirm past the limit = %v, want ErrPurchaseLimitExceeded", err)
+	}
+	if held, err := repo.CountUserTickets(ctx, eventID, userID); err != nil || held != 2 {
+		t.Errorf("CountUserTickets = %d, %v; want 2", held, err)
+	}
+	gotEvent, limit, err := repo.EventLimit(ctx, typeID)
+	if err != nil || gotEvent != eventID || limit == nil || *limit != 3 {
+		t.Errorf("EventLimit = %d, %v, %v; want %d, 3", gotEvent, limit, err, eventID)
+	}
+}
@@ -0,0 +1,168 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// LegacyKeyID names the old shared HMAC secret (MY_JWT_KEY).
+// Tokens signed before key IDs existed carry no kid and are checked against it.
+const LegacyKeyID = "legacy-hs256"
+
+// Key is one signing/verification key
+type Key struct {
+	ID        string
+	Algorithm string      // "EdDSA", "RS256" or "HS256"
+	private   interface{} // ed25519.PrivateKey, *rsa.PrivateKey or []byte; nil for verify-only keys
+	public    interface{} // ed25519.PublicKey, *rsa.PublicKey or []byte
+}
+
+// Keyring holds every key we accept plus the one we currently sign with.
+// Rotation: add the new key, make it active, keep the old one until its tokens expire, then remove it.
+type Keyring struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// Load reads every *.pem file in dir. The file name (without .pem) is the key ID.
+// Private keys (PKCS#8 Ed25519 or RSA) can sign and verify; public keys (*.pub.pem) only verify.
+// legacySecret, if set, is accepted for verification and used for signing only when no other key is active.
+func Load(dir string, activeKID string, legacySecret string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*Key)}
+
+	if dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			key, err := loadPEM(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load key %s: %w", path, err)
+			}
+			if existing, ok := k.keys[key.ID]; ok && existing.private != nil {
+				continue // kid.pem and kid.pub.pem side by side: keep the private one
+			}
+			k.keys[key.ID] = key
+		}
+	}
+
+	if legacySecret != "" {
+		k.keys[LegacyKeyID] = &Key{ID: LegacyKeyID, Algorithm: "HS256", private: []byte(legacySecret), public: []byte(legacySecret)}
+	}
+
+	switch {
+	case activeKID != "":
+		key, ok := k.keys[activeKID]
+		if !ok || key.private == nil {
+			return nil, fmt.Errorf("active key %q not found or has no private part", activeKID)
+		}
+		k.active = key
+	case len(k.keys) == 1 && k.keys[LegacyKeyID] != nil:
+		k.active = k.keys[LegacyKeyID]
+	default:
+		return nil, errors.New("no active signing key: set JWT_ACTIVE_KID (or only MY_JWT_KEY)")
+	}
+	return k, nil
+}
+
+func loadPEM(path string) (*Key, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	name := filepath.Base(path)
+	if strings.HasSuffix(name, ".pub.pem") {
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(strings.TrimSuffix(name, ".pub.pem"), nil, pub)
+	}
+
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch p := priv.(type) {
+	case ed25519.PrivateKey:
+		return newKey(strings.TrimSuffix(name, ".pem"), p, p.Public())
+	case *rsa.PrivateKey:
+		return newKey(strings.TrimSuffix(name, ".pem"), p, &p.PublicKey)
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", priv)
+}
+
+func newKey(id string, private, public interface{}) (*Key, error) {
+	key := &Key{ID: id, private: private, public: public}
+	switch pub := public.(type) {
+	case ed25519.PublicKey:
+		key.Algorithm = "EdDSA"
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.Algorithm = "RS256"
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+	return key, nil
+}
+
+// ActiveKeyID is the kid new tokens are signed with
+func (k *Keyring) ActiveKeyID() string {
+	return k.active.ID
+}
+
+// Sign signs the claims with the active key and stamps its kid in the header
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(k.active.Algorithm), claims)
+	token.Header["kid"] = k.active.ID
+	return token.SignedString(k.active.private)
+}
+
+// Keyfunc is passed to jwt.Parse. It picks the key by kid and refuses any algorithm
+// other than the one that key was issued for (no alg-confusion tricks).
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = LegacyKeyID
+	}
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method %q for key %q", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// Algorithms lists the algorithms of every loaded key, for jwt.WithValidMethods
+func (k *Keyring) Algorithms() []string {
+	seen := make(map[string]bool)
+	var algs []string
+	for _, key := range k.keys {
+		if !seen[key.Algorithm] {
+			seen[key.Algorithm] = true
+			algs = append(algs, key.Algorithm)
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
@@ -0,0 +1,125 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writePEM(t *testing.T, dir, name, kind string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func parse(k *Keyring, token string) (*jwt.Token, error) {
+	return jwt.Parse(token, k.Keyfunc, jwt.WithValidMethods(k.Algorithms()))
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	_, retired, _ := ed25519.GenerateKey(rand.Reader)
+	_, active, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(retired.Public())
+	writePEM(t, dir, "2026-01.pub.pem", "PUBLIC KEY", der)
+	der, _ = x509.MarshalPKCS8PrivateKey(active)
+	writePEM(t, dir, "2026-02.pem", "PRIVATE KEY", der)
+
+	k, err := Load(dir, "2026-02", "old-shared-secret")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := k.Algorithms(); !slices.Equal(got, []string{"EdDSA", "HS256"}) {
+		t.Errorf("Algorithms = %v", got)
+	}
+
+	claims := jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Hour).Unix()}
+	token, err := k.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parse(k, token)
+	if err != nil {
+		t.Fatalf("own token: %v", err)
+	}
+	if parsed.Header["kid"] != "2026-02" || parsed.Method.Alg() != "EdDSA" {
+		t.Errorf("header = %v", parsed.Header)
+	}
+
+	// Tokens of the retired key and of the pre-kid era still verify
+	old := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	old.Header["kid"] = "2026-01"
+	oldToken, _ := old.SignedString(retired)
+	if _, err := parse(k, oldToken); err != nil {
+		t.Errorf("retired key's token: %v", err)
+	}
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("old-shared-secret"))
+	if _, err := parse(k, legacy); err != nil {
+		t.Errorf("legacy token without kid: %v", err)
+	}
+
+	// HMAC over the public key, claiming the Ed25519 kid
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = "2026-02"
+	forgedToken, _ := forged.SignedString([]byte(active.Public().(ed25519.PublicKey)))
+	if _, err := parse(k, forgedToken); err == nil {
+		t.Error("token with the wrong algorithm for its kid was accepted")
+	}
+	unknown := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	unknown.Header["kid"] = "2025-12"
+	unknownToken, _ := unknown.SignedString(active)
+	if _, err := parse(k, unknownToken); err == nil {
+		t.Error("token with an unknown kid was accepted")
+	}
+
+	// Only the asymmetric keys are published
+	set := k.JWKS()
+	if len(set.Keys) != 2 || set.Keys[0].KeyID != "2026-01" || set.Keys[1].KeyID != "2026-02" ||
+		set.Keys[0].KeyType != "OKP" || set.Keys[0].Curve != "Ed25519" {
+		t.Errorf("JWKS = %+v", set)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(priv.Public())
+	writePEM(t, dir, "verify-only.pub.pem", "PUBLIC KEY", der)
+
+	if _, err := Load(dir, "verify-only", ""); err == nil {
+		t.Error("a public key was accepted as the active key")
+	}
+	if _, err := Load(dir, "", ""); err == nil {
+		t.Error("loaded without any signing key")
+	}
+
+	// Only the legacy secret: it signs, as before key rotation
+	k, err := Load("", "", "old-shared-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.ActiveKeyID() != LegacyKeyID || len(k.JWKS().Keys) != 0 {
+		t.Errorf("active %q, JWKS %+v", k.ActiveKeyID(), k.JWKS())
+	}
+
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ = x509.MarshalPKCS8PrivateKey(weak)
+	writePEM(t, dir, "weak.pem", "PRIVATE KEY", der)
+	if _, err := Load(dir, "weak", ""); err == nil {
+		t.Error("a 1024-bit RSA key was accepted")
+	}
+}
@@ -0,0 +1,26 @@
+package ledger
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// GetReconciliation handles GET /admin/ledger/reconciliation
+func (h *Handler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	report, err := h.repo.Reconcile(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to build reconciliation report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,108 @@
+package ledger
+
+import "time"
+
+// Direction is the side of the journal a posting lands on
+type Direction string
+
+const (
+	Debit  Direction = "debit"
+	Credit Direction = "credit"
+)
+
+// Account codes seeded by the ledger migration
+const (
+	AccountCustomerFunds    = "customer_funds"
+	AccountOrganizerPayable = "organizer_payable"
+	AccountFeeRevenue       = "fee_revenue"
+	AccountTaxPayable       = "tax_payable"
+)
+
+// Entry kinds
+const (
+	KindBooking = "booking"
+	KindRefund  = "refund"
+	KindFee     = "fee"
+)
+
+// Posting is one leg of a journal entry. Amount is in minor units (cents) and always positive.
+type Posting struct {
+	AccountCode string    `json:"account"`
+	Direction   Direction `json:"direction"`
+	Amount      int64     `json:"amount"`
+}
+
+// Entry is a balanced set of postings describing a single money movement
+type Entry struct {
+	ID            int64     `json:"id"`
+	Kind          string    `json:"kind"`
+	ReferenceType string    `json:"reference_type"`
+	ReferenceID   int64     `json:"reference_id"`
+	Currency      string    `json:"currency"`
+	Postings      []Posting `json:"postings"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// BookingEntry records the face value and tax a customer paid for a booking.
+// The face value is held on behalf of the organizer until payout; tax is owed to the authorities.
+func BookingEntry(bookingID int64, faceValue int64, tax int64, currency string) Entry {
+	entry := Entry{
+		Kind:          KindBooking,
+		ReferenceType: "booking",
+		ReferenceID:   bookingID,
+		Currency:      currency,
+		Postings: []Posting{
+			{AccountCode: AccountCustomerFunds, Direction: Debit, Amount: faceValue + tax},
+		},
+	}
+	if faceValue > 0 {
+		entry.Postings = append(entry.Postings, Posting{AccountCode: AccountOrganizerPayable, Direction: Credit, Amount: faceValue})
+	}
+	if tax > 0 {
+		entry.Postings = append(entry.Postings, Posting{AccountCode: AccountTaxPayable, Direction: Credit, Amount: tax})
+	}
+	return entry
+}
+
+// FeeEntry records a platform fee charged to the customer on top of the face value
+func FeeEntry(bookingID int64, amount int64, currency string) Entry {
+	return Entry{
+		Kind:          KindFee,
+		ReferenceType: "booking",
+		ReferenceID:   bookingID,
+		Currency:      currency,
+		Postings: []Posting{
+			{AccountCode: AccountCustomerFunds, Direction: Debit, Amount: amount},
+			{AccountCode: AccountFeeRevenue, Direction: Credit, Amount: amount},
+		},
+	}
+}
+
+// AccountBalance is the running total of one account in one currency
+type AccountBalance struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Currency string `json:"currency"`
+	Debits   int64  `json:"debits"`
+	Credits  int64  `json:"credits"`
+	Balance  int64  `json:"balance"` // Signed by the account's normal side
+}
+
+// CurrencyTotal sums every posting in one currency. Amounts in different currencies never add up.
+type CurrencyTotal struct {
+	Currency string `json:"currency"`
+	Debits   int64  `json:"debits"`
+	Credits  int64  `json:"credits"`
+	Balanced bool   `json:"balanced"`
+}
+
+// ReconciliationReport is what finance uses to prove the books add up
+type ReconciliationReport struct {
+	GeneratedAt          time.Time        `json:"generated_at"`
+	Accounts             []AccountBalance `json:"accounts"`
+	Totals               []CurrencyTotal  `json:"totals"`
+	Balanced             bool             `json:"balanced"`
+	UnbalancedEntries    []int64          `json:"unbalanced_entries"`
+	BookingsWithoutEntry []int32          `json:"bookings_without_entry"`
+}
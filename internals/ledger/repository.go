@@ -0,0 +1,223 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	database "ticketmaster/internals/db"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrUnbalancedEntry = errors.New("ledger entry is not balanced")
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Post writes a journal entry inside the caller's transaction, so the money movement
+// commits or rolls back together with the business change that caused it.
+func (r *Repository) Post(ctx context.Context, tx pgx.Tx, entry Entry) (int64, error) {
+	// 1. Validate before touching the database
+	if err := validate(entry); err != nil {
+		return 0, err
+	}
+
+	// 2. Create the entry header
+	var entryID int64
+	err := tx.QueryRow(ctx,
+		`INSERT INTO ledger_entries (kind, reference_type, reference_id, currency) VALUES ($1, $2, $3, $4) RETURNING id`,
+		entry.Kind, entry.ReferenceType, entry.ReferenceID, entry.Currency,
+	).Scan(&entryID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert ledger entry: %w", err)
+	}
+
+	// 3. Write every leg. The deferred trigger re-checks the balance at commit.
+	for _, p := range entry.Postings {
+		tag, err := tx.Exec(ctx,
+			`INSERT INTO ledger_postings (entry_id, account_id, direction, amount)
+			 SELECT $1, id, $3, $4 FROM ledger_accounts WHERE code = $2`,
+			entryID, p.AccountCode, string(p.Direction), p.Amount,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert ledger posting: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return 0, fmt.Errorf("unknown ledger account %q", p.AccountCode)
+		}
+	}
+
+	return entryID, nil
+}
+
+// ReverseBooking posts a refund entry that mirrors every booking and fee posting
+// recorded for the booking, so organizer share, fees and taxes all unwind together.
+func (r *Repository) ReverseBooking(ctx context.Context, tx pgx.Tx, bookingID int64) (int64, error) {
+	// 1. Net the original legs per account
+	rows, err := tx.Query(ctx, `
+		SELECT a.code, e.currency,
+		       SUM(CASE WHEN p.direction = 'debit' THEN p.amount ELSE -p.amount END)
+		FROM ledger_postings p
+		JOIN ledger_entries e ON e.id = p.entry_id
+		JOIN ledger_accounts a ON a.id = p.account_id
+		WHERE e.reference_type = 'booking' AND e.reference_id = $1
+		  AND e.kind IN ('booking', 'fee')
+		GROUP BY a.code, e.currency`, bookingID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load booking postings: %w", err)
+	}
+
+	// 2. Swap the sides
+	entry := Entry{Kind: KindRefund, ReferenceType: "booking", ReferenceID: bookingID}
+	for rows.Next() {
+		var code, currency string
+		var net int64
+		if err := rows.Scan(&code, &currency, &net); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		entry.Currency = currency
+		switch {
+		case net > 0:
+			entry.Postings = append(entry.Postings, Posting{AccountCode: code, Direction: Credit, Amount: net})
+		case net < 0:
+			entry.Postings = append(entry.Postings, Posting{AccountCode: code, Direction: Debit, Amount: -net})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(entry.Postings) == 0 {
+		return 0, fmt.Errorf("booking %d has no ledger entries to reverse", bookingID)
+	}
+
+	return r.Post(ctx, tx, entry)
+}
+
+func validate(entry Entry) error {
+	if len(entry.Postings) < 2 {
+		return fmt.Errorf("%w: needs at least two postings", ErrUnbalancedEntry)
+	}
+	if len(entry.Currency) != 3 {
+		return fmt.Errorf("invalid currency %q", entry.Currency)
+	}
+
+	var debits, credits int64
+	for _, p := range entry.Postings {
+		if p.Amount <= 0 {
+			return fmt.Errorf("posting amount must be positive, got %d", p.Amount)
+		}
+		switch p.Direction {
+		case Debit:
+			debits += p.Amount
+		case Credit:
+			credits += p.Amount
+		default:
+			return fmt.Errorf("invalid posting direction %q", p.Direction)
+		}
+	}
+	if debits != credits {
+		return fmt.Errorf("%w: debits %d != credits %d", ErrUnbalancedEntry, debits, credits)
+	}
+	return nil
+}
+
+// Reconcile builds the reconciliation report from the raw postings
+func (r *Repository) Reconcile(ctx context.Context) (*ReconciliationReport, error) {
+	report := &ReconciliationReport{
+		GeneratedAt:          time.Now().UTC(),
+		Accounts:             []AccountBalance{},
+		Totals:               []CurrencyTotal{},
+		UnbalancedEntries:    []int64{},
+		BookingsWithoutEntry: []int32{},
+	}
+
+	// 1. Balance per account and currency, and debits/credits per currency
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT a.code, a.name, a.type, e.currency,
+		       COALESCE(SUM(p.amount) FILTER (WHERE p.direction = 'debit'), 0),
+		       COALESCE(SUM(p.amount) FILTER (WHERE p.direction = 'credit'), 0)
+		FROM ledger_postings p
+		JOIN ledger_accounts a ON a.id = p.account_id
+		JOIN ledger_entries e ON e.id = p.entry_id
+		GROUP BY a.code, a.name, a.type, e.currency
+		ORDER BY a.code, e.currency`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum postings: %w", err)
+	}
+	for rows.Next() {
+		var b AccountBalance
+		if err := rows.Scan(&b.Code, &b.Name, &b.Type, &b.Currency, &b.Debits, &b.Credits); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if b.Type == "asset" || b.Type == "expense" {
+			b.Balance = b.Debits - b.Credits
+		} else {
+			b.Balance = b.Credits - b.Debits
+		}
+		report.Accounts = append(report.Accounts, b)
+
+		// Rows come ordered by account then currency, so look the currency up
+		i := slices.IndexFunc(report.Totals, func(t CurrencyTotal) bool { return t.Currency == b.Currency })
+		if i < 0 {
+			report.Totals = append(report.Totals, CurrencyTotal{Currency: b.Currency})
+			i = len(report.Totals) - 1
+		}
+		report.Totals[i].Debits += b.Debits
+		report.Totals[i].Credits += b.Credits
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 2. Entries whose legs don't cancel out (should be impossible thanks to the trigger)
+	rows, err = r.db.Pool.Query(ctx, `
+		SELECT entry_id FROM ledger_postings
+		GROUP BY entry_id
+		HAVING SUM(CASE WHEN direction = 'debit' THEN amount ELSE -amount END) <> 0
+		ORDER BY entry_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unbalanced entries: %w", err)
+	}
+	unbalanced, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+	report.UnbalancedEntries = append(report.UnbalancedEntries, unbalanced...)
+
+	// 3. Paid bookings that never made it into the books
+	rows, err = r.db.Pool.Query(ctx, `
+		SELECT b.id FROM bookings b
+		WHERE b.amount > 0 AND NOT EXISTS (
+			SELECT 1 FROM ledger_entries e
+			WHERE e.kind IN ('booking', 'fee') AND e.reference_type = 'booking' AND e.reference_id = b.id
+		)
+		ORDER BY b.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unrecorded bookings: %w", err)
+	}
+	missing, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	if err != nil {
+		return nil, err
+	}
+	report.BookingsWithoutEntry = append(report.BookingsWithoutEntry, missing...)
+
+	slices.SortFunc(report.Totals, func(a, b CurrencyTotal) int { return strings.Compare(a.Currency, b.Currency) })
+	report.Balanced = len(report.UnbalancedEntries) == 0
+	for i := range report.Totals {
+		report.Totals[i].Balanced = report.Totals[i].Debits == report.Totals[i].Credits
+		report.Balanced = report.Balanced && report.Totals[i].Balanced
+	}
+	return report, nil
+}
@@ -0,0 +1,158 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"ticketmaster/internals/db/dbtest"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   Entry
+		wantErr bool
+	}{
+		{"booking with tax", BookingEntry(1, 5000, 400, "USD"), false},
+		{"fee", FeeEntry(1, 250, "EUR"), false},
+		{"single leg", Entry{Currency: "USD", Postings: []Posting{{AccountCustomerFunds, Debit, 10}}}, true},
+		{"bad currency", FeeEntry(1, 250, "US"), true},
+		{"zero amount", FeeEntry(1, 0, "USD"), true},
+		{"unbalanced", Entry{Currency: "USD", Postings: []Posting{
+			{AccountCustomerFunds, Debit, 100},
+			{AccountFeeRevenue, Credit, 90},
+		}}, true},
+		{"bad direction", Entry{Currency: "USD", Postings: []Posting{
+			{AccountCustomerFunds, Debit, 100},
+			{AccountFeeRevenue, "sideways", 100},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate(tt.entry); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBalanceTriggerRejectsUnbalancedEntries(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+
+	// Post validates in Go first
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewRepository(db).Post(ctx, tx, Entry{Kind: KindFee, ReferenceType: "booking", ReferenceID: 1, Currency: "USD", Postings: []Posting{
+		{AccountCustomerFunds, Debit, 100},
+		{AccountFeeRevenue, Credit, 90},
+	}})
+	tx.Rollback(ctx)
+	if !errors.Is(err, ErrUnbalancedEntry) {
+		t.Fatalf("Post() error = %v, want ErrUnbalancedEntry", err)
+	}
+
+	// Raw SQL that skips Post is stopped by the deferred trigger at commit
+	tx, err = db.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	var entryID int64
+	err = tx.QueryRow(ctx, `INSERT INTO ledger_entries (kind, reference_type, reference_id, currency) VALUES ('fee', 'booking', 1, 'USD') RETURNING id`).Scan(&entryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO ledger_postings (entry_id, account_id, direction, amount)
+		SELECT $1, id, 'debit', 100 FROM ledger_accounts WHERE code = 'customer_funds'`, entryID)
+	if err != nil {
+		t.Fatalf("the check is deferred, the insert itself should pass: %v", err)
+	}
+	err = tx.Commit(ctx)
+	if err == nil || !strings.Contains(err.Error(), "unbalanced by 100") {
+		t.Fatalf("Commit() error = %v, want the trigger to reject the entry", err)
+	}
+
+	var count int
+	if err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM ledger_entries`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("ledger_entries has %d rows after the rejected commit, want 0", count)
+	}
+}
+
+func TestReconcileTotalsPerCurrency(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	repo := NewRepository(db)
+
+	post := func(entries ...Entry) {
+		tx, err := db.Pool.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback(ctx)
+		for _, e := range entries {
+			if _, err := repo.Post(ctx, tx, e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	post(BookingEntry(1, 5000, 400, "USD"), FeeEntry(1, 250, "USD"), BookingEntry(2, 3000, 0, "EUR"))
+
+	report, err := repo.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Balanced {
+		t.Errorf("report not balanced: %+v", report)
+	}
+	want := []CurrencyTotal{
+		{Currency: "EUR", Debits: 3000, Credits: 3000, Balanced: true},
+		{Currency: "USD", Debits: 5650, Credits: 5650, Balanced: true},
+	}
+	if len(report.Totals) != len(want) {
+		t.Fatalf("totals = %+v, want %+v", report.Totals, want)
+	}
+	for i := range want {
+		if report.Totals[i] != want[i] {
+			t.Errorf("totals[%d] = %+v, want %+v", i, report.Totals[i], want[i])
+		}
+	}
+
+	// Corrupt the books behind the trigger's back: +100 USD and -100 EUR cancel out
+	// when summed together, but each currency is off on its own.
+	dbtest.Exec(t, db, `ALTER TABLE ledger_postings DISABLE TRIGGER ledger_postings_balanced`)
+	dbtest.Exec(t, db, `
+		WITH usd AS (INSERT INTO ledger_entries (kind, reference_type, reference_id, currency) VALUES ('fee', 'booking', 3, 'USD') RETURNING id)
+		INSERT INTO ledger_postings (entry_id, account_id, direction, amount)
+		SELECT usd.id, a.id, 'debit', 100 FROM usd, ledger_accounts a WHERE a.code = 'customer_funds'`)
+	dbtest.Exec(t, db, `
+		WITH eur AS (INSERT INTO ledger_entries (kind, reference_type, reference_id, currency) VALUES ('fee', 'booking', 4, 'EUR') RETURNING id)
+		INSERT INTO ledger_postings (entry_id, account_id, direction, amount)
+		SELECT eur.id, a.id, 'credit', 100 FROM eur, ledger_accounts a WHERE a.code = 'fee_revenue'`)
+
+	report, err = repo.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Balanced {
+		t.Error("report balanced although both currencies are off by 100")
+	}
+	for _, total := range report.Totals {
+		if total.Balanced {
+			t.Errorf("%s total marked balanced: %+v", total.Currency, total)
+		}
+	}
+	if len(report.UnbalancedEntries) != 2 {
+		t.Errorf("unbalanced entries = %v, want 2", report.UnbalancedEntries)
+	}
+}
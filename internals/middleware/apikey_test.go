@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestScopeMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	asKey := func(scopes ...string) context.Context {
+		return context.WithValue(context.Background(), ScopesKey, scopes)
+	}
+	asUser := context.WithValue(context.Background(), UserIDKey, int32(7))
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		ctx     context.Context
+		want    int
+	}{
+		{"key scope: key with scope", RequireAPIKeyScope("admin")(ok), asKey("seats:write", "admin"), http.StatusNoContent},
+		{"key scope: key with one of the scopes", RequireAPIKeyScope("seats:write", "admin")(ok), asKey("seats:write"), http.StatusNoContent},
+		{"key scope: key with none of the scopes", RequireAPIKeyScope("seats:write", "admin")(ok), asKey("refunds:read"), http.StatusForbidden},
+		{"key scope: key without scope", RequireAPIKeyScope("admin")(ok), asKey("tickets:scan"), http.StatusForbidden},
+		{"key scope: user", RequireAPIKeyScope("admin")(ok), asUser, http.StatusForbidden},
+		{"key scope: nobody", RequireAPIKeyScope("admin")(ok), context.Background(), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/admin/events", nil).WithContext(tt.ctx)
+			tt.handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireOwner(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	owners := map[int32]int32{1: 10, 2: 20} // Event 3 has no organization, 4 doesn't exist
+	lookup := func(_ context.Context, id int32) (*int32, error) {
+		if org, found := owners[id]; found {
+			return &org, nil
+		}
+		return nil, nil
+	}
+	router := chi.NewRouter()
+	router.Use(MarkOperator("platform"))
+	router.With(RequireOwner("id", lookup)).Post("/admin/events/{id}/cancel", ok)
+
+	asKey := func(orgID int32, scopes ...string) context.Context {
+		ctx := context.WithValue(context.Background(), OrganizationIDKey, orgID)
+		return context.WithValue(ctx, ScopesKey, scopes)
+	}
+	tests := []struct {
+		name string
+		path string
+		ctx  context.Context
+		want int
+	}{
+		{"own event", "/admin/events/1/cancel", asKey(10, "admin"), http.StatusNoContent},
+		{"another organization's event", "/admin/events/2/cancel", asKey(10, "admin"), http.StatusForbidden},
+		{"event without an organization", "/admin/events/3/cancel", asKey(10, "admin"), http.StatusForbidden},
+		{"unknown event", "/admin/events/4/cancel", asKey(10, "admin"), http.StatusForbidden},
+		{"operator, any event", "/admin/events/2/cancel", asKey(1, "platform", "admin"), http.StatusNoContent},
+		{"operator, event without an organization", "/admin/events/3/cancel", asKey(1, "platform"), http.StatusNoContent},
+		{"user token", "/admin/events/1/cancel", context.WithValue(context.Background(), UserIDKey, int32(10)), http.StatusForbidden},
+		{"bad id", "/admin/events/x/cancel", asKey(10, "admin"), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, nil).WithContext(tt.ctx))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
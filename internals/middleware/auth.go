@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"ticketmaster/internals/keyring"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -12,21 +13,39 @@ import (
 // Define a custom key type to avoid context collisions
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey      contextKey = "user_id"
+	TokenIDKey     contextKey = "token_id"     // The access token's jti (string)
+	TokenExpiryKey contextKey = "token_expiry" // The access token's exp (time.Time)
+)
+
+// Denylist tells the middleware whether a token was revoked before it expired (e.g. on logout)
+type Denylist interface {
+	IsTokenDenied(ctx context.Context, jti string) (bool, error)
+}
 
 type authMiddleware struct {
-	jwtKey string
+	keys     *keyring.Keyring
+	denylist Denylist
+	apiKeys  APIKeyVerifier
 }
 
-func NewMiddleware(jwtString string) (*authMiddleware, error) {
-	if jwtString == "" {
-		return nil, fmt.Errorf("jwt secret cannot be empty")
+// NewMiddleware builds the auth middleware. apiKeys may be nil to accept user tokens only.
+func NewMiddleware(keys *keyring.Keyring, denylist Denylist, apiKeys APIKeyVerifier) (*authMiddleware, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("keyring cannot be nil")
 	}
-	return &authMiddleware{jwtKey: jwtString}, nil
+	return &authMiddleware{keys: keys, denylist: denylist, apiKeys: apiKeys}, nil
 }
 
 func (a *authMiddleware) Auth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 0. Machine credentials may also come as "X-API-Key: tm_..."
+		if key := r.Header.Get("X-API-Key"); key != "" {
+			a.apiKeyAuth(w, r, next, key)
+			return
+		}
+
 		// 1. Get the header: "Authorization: Bearer <token>"
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -40,15 +59,13 @@ func (a *authMiddleware) Auth(next http.Handler) http.Handler {
 			http.Error(w, "Invalid token format", http.StatusUnauthorized)
 			return
 		}
+		if strings.HasPrefix(tokenString, APIKeyPrefix) {
+			a.apiKeyAuth(w, r, next, tokenString)
+			return
+		}
 
-		// 3. Parse & Validate Token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method")
-			}
-			// In production, use os.Getenv("JWT_SECRET")
-			return []byte(a.jwtKey), nil
-		})
+		// 3. Parse & Validate Token (the kid header picks the key, any active key is accepted)
+		token, err := jwt.Parse(tokenString, a.keys.Keyfunc, jwt.WithValidMethods(a.keys.Algorithms()))
 
 		if err != nil || !token.Valid {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
@@ -70,10 +87,32 @@ func (a *authMiddleware) Auth(next http.Handler) http.Handler {
 		}
 		userID := int32(userIDFloat)
 
-		// 5. Inject into Context (The critical part!)
+		// 5. Revocation check. Tokens without a jti predate revocation support: force a fresh login.
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+		denied, err := a.denylist.IsTokenDenied(r.Context(), jti)
+		if err != nil {
+			// Fail closed: if we can't tell whether it was revoked, don't trust it
+			http.Error(w, "Unable to verify token", http.StatusServiceUnavailable)
+			return
+		}
+		if denied {
+			http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+			return
+		}
+		expiresAt, _ := claims.GetExpirationTime()
+
+		// 6. Inject into Context (The critical part!)
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, TokenIDKey, jti)
+		if expiresAt != nil {
+			ctx = context.WithValue(ctx, TokenExpiryKey, expiresAt.Time)
+		}
 
-		// 6. Pass the request down the chain
+		// 7. Pass the request down the chain
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"ticketmaster/internals/cache"
+	"ticketmaster/internals/keyring"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// brokenDenylist is Redis being down
+type brokenDenylist struct{}
+
+func (brokenDenylist) IsTokenDenied(context.Context, string) (bool, error) {
+	return false, context.DeadlineExceeded
+}
+
+func TestAuthDenylist(t *testing.T) {
+	keys, err := keyring.Load("", "", "test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := cache.NewRedisStore(miniredis.RunT(t).Addr(), "")
+	ctx := context.Background()
+
+	sign := func(claims jwt.MapClaims) string {
+		t.Helper()
+		token, err := keys.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	live := sign(jwt.MapClaims{"user_id": 7, "jti": "live", "exp": exp})
+	revoked := sign(jwt.MapClaims{"user_id": 7, "jti": "revoked", "exp": exp})
+	noJTI := sign(jwt.MapClaims{"user_id": 7, "exp": exp})
+	expired := sign(jwt.MapClaims{"user_id": 7, "jti": "old", "exp": time.Now().Add(-time.Minute).Unix()})
+	if err := store.DenyToken(ctx, "revoked", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value(UserIDKey) != int32(7) || r.Context().Value(TokenIDKey) != "live" {
+			t.Errorf("context = user %v, jti %v", r.Context().Value(UserIDKey), r.Context().Value(TokenIDKey))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	withRedis, _ := NewMiddleware(keys, store, nil)
+	withBroken, _ := NewMiddleware(keys, brokenDenylist{}, nil)
+
+	tests := []struct {
+		name  string
+		auth  *authMiddleware
+		token string
+		want  int
+	}{
+		{"live token", withRedis, live, http.StatusNoContent},
+		{"denylisted token", withRedis, revoked, http.StatusUnauthorized},
+		{"token without a jti", withRedis, noJTI, http.StatusUnauthorized},
+		{"expired token", withRedis, expired, http.StatusUnauthorized},
+		{"denylist unavailable", withBroken, live, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			tt.auth.Auth(ok).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
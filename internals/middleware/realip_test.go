@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		realIP     string
+		forwarded  string
+		want       string
+	}{
+		{"direct client spoofing X-Real-IP", "203.0.113.9:5000", "1.2.3.4", "", "203.0.113.9:5000"},
+		{"direct client spoofing X-Forwarded-For", "203.0.113.9:5000", "", "1.2.3.4", "203.0.113.9:5000"},
+		{"nginx in the trusted range", "10.1.2.3:41000", "198.51.100.7", "", "198.51.100.7"},
+		{"trusted single IP", "192.168.1.5:41000", "198.51.100.7", "", "198.51.100.7"},
+		{"neighbour of the trusted IP", "192.168.1.6:41000", "198.51.100.7", "", "192.168.1.6:41000"},
+		{"forwarded chain skips our proxies", "10.1.2.3:41000", "", "1.2.3.4, 198.51.100.7, 10.9.9.9", "198.51.100.7"},
+		{"garbage header", "10.1.2.3:41000", "not-an-ip", "", "10.1.2.3:41000"},
+		{"proxy without headers", "10.1.2.3:41000", "", "", "10.1.2.3:41000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = r.RemoteAddr }))
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if nets, err := ParseTrustedProxies(""); err != nil || len(nets) != 0 {
+		t.Errorf("empty list = %v, %v; want no proxies", nets, err)
+	}
+	if nets, err := ParseTrustedProxies("::1,172.16.0.0/12"); err != nil || len(nets) != 2 {
+		t.Errorf("ParseTrustedProxies = %v, %v; want 2 networks", nets, err)
+	}
+	for _, bad := range []string{"nginx", "10.0.0.0/33"} {
+		if _, err := ParseTrustedProxies(bad); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package organizations
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"ticketmaster/internals/validation"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// CreateOrganization handles POST /admin/organizations
+func (h *Handler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var req OrganizationCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	var errs validation.Errors
+	errs.Required("name", req.Name)
+	errs.MaxLength("name", req.Name, 200)
+	if errs.Respond(w) {
+		return
+	}
+
+	org, err := h.repo.CreateOrganization(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, ErrNameTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create organization", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(org)
+}
+
+// GetOrganizations handles GET /admin/organizations
+func (h *Handler) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.repo.GetAll(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch organizations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orgs)
+}
@@ -0,0 +1,13 @@
+package organizations
+
+import "time"
+
+type Organization struct {
+	ID        int32     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OrganizationCreationRequest struct {
+	Name string `json:"name"`
+}
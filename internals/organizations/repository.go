@@ -0,0 +1,41 @@
+package organizations
+
+import (
+	"context"
+	"errors"
+	database "ticketmaster/internals/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrNameTaken = errors.New("an organization with this name already exists")
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) CreateOrganization(ctx context.Context, req OrganizationCreationRequest) (*Organization, error) {
+	rows, err := r.db.Pool.Query(ctx, `INSERT INTO organizations (name) VALUES ($1) RETURNING id, name, created_at`, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	org, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[Organization])
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrNameTaken
+	}
+	return org, err
+}
+
+func (r *Repository) GetAll(ctx context.Context) ([]Organization, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT id, name, created_at FROM organizations ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Organization])
+}
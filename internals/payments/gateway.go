@@ -0,0 +1,38 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// RefundRequest asks the payment provider to return money to a customer.
+// IdempotencyKey must be stable across retries so a crash mid-call never refunds twice.
+type RefundRequest struct {
+	IdempotencyKey string
+	BookingID      int32
+	Amount         int64 // Minor units (cents)
+	Currency       string
+}
+
+// Gateway is the payment layer the rest of the system talks to.
+// A real provider (Stripe, Adyen...) plugs in behind this interface.
+type Gateway interface {
+	Refund(ctx context.Context, req RefundRequest) (providerRef string, err error)
+}
+
+// SimulatedGateway accepts every request without moving real money.
+// It is what local and staging environments run against.
+type SimulatedGateway struct{}
+
+func NewSimulatedGateway() *SimulatedGateway {
+	return &SimulatedGateway{}
+}
+
+func (g *SimulatedGateway) Refund(ctx context.Context, req RefundRequest) (string, error) {
+	if req.Amount <= 0 {
+		return "", fmt.Errorf("refund amount must be positive, got %d", req.Amount)
+	}
+	log.Printf("💸 [simulated] refunded %d %s for booking %d", req.Amount, req.Currency, req.BookingID)
+	return "sim_" + req.IdempotencyKey, nil
+}
@@ -0,0 +1,36 @@
+package pricing
+
+// Discount lowers the face value before fees and tax are computed.
+// Percent is in basis points, Fixed in minor units of the tier's currency.
+type Discount struct {
+	Percent int32
+	Fixed   int64
+}
+
+// Quote computes the breakdown for one ticket in the given tier.
+// Service fee is a percentage of the (discounted) face value plus a fixed part;
+// tax applies to the discounted face value and fees.
+func Quote(t Tier, d Discount) Breakdown {
+	b := Breakdown{
+		Currency:  t.Currency,
+		FaceValue: t.FaceValue,
+		Discount:  min(applyBps(t.FaceValue, d.Percent)+d.Fixed, t.FaceValue),
+	}
+	net := b.NetFaceValue()
+	b.ServiceFee = applyBps(net, t.ServiceFeeBps) + t.ServiceFeeFixed
+	b.FacilityFee = t.FacilityFee
+	b.Tax = applyBps(net+b.Fees(), t.TaxBps)
+	b.Total = net + b.Fees() + b.Tax
+	return b
+}
+
+// legacyTier prices a seat that has no tier from its old whole-unit seats.price column
+func legacyTier(price int32) Tier {
+	return Tier{Currency: DefaultCurrency, FaceValue: int64(price) * 100}
+}
+
+// applyBps returns amount * bps / 10000, rounded half up.
+// Integer math only: floats and money don't mix.
+func applyBps(amount int64, bps int32) int64 {
+	return (amount*int64(bps) + 5000) / 10000
+}
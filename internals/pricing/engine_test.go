@@ -0,0 +1,54 @@
+package pricing
+
+import "testing"
+
+func TestQuote(t *testing.T) {
+	tier := Tier{Currency: "EUR", FaceValue: 5000, ServiceFeeBps: 1000, ServiceFeeFixed: 150, FacilityFee: 200, TaxBps: 800}
+
+	tests := []struct {
+		name     string
+		discount Discount
+		want     Breakdown
+	}{
+		{"no discount", Discount{}, Breakdown{Currency: "EUR", FaceValue: 5000, ServiceFee: 650, FacilityFee: 200, Tax: 468, Total: 6318}},
+		// Fees and tax follow the discounted face value
+		{"percent", Discount{Percent: 2000}, Breakdown{Currency: "EUR", FaceValue: 5000, Discount: 1000, ServiceFee: 550, FacilityFee: 200, Tax: 380, Total: 5130}},
+		{"percent and fixed", Discount{Percent: 1000, Fixed: 500}, Breakdown{Currency: "EUR", FaceValue: 5000, Discount: 1000, ServiceFee: 550, FacilityFee: 200, Tax: 380, Total: 5130}},
+		// Never below zero: the fees are still owed
+		{"more than face value", Discount{Fixed: 9999}, Breakdown{Currency: "EUR", FaceValue: 5000, Discount: 5000, ServiceFee: 150, FacilityFee: 200, Tax: 28, Total: 378}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Quote(tier, tt.discount)
+			if got != tt.want {
+				t.Errorf("Quote = %+v, want %+v", got, tt.want)
+			}
+			if got.NetFaceValue()+got.Fees()+got.Tax != got.Total {
+				t.Errorf("items of %+v don't add up to the total", got)
+			}
+		})
+	}
+
+	if got := Quote(legacyTier(45), Discount{}); got.Currency != DefaultCurrency || got.FaceValue != 4500 || got.Total != 4500 {
+		t.Errorf("legacy seat quote = %+v, want 4500 %s with no fees", got, DefaultCurrency)
+	}
+}
+
+func TestApplyBps(t *testing.T) {
+	tests := []struct {
+		amount int64
+		bps    int32
+		want   int64
+	}{
+		{10000, 250, 250},
+		{5, 1000, 1}, // 0.5 rounds up
+		{4, 1000, 0},
+		{0, 1000, 0},
+		{12345, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := applyBps(tt.amount, tt.bps); got != tt.want {
+			t.Errorf("applyBps(%d, %d) = %d, want %d", tt.amount, tt.bps, got, tt.want)
+		}
+	}
+}
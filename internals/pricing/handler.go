@@ -0,0 +1,154 @@
+package pricing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"ticketmaster/internals/validation"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// CreateTier handles POST /admin/events/{id}/tiers
+func (h *Handler) CreateTier(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var req TierCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Currency = strings.ToUpper(req.Currency)
+	var errs validation.Errors
+	validateTier(&errs, req)
+	if errs.Respond(w) {
+		return
+	}
+
+	tier, err := h.repo.CreateTier(r.Context(), eventID, req)
+	if err != nil {
+		http.Error(w, "Failed to create price tier", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tier)
+}
+
+// GetTiers handles GET /events/{id}/tiers
+func (h *Handler) GetTiers(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	tiers, err := h.repo.GetTiers(r.Context(), eventID)
+	if err != nil {
+		http.Error(w, "Failed to fetch price tiers", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tiers)
+}
+
+// AssignSection handles PUT /admin/events/{id}/sections/{section}/tier
+func (h *Handler) AssignSection(w http.ResponseWriter, r *http.Request) {
+	eventID, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var req SectionTierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := h.repo.AssignSection(r.Context(), eventID, chi.URLParam(r, "section"), req.TierID)
+	if err != nil {
+		if errors.Is(err, ErrTierNotFound) {
+			http.Error(w, "Price tier not found for this event", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to assign price tier", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AssignSeats handles PUT /admin/tiers/{id}/seats
+func (h *Handler) AssignSeats(w http.ResponseWriter, r *http.Request) {
+	tierID, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var req SeatTierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.SeatIDs) == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.repo.AssignSeats(r.Context(), tierID, req.SeatIDs)
+	if err != nil {
+		http.Error(w, "Failed to assign price tier", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"updated": updated})
+}
+
+// GetSeatPrice handles GET /seats/{id}/price so checkout can show the total before booking
+func (h *Handler) GetSeatPrice(w http.ResponseWriter, r *http.Request) {
+	seatID, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	quote, err := h.repo.QuoteSeat(r.Context(), h.repo.db.Pool, seatID)
+	if err != nil {
+		if errors.Is(err, ErrSeatNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to price seat", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
+
+func validateTier(errs *validation.Errors, req TierCreationRequest) {
+	errs.Required("name", req.Name)
+	errs.MaxLength("name", req.Name, 100)
+	errs.Check(len(req.Currency) == 3 && strings.Trim(req.Currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == "",
+		"currency", "must be a 3-letter ISO 4217 code")
+	errs.Check(req.FaceValue >= 0, "face_value", "cannot be negative")
+	errs.Check(req.ServiceFeeFixed >= 0, "service_fee_fixed", "cannot be negative")
+	errs.Check(req.FacilityFee >= 0, "facility_fee", "cannot be negative")
+	errs.Check(req.ServiceFeeBps >= 0 && req.ServiceFeeBps <= 10000, "service_fee_bps", "must be between 0 and 10000 basis points")
+	errs.Check(req.TaxBps >= 0 && req.TaxBps <= 10000, "tax_bps", "must be between 0 and 10000 basis points")
+}
+
+func intParam(w http.ResponseWriter, r *http.Request, name string) (int32, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, name), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid "+name, http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
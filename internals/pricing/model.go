@@ -0,0 +1,59 @@
+package pricing
+
+import "time"
+
+// DefaultCurrency applies to seats that have no price tier yet
+const DefaultCurrency = "USD"
+
+// Tier is a price level for an event. Money is in minor units, rates in basis points (1% = 100).
+type Tier struct {
+	ID              int32     `json:"id"`
+	EventID         int32     `json:"event_id"`
+	Name            string    `json:"name"`
+	Currency        string    `json:"currency"`
+	FaceValue       int64     `json:"face_value"`
+	ServiceFeeBps   int32     `json:"service_fee_bps"`
+	ServiceFeeFixed int64     `json:"service_fee_fixed"`
+	FacilityFee     int64     `json:"facility_fee"`
+	TaxBps          int32     `json:"tax_bps"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Breakdown is the itemised price of one ticket, in minor units of Currency
+type Breakdown struct {
+	Currency    string `json:"currency"`
+	FaceValue   int64  `json:"face_value"`
+	Discount    int64  `json:"discount"`
+	ServiceFee  int64  `json:"service_fee"`
+	FacilityFee int64  `json:"facility_fee"`
+	Tax         int64  `json:"tax"`
+	Total       int64  `json:"total"`
+}
+
+// NetFaceValue is the face value after any promo discount; it is what the organizer receives
+func (b Breakdown) NetFaceValue() int64 {
+	return b.FaceValue - b.Discount
+}
+
+// Fees is what the platform keeps on top of the face value
+func (b Breakdown) Fees() int64 {
+	return b.ServiceFee + b.FacilityFee
+}
+
+type TierCreationRequest struct {
+	Name            string `json:"name"`
+	Currency        string `json:"currency"`
+	FaceValue       int64  `json:"face_value"`
+	ServiceFeeBps   int32  `json:"service_fee_bps"`
+	ServiceFeeFixed int64  `json:"service_fee_fixed"`
+	FacilityFee     int64  `json:"facility_fee"`
+	TaxBps          int32  `json:"tax_bps"`
+}
+
+type SectionTierRequest struct {
+	TierID int32 `json:"tier_id"`
+}
+
+type SeatTierRequest struct {
+	SeatIDs []int32 `json:"seat_ids"`
+}
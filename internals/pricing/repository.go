@@ -0,0 +1,156 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	database "ticketmaster/internals/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrSeatNotFound = errors.New("seat not found")
+	ErrTierNotFound = errors.New("price tier not found")
+)
+
+// Querier is satisfied by both the pool and a transaction,
+// so quotes can be computed inside the booking transaction.
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const tierColumns = `id, event_id, name, currency, face_value, service_fee_bps, service_fee_fixed, facility_fee, tax_bps, created_at`
+
+func (r *Repository) CreateTier(ctx context.Context, eventID int32, req TierCreationRequest) (*Tier, error) {
+	query := `INSERT INTO price_tiers (event_id, name, currency, face_value, service_fee_bps, service_fee_fixed, facility_fee, tax_bps)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ` + tierColumns
+	rows, err := r.db.Pool.Query(ctx, query, eventID, req.Name, req.Currency, req.FaceValue,
+		req.ServiceFeeBps, req.ServiceFeeFixed, req.FacilityFee, req.TaxBps)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[Tier])
+}
+
+func (r *Repository) GetTiers(ctx context.Context, eventID int32) ([]Tier, error) {
+	query := `SELECT ` + tierColumns + ` FROM price_tiers WHERE event_id = $1 ORDER BY face_value DESC`
+	rows, err := r.db.Pool.Query(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Tier])
+}
+
+// AssignSection prices every seat of a section that has no seat-level tier
+func (r *Repository) AssignSection(ctx context.Context, eventID int32, section string, tierID int32) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO section_price_tiers (event_id, section, tier_id)
+		SELECT $1, $2, id FROM price_tiers WHERE id = $3 AND event_id = $1
+		ON CONFLICT (event_id, section) DO UPDATE SET tier_id = EXCLUDED.tier_id`
+	tag, err := tx.Exec(ctx, query, eventID, section, tierID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTierNotFound
+	}
+
+	// The seats' prices changed: touch them so GET /seats (filtered by price) gets a new ETag
+	if _, err := tx.Exec(ctx, `UPDATE seats SET tier_id = tier_id WHERE event_id = $1 AND section = $2`, eventID, section); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// AssignSeats overrides the tier of individual seats. Seats of other events are ignored.
+func (r *Repository) AssignSeats(ctx context.Context, tierID int32, seatIDs []int32) (int64, error) {
+	query := `
+		UPDATE seats SET tier_id = t.id
+		FROM price_tiers t
+		WHERE t.id = $1 AND seats.event_id = t.event_id AND seats.id = ANY($2)`
+	tag, err := r.db.Pool.Exec(ctx, query, tierID, seatIDs)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// TierOrganization returns the organization running the tier's event, nil if it has none or doesn't exist
+func (r *Repository) TierOrganization(ctx context.Context, tierID int32) (*int32, error) {
+	var orgID *int32
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT e.organization_id FROM price_tiers t JOIN events e ON e.id = t.event_id
+		WHERE t.id = $1`, tierID).Scan(&orgID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tier: %w", err)
+	}
+	return orgID, nil
+}
+
+// TierForSeat resolves the seat's tier (seat override, then section).
+// Seats without any tier get a synthetic one (ID 0) built from the legacy seats.price column.
+func (r *Repository) TierForSeat(ctx context.Context, q Querier, seatID int32) (Tier, error) {
+	// 1. Which tier applies?
+	var price int32
+	var tierID *int32
+	err := q.QueryRow(ctx, `
+		SELECT s.price, COALESCE(s.tier_id, st.tier_id)
+		FROM seats s
+		LEFT JOIN section_price_tiers st ON st.event_id = s.event_id AND st.section = s.section
+		WHERE s.id = $1`, seatID).Scan(&price, &tierID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return Tier{}, ErrSeatNotFound
+		}
+		return Tier{}, fmt.Errorf("failed to resolve price tier: %w", err)
+	}
+	if tierID == nil {
+		return legacyTier(price), nil
+	}
+
+	// 2. Load it
+	return r.TierByID(ctx, q, *tierID)
+}
+
+// TierByID loads a tier, e.g. the one a general-admission ticket type is sold at
+func (r *Repository) TierByID(ctx context.Context, q Querier, tierID int32) (Tier, error) {
+	var t Tier
+	err := q.QueryRow(ctx, `SELECT `+tierColumns+` FROM price_tiers WHERE id = $1`, tierID).Scan(
+		&t.ID, &t.EventID, &t.Name, &t.Currency, &t.FaceValue,
+		&t.ServiceFeeBps, &t.ServiceFeeFixed, &t.FacilityFee, &t.TaxBps, &t.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return Tier{}, ErrTierNotFound
+	}
+	if err != nil {
+		return Tier{}, fmt.Errorf("failed to load price tier: %w", err)
+	}
+	return t, nil
+}
+
+// QuoteSeat prices a seat at its full (undiscounted) price
+func (r *Repository) QuoteSeat(ctx context.Context, q Querier, seatID int32) (Breakdown, error) {
+	t, err := r.TierForSeat(ctx, q, seatID)
+	if err != nil {
+		return Breakdown{}, err
+	}
+	return Quote(t, Discount{}), nil
+}
@@ -0,0 +1,114 @@
+package promotions
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/validation"
+)
+
+type Handler struct {
+	repo       *Repository
+	eventOwner middleware.OwnerLookup
+}
+
+// NewHandler creates the promo code handler. eventOwner tells whose event a code is for.
+func NewHandler(repo *Repository, eventOwner middleware.OwnerLookup) *Handler {
+	return &Handler{repo: repo, eventOwner: eventOwner}
+}
+
+// CreateCode handles POST /admin/promo-codes
+func (h *Handler) CreateCode(w http.ResponseWriter, r *http.Request) {
+	var req PromoCodeCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Code = NormalizeCode(req.Code)
+	var errs validation.Errors
+	validateCode(&errs, req)
+	if errs.Respond(w) {
+		return
+	}
+
+	// A code for every event is the operator's to hand out
+	var owner *int32
+	if req.EventID != nil {
+		var err error
+		if owner, err = h.eventOwner(r.Context(), *req.EventID); err != nil {
+			http.Error(w, "Failed to create promo code", http.StatusInternalServerError)
+			return
+		}
+	}
+	if !middleware.ActsFor(r.Context(), owner) {
+		http.Error(w, "Event belongs to another organization", http.StatusForbidden)
+		return
+	}
+
+	promo, err := h.repo.CreateCode(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrCodeTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, ErrEventNotFound), errors.Is(err, ErrTierNotFound):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, "Failed to create promo code", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(promo)
+}
+
+// GetCodes handles GET /admin/promo-codes: the operator sees every code, an organization
+// the codes of its own events
+func (h *Handler) GetCodes(w http.ResponseWriter, r *http.Request) {
+	var orgID *int32
+	if !middleware.IsOperator(r.Context()) {
+		id, ok := r.Context().Value(middleware.OrganizationIDKey).(int32)
+		if !ok {
+			http.Error(w, "Requires an API key", http.StatusForbidden)
+			return
+		}
+		orgID = &id
+	}
+	codes, err := h.repo.GetAll(r.Context(), orgID)
+	if err != nil {
+		http.Error(w, "Failed to fetch promo codes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(codes)
+}
+
+func validateCode(errs *validation.Errors, req PromoCodeCreationRequest) {
+	errs.Required("code", req.Code)
+	errs.MaxLength("code", req.Code, 64)
+	errs.Check(req.Kind == KindDiscount || req.Kind == KindAccess, "kind", "must be 'discount' or 'access'")
+	errs.Check(req.MaxUses == nil || *req.MaxUses >= 1, "max_uses", "must be at least 1")
+	errs.Check(req.PerUserLimit == nil || *req.PerUserLimit >= 1, "per_user_limit", "must be at least 1")
+	errs.Check(req.ValidFrom == nil || req.ValidUntil == nil || req.ValidUntil.After(*req.ValidFrom),
+		"valid_until", "must be after valid_from")
+
+	switch req.Kind {
+	case KindAccess:
+		errs.Check(req.DiscountType == nil, "discount_type", "access codes cannot carry a discount")
+	case KindDiscount:
+		switch {
+		case req.DiscountType == nil:
+			errs.Add("discount_type", "is required for discount codes")
+		case *req.DiscountType == DiscountPercent:
+			errs.Check(req.DiscountValue >= 1 && req.DiscountValue <= 10000, "discount_value",
+				"percent discounts are basis points between 1 and 10000")
+		case *req.DiscountType == DiscountFixed:
+			errs.Check(req.DiscountValue >= 1, "discount_value", "fixed discounts must be a positive amount")
+		default:
+			errs.Add("discount_type", "must be 'percent' or 'fixed'")
+		}
+	}
+}
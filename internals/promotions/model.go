@@ -0,0 +1,80 @@
+package promotions
+
+import (
+	"ticketmaster/internals/pricing"
+	"time"
+)
+
+// Code kinds
+const (
+	KindDiscount = "discount" // Lowers the price
+	KindAccess   = "access"   // Unlocks presale before the public on-sale
+)
+
+// Discount types
+const (
+	DiscountPercent = "percent"
+	DiscountFixed   = "fixed"
+)
+
+type PromoCode struct {
+	ID            int32      `json:"id"`
+	Code          string     `json:"code"`
+	Kind          string     `json:"kind"`
+	DiscountType  *string    `json:"discount_type"`
+	DiscountValue int64      `json:"discount_value"` // Basis points for percent, minor units for fixed
+	MaxUses       *int32     `json:"max_uses"`
+	PerUserLimit  *int32     `json:"per_user_limit"`
+	ValidFrom     *time.Time `json:"valid_from"`
+	ValidUntil    *time.Time `json:"valid_until"`
+	EventID       *int32     `json:"event_id"`
+	TierID        *int32     `json:"tier_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ActiveAt reports whether now falls inside the code's validity window
+func (p *PromoCode) ActiveAt(now time.Time) bool {
+	if p.ValidFrom != nil && now.Before(*p.ValidFrom) {
+		return false
+	}
+	if p.ValidUntil != nil && !now.Before(*p.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+// AppliesTo reports whether the code may be used for a seat of this event and tier.
+// tierID 0 means the seat has no price tier.
+func (p *PromoCode) AppliesTo(eventID *int32, tierID int32) bool {
+	if p.EventID != nil && (eventID == nil || *eventID != *p.EventID) {
+		return false
+	}
+	if p.TierID != nil && *p.TierID != tierID {
+		return false
+	}
+	return true
+}
+
+// Discount converts the code into the pricing engine's terms
+func (p *PromoCode) Discount() pricing.Discount {
+	if p.Kind != KindDiscount || p.DiscountType == nil {
+		return pricing.Discount{}
+	}
+	if *p.DiscountType == DiscountPercent {
+		return pricing.Discount{Percent: int32(p.DiscountValue)}
+	}
+	return pricing.Discount{Fixed: p.DiscountValue}
+}
+
+type PromoCodeCreationRequest struct {
+	Code          string     `json:"code"`
+	Kind          string     `json:"kind"`
+	DiscountType  *string    `json:"discount_type"`
+	DiscountValue int64      `json:"discount_value"`
+	MaxUses       *int32     `json:"max_uses"`
+	PerUserLimit  *int32     `json:"per_user_limit"`
+	ValidFrom     *time.Time `json:"valid_from"`
+	ValidUntil    *time.Time `json:"valid_until"`
+	EventID       *int32     `json:"event_id"`
+	TierID        *int32     `json:"tier_id"`
+}
@@ -0,0 +1,80 @@
+package promotions
+
+import (
+	"testing"
+	"ticketmaster/internals/pricing"
+	"time"
+)
+
+func TestActiveAt(t *testing.T) {
+	from := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	until := from.Add(48 * time.Hour)
+	p := PromoCode{ValidFrom: &from, ValidUntil: &until}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"before the window", from.Add(-time.Second), false},
+		{"opening instant", from, true},
+		{"inside", from.Add(24 * time.Hour), true},
+		{"closing instant", until, false},
+		{"after", until.Add(time.Hour), false},
+	}
+	for _, tt := range tests {
+		if got := p.ActiveAt(tt.now); got != tt.want {
+			t.Errorf("%s: ActiveAt = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if !(&PromoCode{}).ActiveAt(time.Now()) {
+		t.Error("a code without a window should always be active")
+	}
+}
+
+func TestAppliesTo(t *testing.T) {
+	event, other, tier := int32(1), int32(2), int32(5)
+	tests := []struct {
+		name    string
+		promo   PromoCode
+		eventID *int32
+		tierID  int32
+		want    bool
+	}{
+		{"any event", PromoCode{}, &other, 0, true},
+		{"seat without an event", PromoCode{}, nil, 0, true},
+		{"its event", PromoCode{EventID: &event}, &event, 0, true},
+		{"another event", PromoCode{EventID: &event}, &other, 0, false},
+		{"event code on a seat without an event", PromoCode{EventID: &event}, nil, 0, false},
+		{"its tier", PromoCode{EventID: &event, TierID: &tier}, &event, 5, true},
+		{"another tier", PromoCode{EventID: &event, TierID: &tier}, &event, 6, false},
+		{"tier code on an untiered seat", PromoCode{TierID: &tier}, &event, 0, false},
+	}
+	for _, tt := range tests {
+		if got := tt.promo.AppliesTo(tt.eventID, tt.tierID); got != tt.want {
+			t.Errorf("%s: AppliesTo = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiscount(t *testing.T) {
+	percent, fixed := DiscountPercent, DiscountFixed
+	tests := []struct {
+		name  string
+		promo PromoCode
+		want  pricing.Discount
+	}{
+		{"percent", PromoCode{Kind: KindDiscount, DiscountType: &percent, DiscountValue: 1500}, pricing.Discount{Percent: 1500}},
+		{"fixed", PromoCode{Kind: KindDiscount, DiscountType: &fixed, DiscountValue: 500}, pricing.Discount{Fixed: 500}},
+		{"access code", PromoCode{Kind: KindAccess, DiscountType: &percent, DiscountValue: 1500}, pricing.Discount{}},
+		{"no type", PromoCode{Kind: KindDiscount, DiscountValue: 1500}, pricing.Discount{}},
+	}
+	for _, tt := range tests {
+		if got := tt.promo.Discount(); got != tt.want {
+			t.Errorf("%s: Discount = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	if NormalizeCode("  early-Bird ") != "EARLY-BIRD" {
+		t.Errorf("NormalizeCode = %q", NormalizeCode("  early-Bird "))
+	}
+}
@@ -0,0 +1,134 @@
+package promotions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	database "ticketmaster/internals/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrCodeNotFound      = errors.New("promo code not found")
+	ErrCodeInactive      = errors.New("promo code is not valid at this time")
+	ErrCodeNotApplicable = errors.New("promo code does not apply to this seat")
+	ErrCodeExhausted     = errors.New("promo code has been fully redeemed")
+	ErrUserLimitReached  = errors.New("you have already used this promo code the maximum number of times")
+	ErrCodeTaken         = errors.New("a promo code with this code already exists")
+	ErrEventNotFound     = errors.New("event not found")
+	ErrTierNotFound      = errors.New("price tier not found")
+)
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const codeColumns = `id, code, kind, discount_type, discount_value, max_uses, per_user_limit, valid_from, valid_until, event_id, tier_id, created_at`
+
+func (r *Repository) CreateCode(ctx context.Context, req PromoCodeCreationRequest) (*PromoCode, error) {
+	query := `INSERT INTO promo_codes (code, kind, discount_type, discount_value, max_uses, per_user_limit, valid_from, valid_until, event_id, tier_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING ` + codeColumns
+	rows, err := r.db.Pool.Query(ctx, query, req.Code, req.Kind, req.DiscountType, req.DiscountValue,
+		req.MaxUses, req.PerUserLimit, req.ValidFrom, req.ValidUntil, req.EventID, req.TierID)
+	if err != nil {
+		return nil, constraintError(err)
+	}
+	promo, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[PromoCode])
+	if err != nil {
+		return nil, constraintError(err)
+	}
+	return promo, nil
+}
+
+// constraintError names the duplicate code or the missing event or tier of a failed insert
+func constraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch {
+	case pgErr.Code == "23505":
+		return ErrCodeTaken
+	case pgErr.Code == "23503" && pgErr.ConstraintName == "promo_codes_tier_id_fkey":
+		return ErrTierNotFound
+	case pgErr.Code == "23503":
+		return ErrEventNotFound
+	}
+	return err
+}
+
+// GetAll lists the codes for the organization's events, every code if orgID is nil
+func (r *Repository) GetAll(ctx context.Context, orgID *int32) ([]PromoCode, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT `+codeColumns+` FROM promo_codes
+		WHERE $1::int IS NULL OR event_id IN (SELECT id FROM events WHERE organization_id = $1)
+		ORDER BY id`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[PromoCode])
+}
+
+func (r *Repository) GetByCode(ctx context.Context, code string) (*PromoCode, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT `+codeColumns+` FROM promo_codes WHERE code = $1`, code)
+	if err != nil {
+		return nil, err
+	}
+	promo, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[PromoCode])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCodeNotFound
+		}
+		return nil, err
+	}
+	return promo, nil
+}
+
+// CountUses returns how many times the code was redeemed in total and by this user
+func (r *Repository) CountUses(ctx context.Context, codeID, userID int32) (int64, int64, error) {
+	var total, byUser int64
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE user_id = $2)
+		FROM promo_redemptions WHERE promo_code_id = $1`, codeID, userID).Scan(&total, &byUser)
+	return total, byUser, err
+}
+
+// Redeem records the redemption inside the booking transaction.
+// It re-checks the limits under a row lock, so Postgres stays correct even if Redis lost its counters.
+func (r *Repository) Redeem(ctx context.Context, tx pgx.Tx, promo *PromoCode, userID, bookingID int32) error {
+	// 1. Serialize redemptions of this code
+	var locked int32
+	if err := tx.QueryRow(ctx, `SELECT id FROM promo_codes WHERE id = $1 FOR UPDATE`, promo.ID).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to lock promo code: %w", err)
+	}
+
+	// 2. Double-check the limits
+	var total, byUser int64
+	err := tx.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE user_id = $2)
+		FROM promo_redemptions WHERE promo_code_id = $1`, promo.ID, userID).Scan(&total, &byUser)
+	if err != nil {
+		return fmt.Errorf("failed to count redemptions: %w", err)
+	}
+	if promo.MaxUses != nil && total >= int64(*promo.MaxUses) {
+		return ErrCodeExhausted
+	}
+	if promo.PerUserLimit != nil && byUser >= int64(*promo.PerUserLimit) {
+		return ErrUserLimitReached
+	}
+
+	// 3. Record it
+	_, err = tx.Exec(ctx,
+		`INSERT INTO promo_redemptions (promo_code_id, user_id, booking_id) VALUES ($1, $2, $3)`,
+		promo.ID, userID, bookingID)
+	if err != nil {
+		return fmt.Errorf("failed to record redemption: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package promotions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"ticketmaster/internals/db/dbtest"
+)
+
+func TestCreateCodeNamesConstraintViolations(t *testing.T) {
+	db := dbtest.New(t)
+	repo := NewRepository(db)
+	ctx := context.Background()
+	eventID := dbtest.ID(t, db, `INSERT INTO events (name, starts_at) VALUES ('Gig', NOW() + INTERVAL '1 day') RETURNING id`)
+	missing := eventID + 1000
+
+	if _, err := repo.CreateCode(ctx, PromoCodeCreationRequest{Code: "EARLY", Kind: KindAccess, EventID: &eventID}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		req  PromoCodeCreationRequest
+		want error
+	}{
+		{"duplicate code", PromoCodeCreationRequest{Code: "EARLY", Kind: KindAccess}, ErrCodeTaken},
+		{"unknown event", PromoCodeCreationRequest{Code: "NOEVENT", Kind: KindAccess, EventID: &missing}, ErrEventNotFound},
+		{"unknown tier", PromoCodeCreationRequest{Code: "NOTIER", Kind: KindAccess, EventID: &eventID, TierID: &missing}, ErrTierNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repo.CreateCode(ctx, tt.req); !errors.Is(err, tt.want) {
+				t.Errorf("CreateCode = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
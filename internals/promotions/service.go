@@ -0,0 +1,104 @@
+package promotions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"ticketmaster/internals/cache"
+	"time"
+)
+
+type Service struct {
+	repo       *Repository
+	redisStore *cache.RedisStore
+}
+
+func NewService(repo *Repository, redisStore *cache.RedisStore) *Service {
+	return &Service{repo: repo, redisStore: redisStore}
+}
+
+// NormalizeCode makes codes case- and whitespace-insensitive
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func usesKey(codeID int32) string {
+	return fmt.Sprintf("promo_uses:%d", codeID)
+}
+
+func userUsesKey(codeID, userID int32) string {
+	return fmt.Sprintf("promo_user_uses:%d:%d", codeID, userID)
+}
+
+// Reserve validates the code and takes one use from its Redis counters.
+// The caller must Release it if the booking does not go through.
+func (s *Service) Reserve(ctx context.Context, code string, userID int32) (*PromoCode, error) {
+	// 1. Load and check the window
+	promo, err := s.repo.GetByCode(ctx, NormalizeCode(code))
+	if err != nil {
+		return nil, err
+	}
+	if !promo.ActiveAt(time.Now()) {
+		return nil, ErrCodeInactive
+	}
+
+	// 2. Make sure the counters reflect Postgres (first use, or after they expired)
+	if err := s.seedCounters(ctx, promo, userID); err != nil {
+		return nil, err
+	}
+
+	// 3. The atomic check-and-increment
+	globalLimit, userLimit := int64(-1), int64(-1)
+	if promo.MaxUses != nil {
+		globalLimit = int64(*promo.MaxUses)
+	}
+	if promo.PerUserLimit != nil {
+		userLimit = int64(*promo.PerUserLimit)
+	}
+	err = s.redisStore.ReserveUse(ctx, usesKey(promo.ID), userUsesKey(promo.ID, userID), globalLimit, userLimit)
+	switch {
+	case errors.Is(err, cache.ErrQuotaExhausted):
+		return nil, ErrCodeExhausted
+	case errors.Is(err, cache.ErrUserQuotaExhausted):
+		return nil, ErrUserLimitReached
+	case err != nil:
+		return nil, err
+	}
+	return promo, nil
+}
+
+// Release returns a use taken by Reserve
+func (s *Service) Release(ctx context.Context, promo *PromoCode, userID int32) error {
+	return s.redisStore.ReleaseUse(ctx, usesKey(promo.ID), userUsesKey(promo.ID, userID))
+}
+
+func (s *Service) seedCounters(ctx context.Context, promo *PromoCode, userID int32) error {
+	globalExists, err := s.redisStore.CounterExists(ctx, usesKey(promo.ID))
+	if err != nil {
+		return err
+	}
+	userExists, err := s.redisStore.CounterExists(ctx, userUsesKey(promo.ID, userID))
+	if err != nil {
+		return err
+	}
+	if globalExists && userExists {
+		return nil
+	}
+
+	total, byUser, err := s.repo.CountUses(ctx, promo.ID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to count redemptions: %w", err)
+	}
+	if !globalExists {
+		if _, err := s.redisStore.SeedCounter(ctx, usesKey(promo.ID), total); err != nil {
+			return err
+		}
+	}
+	if !userExists {
+		if _, err := s.redisStore.SeedCounter(ctx, userUsesKey(promo.ID, userID), byUser); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package refunds
+
+import "time"
+
+// Refund statuses
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusSucceeded  = "succeeded"
+	StatusFailed     = "failed"
+)
+
+type Refund struct {
+	ID          int64     `json:"id"`
+	BookingID   int32     `json:"booking_id"`
+	EventID     int32     `json:"event_id"`
+	Amount      int64     `json:"amount"`
+	Currency    string    `json:"currency"`
+	Status      string    `json:"status"`
+	Attempts    int32     `json:"attempts"`
+	ProviderRef *string   `json:"provider_ref"`
+	LastError   *string   `json:"last_error"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Summary is the per-event progress of a mass refund
+type Summary struct {
+	EventID           int32 `json:"event_id"`
+	Pending           int64 `json:"pending"`
+	Processing        int64 `json:"processing"`
+	Succeeded         int64 `json:"succeeded"`
+	Failed            int64 `json:"failed"`
+	AmountRefunded    int64 `json:"amount_refunded"`
+	AmountOutstanding int64 `json:"amount_outstanding"`
+}
+
+// Done reports whether every refund for the event has reached a final state
+func (s Summary) Done() bool {
+	return s.Pending == 0 && s.Processing == 0
+}
@@ -0,0 +1,146 @@
+package refunds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"ticketmaster/internals/notifications"
+	"ticketmaster/internals/payments"
+	"time"
+)
+
+const (
+	// Refunds sent to the payment provider per batch.
+	batchSize = 50
+
+	// Minimum pause between two batches. Together with batchSize this is the rate limit.
+	batchInterval = 2 * time.Second
+
+	// A refund left in 'processing' this long belongs to a crashed worker and is retried.
+	staleAfter = 5 * time.Minute
+
+	// Give up (status 'failed') after this many provider errors.
+	maxAttempts = 5
+)
+
+// Processor drains the refund queue in the background.
+// All state lives in Postgres, so a restarted server simply picks up where the last one stopped.
+type Processor struct {
+	repo    *Repository
+	gateway payments.Gateway
+	hub     *notifications.Hub
+
+	// Wake the worker early instead of waiting for the next tick.
+	wake chan struct{}
+}
+
+func NewProcessor(repo *Repository, gateway payments.Gateway, hub *notifications.Hub) *Processor {
+	return &Processor{
+		repo:    repo,
+		gateway: gateway,
+		hub:     hub,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Wake tells the worker new refunds are waiting. It never blocks.
+func (p *Processor) Wake() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes batches until ctx is cancelled
+func (p *Processor) Run(ctx context.Context) {
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	for {
+		processed, err := p.processBatch(ctx)
+		if err != nil {
+			log.Printf("refund worker: %v", err)
+		}
+
+		if processed > 0 {
+			// Busy: respect the rate limit before the next batch
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		// Idle: wait for a tick or for someone to enqueue refunds
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.wake:
+		}
+	}
+}
+
+func (p *Processor) processBatch(ctx context.Context) (int, error) {
+	batch, err := p.repo.ClaimBatch(ctx, batchSize, staleAfter)
+	if err != nil {
+		return 0, err
+	}
+
+	touched := make(map[int32]bool)
+	for _, refund := range batch {
+		touched[refund.EventID] = true
+		if err := p.refundOne(ctx, refund); err != nil {
+			log.Printf("refund %d (booking %d) failed: %v", refund.ID, refund.BookingID, err)
+			if err := p.repo.Fail(ctx, refund, err, maxAttempts); err != nil {
+				log.Printf("refund worker: %v", err)
+			}
+		}
+	}
+
+	for eventID := range touched {
+		p.notifyProgress(ctx, eventID)
+	}
+	return len(batch), nil
+}
+
+func (p *Processor) refundOne(ctx context.Context, refund Refund) error {
+	var providerRef string
+	if refund.Amount > 0 {
+		ref, err := p.gateway.Refund(ctx, payments.RefundRequest{
+			IdempotencyKey: fmt.Sprintf("refund-%d", refund.ID),
+			BookingID:      refund.BookingID,
+			Amount:         refund.Amount,
+			Currency:       refund.Currency,
+		})
+		if err != nil {
+			return err
+		}
+		providerRef = ref
+	}
+	return p.repo.Complete(ctx, refund, providerRef)
+}
+
+func (p *Processor) notifyProgress(ctx context.Context, eventID int32) {
+	summary, err := p.repo.Summary(ctx, eventID)
+	if err != nil {
+		log.Printf("refund worker: %v", err)
+		return
+	}
+
+	msgType := "refund_progress"
+	if summary.Done() {
+		msgType = "refunds_completed"
+	}
+	msg := map[string]interface{}{
+		"type":      msgType,
+		"event_id":  eventID,
+		"succeeded": summary.Succeeded,
+		"failed":    summary.Failed,
+		"pending":   summary.Pending + summary.Processing,
+	}
+	jsonMsg, _ := json.Marshal(msg)
+	go func() { p.hub.Broadcast <- jsonMsg }()
+}
@@ -0,0 +1,145 @@
+package refunds
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"ticketmaster/internals/db/dbtest"
+	"ticketmaster/internals/ledger"
+	"ticketmaster/internals/notifications"
+	"ticketmaster/internals/payments"
+)
+
+// recordingGateway remembers every refund it was asked for and can fail some bookings
+type recordingGateway struct {
+	mu       sync.Mutex
+	requests []payments.RefundRequest
+	failFor  map[int32]bool
+}
+
+func (g *recordingGateway) Refund(ctx context.Context, req payments.RefundRequest) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.requests = append(g.requests, req)
+	if g.failFor[req.BookingID] {
+		return "", errors.New("provider unavailable")
+	}
+	return "ref_" + req.IdempotencyKey, nil
+}
+
+func TestProcessBatchResumesStaleRefunds(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	ledgerRepo := ledger.NewRepository(db)
+
+	eventID := dbtest.ID(t, db, `INSERT INTO events (name, starts_at, status) VALUES ('Gig', NOW(), 'cancelled') RETURNING id`)
+	userID := dbtest.ID(t, db, `INSERT INTO users (email, password_hash) VALUES ('fan@example.com', 'x') RETURNING id`)
+
+	// One cancelled, paid booking per refund state
+	booking := func(row string) int32 {
+		seatID := dbtest.ID(t, db, `INSERT INTO seats (row_number, seat_number, event_id) VALUES ($1, 1, $2) RETURNING id`, row, eventID)
+		id := dbtest.ID(t, db,
+			`INSERT INTO bookings (seat_id, user_id, status, amount, currency) VALUES ($1, $2, 'cancelled', 5000, 'USD') RETURNING id`,
+			seatID, userID)
+		tx, err := db.Pool.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback(ctx)
+		if _, err := ledgerRepo.Post(ctx, tx, ledger.BookingEntry(int64(id), 5000, 0, "USD")); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	stale, fresh, pending, failing := booking("A"), booking("B"), booking("C"), booking("D")
+
+	repo := NewRepository(db, ledgerRepo)
+	if n, err := func() (int64, error) {
+		tx, err := db.Pool.Begin(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer tx.Rollback(ctx)
+		n, err := repo.EnqueueForEvent(ctx, tx, eventID)
+		if err != nil {
+			return 0, err
+		}
+		return n, tx.Commit(ctx)
+	}(); err != nil || n != 4 {
+		t.Fatalf("EnqueueForEvent = %d, %v; want 4", n, err)
+	}
+
+	// A worker crashed mid-refund ten minutes ago; another one is busy with a refund right now
+	dbtest.Exec(t, db, `UPDATE refunds SET status = 'processing', attempts = 1, updated_at = NOW() - INTERVAL '10 minutes' WHERE booking_id = $1`, stale)
+	dbtest.Exec(t, db, `UPDATE refunds SET status = 'processing', attempts = 1, updated_at = NOW() WHERE booking_id = $1`, fresh)
+
+	hub := notifications.NewHub()
+	go hub.Run()
+	gateway := &recordingGateway{failFor: map[int32]bool{failing: true}}
+	p := NewProcessor(repo, gateway, hub)
+
+	processed, err := p.processBatch(ctx)
+	if err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	if processed != 3 {
+		t.Errorf("processed = %d, want 3 (stale, pending and failing)", processed)
+	}
+
+	for _, req := range gateway.requests {
+		if req.BookingID == fresh {
+			t.Errorf("refund held by a live worker was sent to the provider")
+		}
+	}
+
+	type state struct {
+		status   string
+		attempts int32
+		ref      *string
+	}
+	load := func(bookingID int32) state {
+		var s state
+		err := db.Pool.QueryRow(ctx, `SELECT status, attempts, provider_ref FROM refunds WHERE booking_id = $1`, bookingID).
+			Scan(&s.status, &s.attempts, &s.ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	if s := load(stale); s.status != StatusSucceeded || s.attempts != 2 || s.ref == nil {
+		t.Errorf("stale refund = %+v, want succeeded on attempt 2 with a provider ref", s)
+	}
+	if s := load(fresh); s.status != StatusProcessing || s.attempts != 1 {
+		t.Errorf("fresh refund = %+v, want untouched", s)
+	}
+	if s := load(pending); s.status != StatusSucceeded || s.attempts != 1 {
+		t.Errorf("pending refund = %+v, want succeeded on attempt 1", s)
+	}
+	if s := load(failing); s.status != StatusPending || s.attempts != 1 {
+		t.Errorf("failing refund = %+v, want back in the queue", s)
+	}
+
+	// The resumed refund reuses its idempotency key, so the provider can't pay it twice
+	var refundID int64
+	if err := db.Pool.QueryRow(ctx, `SELECT id FROM refunds WHERE booking_id = $1`, stale).Scan(&refundID); err != nil {
+		t.Fatal(err)
+	}
+	if ref := load(stale).ref; ref != nil && *ref != "ref_refund-"+strconv.FormatInt(refundID, 10) {
+		t.Errorf("provider ref = %q, want the stable idempotency key", *ref)
+	}
+
+	// Exactly one reversal per completed refund
+	var reversals int
+	if err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM ledger_entries WHERE kind = 'refund'`).Scan(&reversals); err != nil {
+		t.Fatal(err)
+	}
+	if reversals != 2 {
+		t.Errorf("refund ledger entries = %d, want 2", reversals)
+	}
+}
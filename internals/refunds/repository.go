@@ -0,0 +1,133 @@
+package refunds
+
+import (
+	"context"
+	"fmt"
+	database "ticketmaster/internals/db"
+	"ticketmaster/internals/ledger"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Repository struct {
+	db     *database.DB
+	ledger *ledger.Repository
+}
+
+func NewRepository(db *database.DB, ledgerRepo *ledger.Repository) *Repository {
+	return &Repository{db: db, ledger: ledgerRepo}
+}
+
+// EnqueueForEvent creates a pending refund for every cancelled booking of the event.
+// It runs inside the cancellation transaction and is safe to call again (one refund per booking).
+func (r *Repository) EnqueueForEvent(ctx context.Context, tx pgx.Tx, eventID int32) (int64, error) {
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO refunds (booking_id, event_id, amount, currency)
+		SELECT b.id, b.event_id, b.amount, b.currency
+		FROM bookings b
+		WHERE b.event_id = $1 AND b.status = 'cancelled'
+		ON CONFLICT (booking_id) DO NOTHING`, eventID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue refunds: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimBatch marks up to limit refunds as processing and returns them.
+// Refunds stuck in processing for longer than staleAfter (a worker crashed) are claimed again.
+// SKIP LOCKED lets several replicas drain the queue without stepping on each other.
+func (r *Repository) ClaimBatch(ctx context.Context, limit int, staleAfter time.Duration) ([]Refund, error) {
+	query := `
+		UPDATE refunds SET status = 'processing', attempts = attempts + 1, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM refunds
+			WHERE status = 'pending'
+			   OR (status = 'processing' AND updated_at < NOW() - make_interval(secs => $2))
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, booking_id, event_id, amount, currency, status, attempts, provider_ref, last_error, created_at, updated_at`
+
+	rows, err := r.db.Pool.Query(ctx, query, limit, staleAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim refunds: %w", err)
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Refund])
+}
+
+// Complete marks the refund as succeeded and reverses the booking in the ledger, atomically
+func (r *Repository) Complete(ctx context.Context, refund Refund, providerRef string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE refunds SET status = 'succeeded', provider_ref = $2, last_error = NULL, updated_at = NOW()
+		 WHERE id = $1 AND status = 'processing'`,
+		refund.ID, providerRef)
+	if err != nil {
+		return fmt.Errorf("failed to mark refund succeeded: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		// Another worker already finished it; don't post the reversal twice
+		return nil
+	}
+
+	if refund.Amount > 0 {
+		if _, err := r.ledger.ReverseBooking(ctx, tx, int64(refund.BookingID)); err != nil {
+			return fmt.Errorf("failed to record refund in ledger: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Fail records the error and puts the refund back in the queue, or gives up after maxAttempts
+func (r *Repository) Fail(ctx context.Context, refund Refund, cause error, maxAttempts int) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`UPDATE refunds
+		 SET status = CASE WHEN attempts >= $3 THEN 'failed' ELSE 'pending' END,
+		     last_error = $2, updated_at = NOW()
+		 WHERE id = $1 AND status = 'processing'`,
+		refund.ID, cause.Error(), maxAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to record refund failure: %w", err)
+	}
+	return nil
+}
+
+// RetryFailed puts every failed refund of the event back in the queue
+func (r *Repository) RetryFailed(ctx context.Context, eventID int32) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE refunds SET status = 'pending', attempts = 0, updated_at = NOW()
+		 WHERE event_id = $1 AND status = 'failed'`, eventID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retry refunds: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Summary counts refunds per status for one event
+func (r *Repository) Summary(ctx context.Context, eventID int32) (*Summary, error) {
+	s := &Summary{EventID: eventID}
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'pending'),
+			COUNT(*) FILTER (WHERE status = 'processing'),
+			COUNT(*) FILTER (WHERE status = 'succeeded'),
+			COUNT(*) FILTER (WHERE status = 'failed'),
+			COALESCE(SUM(amount) FILTER (WHERE status = 'succeeded'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE status <> 'succeeded'), 0)
+		FROM refunds WHERE event_id = $1`
+	err := r.db.Pool.QueryRow(ctx, query, eventID).Scan(
+		&s.Pending, &s.Processing, &s.Succeeded, &s.Failed, &s.AmountRefunded, &s.AmountOutstanding,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize refunds: %w", err)
+	}
+	return s, nil
+}
@@ -1,17 +1,33 @@
 package seats
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"ticketmaster/internals/cache"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/validation"
+
+	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
-	repo *Repository
+	repo       *Repository
+	redisStore *cache.RedisStore
+	eventOwner middleware.OwnerLookup
 }
 
-// NewHandler creates a new Seat Handler
-func NewHandler(repo *Repository) *Handler {
-	return &Handler{repo: repo}
+// NewHandler creates a new Seat Handler. eventOwner tells whose event a new seat joins.
+func NewHandler(repo *Repository, redisStore *cache.RedisStore, eventOwner middleware.OwnerLookup) *Handler {
+	return &Handler{repo: repo, redisStore: redisStore, eventOwner: eventOwner}
 }
 
 func (h *Handler) CreateSeat(w http.ResponseWriter, r *http.Request) {
@@ -21,30 +37,268 @@ func (h *Handler) CreateSeat(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	var errs validation.Errors
+	if seatRequest.RowID == nil {
+		errs.Required("row_number", seatRequest.RowNumber)
+	}
+	errs.MaxLength("row_number", seatRequest.RowNumber, 10)
+	errs.Check(seatRequest.SeatNumber >= 1, "seat_number", "must be at least 1")
+	errs.Check(seatRequest.Price >= 0, "price", "cannot be negative")
+	errs.MaxLength("section", seatRequest.Section, 100)
+	errs.Check((seatRequest.X == nil) == (seatRequest.Y == nil), "x", "x and y must be given together")
+	for _, attr := range seatRequest.Attributes {
+		if !validAttributes[attr] {
+			errs.Add("attributes", "unknown attribute "+attr)
+		}
+	}
+	if errs.Respond(w) {
+		return
+	}
+
+	// Seats outside any event are the operator's to create
+	var owner *int32
+	if seatRequest.EventID != nil {
+		var err error
+		if owner, err = h.eventOwner(ctx, *seatRequest.EventID); err != nil {
+			http.Error(w, "Failed to create seat", http.StatusInternalServerError)
+			return
+		}
+	}
+	if !middleware.ActsFor(ctx, owner) {
+		http.Error(w, "Event belongs to another organization", http.StatusForbidden)
+		return
+	}
 
-	err := h.repo.CreateSeat(ctx, seatRequest.RowNumber, seatRequest.SeatNumber, seatRequest.Price)
+	err := h.repo.CreateSeat(ctx, seatRequest)
 	if err != nil {
+		if errors.Is(err, ErrRowNotFound) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if errors.Is(err, ErrSeatExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, "Failed to create seat", http.StatusInternalServerError)
+		return
+	}
+	if seatRequest.EventID != nil {
+		h.dropAvailability(ctx, *seatRequest.EventID)
 	}
 	seatResponse := &SeatCreationResponse{Message: "Seat created Successfully"}
 	json.NewEncoder(w).Encode(seatResponse)
 }
 
-// GetSeats handles GET /seats
+// GetSeats handles GET /seats.
+//
+// Filters: event_id, status and section (comma-separated), min_price, max_price
+// (face value in minor units, as quoted by /seats/{id}/price).
+// Pages: limit and cursor; the next page's URL is in the Link header.
+// fields picks the JSON fields of each seat. Responses carry an ETag derived from the
+// inventory version, so a client sending it back in If-None-Match gets 304 until a seat changes.
 func (h *Handler) GetSeats(w http.ResponseWriter, r *http.Request) {
 	// 1. Context is crucial for timeouts/cancellation
 	ctx := r.Context()
 
-	// 2. Ask the Repository for data
-	seats, err := h.repo.GetAll(ctx)
+	// 2. Parse the query
+	query := r.URL.Query()
+	filter, fields, ok := parseListQuery(w, query)
+	if !ok {
+		return
+	}
+
+	// 3. Conditional GET: the version is one index probe, far cheaper than the page
+	version, err := h.repo.InventoryVersion(ctx, filter.EventID)
 	if err != nil {
 		http.Error(w, "Failed to fetch seats", http.StatusInternalServerError)
 		return
 	}
+	etag := listETag(version, query)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache") // Cache, but always revalidate
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 
-	// 3. Send the JSON response
+	// 4. Ask the Repository for data
+	seats, next, err := h.repo.List(ctx, filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch seats", http.StatusInternalServerError)
+		return
+	}
+	if next != nil {
+		query.Set("cursor", next.Encode())
+		w.Header().Set("Link", "<"+r.URL.Path+"?"+query.Encode()+">; rel=\"next\"")
+		w.Header().Set("X-Next-Cursor", next.Encode())
+	}
+
+	// 5. Send the JSON response
+	var body any = seats
+	if len(fields) > 0 {
+		body = selectFields(seats, fields)
+	}
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(seats); err != nil {
+	if err := json.NewEncoder(w).Encode(body); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
+
+func parseListQuery(w http.ResponseWriter, query url.Values) (ListFilter, []string, bool) {
+	filter := ListFilter{Limit: DefaultPageSize}
+	var errs validation.Errors
+
+	intParam := func(name string) *int32 {
+		v := query.Get(name)
+		if v == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			errs.Add(name, "must be a number")
+			return nil
+		}
+		i := int32(n)
+		return &i
+	}
+	priceParam := func(name string) *int64 {
+		v := query.Get(name)
+		if v == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			errs.Add(name, "must be a non-negative number")
+			return nil
+		}
+		return &n
+	}
+	filter.EventID = intParam("event_id")
+	filter.MinPrice = priceParam("min_price")
+	filter.MaxPrice = priceParam("max_price")
+	if limit := intParam("limit"); limit != nil {
+		filter.Limit = int(*limit)
+		errs.Check(filter.Limit >= 1 && filter.Limit <= MaxPageSize, "limit", "must be between 1 and "+strconv.Itoa(MaxPageSize))
+	}
+	errs.Check(filter.MinPrice == nil || filter.MaxPrice == nil || *filter.MinPrice <= *filter.MaxPrice,
+		"max_price", "must not be below min_price")
+
+	filter.Statuses = splitList(query.Get("status"))
+	filter.Sections = splitList(query.Get("section"))
+
+	if c := query.Get("cursor"); c != "" {
+		cursor, err := DecodeCursor(c)
+		errs.Check(err == nil, "cursor", "is not a cursor returned by this endpoint")
+		filter.After = cursor
+	}
+
+	fields := splitList(query.Get("fields"))
+	for _, f := range fields {
+		if _, ok := seatFields[f]; !ok {
+			errs.Add("fields", "unknown field "+f)
+		}
+	}
+
+	if errs.Respond(w) {
+		return ListFilter{}, nil, false
+	}
+	return filter, fields, true
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// listETag is weak: the same version and query give the same seats, not necessarily the same bytes
+func listETag(version int64, query url.Values) string {
+	h := fnv.New64a()
+	h.Write([]byte(query.Encode())) // Encode sorts by key, so parameter order doesn't matter
+	return fmt.Sprintf(`W/"%d-%x"`, version, h.Sum64())
+}
+
+// etagMatches implements the weak comparison If-None-Match asks for
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// maxManifestBytes bounds the upload; MaxManifestSeats fully described seats
+// are about 15 MB as compact JSON and 5 MB as CSV
+const maxManifestBytes = 16 << 20
+
+// ImportSeats handles POST /admin/events/{id}/seats/import.
+// The body is a CSV (Content-Type: text/csv) or JSON manifest; ?dry_run=true only validates.
+func (h *Handler) ImportSeats(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid event id", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = FormatJSON
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+			format = FormatCSV
+		}
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	// 1. Parse; a manifest that can't be read at all has no lines to point at
+	manifest, err := ParseManifest(http.MaxBytesReader(w, r.Body, maxManifestBytes), format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(manifest) == 0 {
+		http.Error(w, "Manifest has no seats", http.StatusBadRequest)
+		return
+	}
+
+	// 2. Validate and insert, all or nothing
+	result, err := h.repo.Import(r.Context(), int32(eventID), manifest, dryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrEventNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrEventCancelled):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to import seats", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if result.Inserted > 0 {
+		h.dropAvailability(r.Context(), result.EventID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case len(result.Errors) > 0:
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	case !dryRun:
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// dropAvailability makes the next availability read rebuild the event's bitmap with the new seats
+func (h *Handler) dropAvailability(ctx context.Context, eventID int32) {
+	if err := h.redisStore.DropAvailability(ctx, eventID); err != nil {
+		log.Printf("cannot drop availability bitmap of event %d: %v", eventID, err)
+	}
+}
@@ -0,0 +1,80 @@
+package seats
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// Page sizes of GET /seats
+const (
+	DefaultPageSize = 500
+	MaxPageSize     = 5000
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListFilter narrows GET /seats. Zero values mean "no filter"; prices are face values in
+// minor units, resolved through the seat's price tier like /seats/{id}/price.
+type ListFilter struct {
+	EventID  *int32
+	Statuses []string
+	Sections []string
+	MinPrice *int64
+	MaxPrice *int64
+	After    *Cursor
+	Limit    int
+}
+
+// Cursor is the keyset position of the last seat of a page, in listing order
+type Cursor struct {
+	RowNumber  string `json:"r"`
+	SeatNumber int32  `json:"n"`
+	ID         int32  `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// seatFields are the names ?fields= may pick, as they appear in the JSON
+var seatFields = map[string]func(Seat) any{
+	"id":          func(s Seat) any { return s.ID },
+	"row_number":  func(s Seat) any { return s.RowNumber },
+	"seat_number": func(s Seat) any { return s.SeatNumber },
+	"status":      func(s Seat) any { return s.Status },
+	"price":       func(s Seat) any { return s.Price },
+	"event_id":    func(s Seat) any { return s.EventID },
+	"section":     func(s Seat) any { return s.Section },
+	"tier_id":     func(s Seat) any { return s.TierID },
+	"row_id":      func(s Seat) any { return s.RowID },
+	"x":           func(s Seat) any { return s.X },
+	"y":           func(s Seat) any { return s.Y },
+	"attributes":  func(s Seat) any { return s.Attributes },
+}
+
+// selectFields keeps only the named fields of each seat
+func selectFields(seats []Seat, fields []string) []map[string]any {
+	out := make([]map[string]any, len(seats))
+	for i, s := range seats {
+		m := make(map[string]any, len(fields))
+		for _, f := range fields {
+			m[f] = seatFields[f](s)
+		}
+		out[i] = m
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package seats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{RowNumber: "AA", SeatNumber: 12, ID: 345}
+	got, err := DecodeCursor(c.Encode())
+	if err != nil || *got != c {
+		t.Fatalf("DecodeCursor(Encode()) = %+v, %v; want %+v", got, err, c)
+	}
+	for _, bad := range []string{"not base64!", "bm90IGpzb24"} {
+		if _, err := DecodeCursor(bad); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
+
+func TestParseListQuery(t *testing.T) {
+	tests := []struct {
+		query  string
+		ok     bool
+		verify func(t *testing.T, f ListFilter, fields []string)
+	}{
+		{"", true, func(t *testing.T, f ListFilter, fields []string) {
+			if f.Limit != DefaultPageSize || f.MinPrice != nil || len(fields) != 0 {
+				t.Errorf("filter = %+v, want defaults", f)
+			}
+		}},
+		{"event_id=3&status=available,held&section=Floor&min_price=2500&max_price=12000&limit=10&fields=id,price", true,
+			func(t *testing.T, f ListFilter, fields []string) {
+				if *f.EventID != 3 || len(f.Statuses) != 2 || f.Sections[0] != "Floor" || f.Limit != 10 || len(fields) != 2 {
+					t.Errorf("filter = %+v, fields = %v", f, fields)
+				}
+				if *f.MinPrice != 2500 || *f.MaxPrice != 12000 {
+					t.Errorf("prices = %d..%d, want 2500..12000 minor units", *f.MinPrice, *f.MaxPrice)
+				}
+			}},
+		// Minor units easily pass int32 for expensive tickets in small currencies
+		{"max_price=5000000000", true, func(t *testing.T, f ListFilter, fields []string) {
+			if *f.MaxPrice != 5000000000 {
+				t.Errorf("max_price = %d", *f.MaxPrice)
+			}
+		}},
+		{"min_price=200&max_price=100", false, nil},
+		{"min_price=-1", false, nil},
+		{"limit=0", false, nil},
+		{"limit=5001", false, nil},
+		{"fields=id,password", false, nil},
+		{"cursor=garbage", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			rec := httptest.NewRecorder()
+			f, fields, ok := parseListQuery(rec, q)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v (status %d: %s)", ok, tt.ok, rec.Code, rec.Body)
+			}
+			if !ok && rec.Code != http.StatusUnprocessableEntity {
+				t.Errorf("status = %d, want 422", rec.Code)
+			}
+			if tt.verify != nil {
+				tt.verify(t, f, fields)
+			}
+		})
+	}
+}
+
+func TestListETag(t *testing.T) {
+	a, _ := url.ParseQuery("event_id=1&status=available")
+	b, _ := url.ParseQuery("status=available&event_id=1")
+	if listETag(7, a) != listETag(7, b) {
+		t.Error("parameter order changed the ETag")
+	}
+	if listETag(7, a) == listETag(8, a) {
+		t.Error("a new inventory version kept the ETag")
+	}
+	if !etagMatches(`"x", `+listETag(7, a), listETag(7, a)) || etagMatches("", listETag(7, a)) {
+		t.Error("etagMatches disagrees with If-None-Match semantics")
+	}
+}
@@ -0,0 +1,210 @@
+package seats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Manifest formats accepted by the bulk import
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// MaxManifestSeats caps one import; a large arena is ~20,000 seats
+const MaxManifestSeats = 100000
+
+var ErrManifestInvalid = errors.New("seat manifest is invalid")
+
+// ManifestSeat is one line of a venue manifest. Price is a pointer so a missing
+// price can be told apart from a free seat.
+type ManifestSeat struct {
+	Line       int      `json:"-"`
+	Section    string   `json:"section"`
+	Row        string   `json:"row"`
+	SeatNumber int32    `json:"seat_number"`
+	Price      *int32   `json:"price"`
+	TierID     *int32   `json:"tier_id"`
+	X          *float64 `json:"x"`
+	Y          *float64 `json:"y"`
+	Attributes []string `json:"attributes"`
+}
+
+// LineError points at the manifest line (CSV, header is line 1) or entry (JSON, 1-based) that failed
+type LineError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportResult is returned by the bulk import. Nothing is inserted unless Errors is empty.
+type ImportResult struct {
+	EventID  int32       `json:"event_id"`
+	Seats    int         `json:"seats"`
+	Inserted int         `json:"inserted"`
+	DryRun   bool        `json:"dry_run"`
+	Errors   []LineError `json:"errors"`
+}
+
+// ParseManifest reads a CSV or JSON manifest.
+//
+// CSV needs a header row naming the columns; section, row, seat_number and price are
+// required, tier_id, x, y and attributes (separated by ';') are optional.
+// JSON is an array of objects with the same keys, attributes being an array.
+func ParseManifest(r io.Reader, format string) ([]ManifestSeat, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatJSON:
+		return parseJSON(r)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrManifestInvalid, format)
+	}
+}
+
+func parseCSV(r io.Reader) ([]ManifestSeat, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // Short lines are reported per line below, not as a parse failure
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read header: %v", ErrManifestInvalid, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheet exports often start with a byte order mark
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"section", "row", "seat_number", "price"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrManifestInvalid, name)
+		}
+	}
+
+	var seats []ManifestSeat
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrManifestInvalid, err)
+		}
+		if len(seats) == MaxManifestSeats {
+			return nil, fmt.Errorf("%w: more than %d seats", ErrManifestInvalid, MaxManifestSeats)
+		}
+		line, _ := reader.FieldPos(0)
+		seats = append(seats, csvSeat(line, record, columns))
+	}
+	return seats, nil
+}
+
+// csvSeat converts a record. Cells that don't parse are left unset, Validate reports them.
+func csvSeat(line int, record []string, columns map[string]int) ManifestSeat {
+	cell := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	seat := ManifestSeat{Line: line, Section: cell("section"), Row: cell("row")}
+	if n, err := strconv.ParseInt(cell("seat_number"), 10, 32); err == nil {
+		seat.SeatNumber = int32(n)
+	}
+	if n, err := strconv.ParseInt(cell("price"), 10, 32); err == nil {
+		price := int32(n)
+		seat.Price = &price
+	}
+	if n, err := strconv.ParseInt(cell("tier_id"), 10, 32); err == nil {
+		tierID := int32(n)
+		seat.TierID = &tierID
+	}
+	if f, err := strconv.ParseFloat(cell("x"), 64); err == nil {
+		seat.X = &f
+	}
+	if f, err := strconv.ParseFloat(cell("y"), 64); err == nil {
+		seat.Y = &f
+	}
+	for _, attr := range strings.Split(cell("attributes"), ";") {
+		if attr = strings.TrimSpace(attr); attr != "" {
+			seat.Attributes = append(seat.Attributes, attr)
+		}
+	}
+	return seat
+}
+
+func parseJSON(r io.Reader) ([]ManifestSeat, error) {
+	var seats []ManifestSeat
+	if err := json.NewDecoder(r).Decode(&seats); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrManifestInvalid, err)
+	}
+	if len(seats) > MaxManifestSeats {
+		return nil, fmt.Errorf("%w: more than %d seats", ErrManifestInvalid, MaxManifestSeats)
+	}
+	for i := range seats {
+		seats[i].Line = i + 1
+		seats[i].Section = strings.TrimSpace(seats[i].Section)
+		seats[i].Row = strings.TrimSpace(seats[i].Row)
+	}
+	return seats, nil
+}
+
+// Validate checks every line on its own and against the others. It does not need the database.
+func Validate(seats []ManifestSeat) []LineError {
+	errs := []LineError{}
+	seen := make(map[string]int, len(seats))
+	for _, s := range seats {
+		add := func(field, message string) {
+			errs = append(errs, LineError{Line: s.Line, Field: field, Message: message})
+		}
+		if s.Section == "" {
+			add("section", "is required")
+		} else if len([]rune(s.Section)) > 100 {
+			add("section", "must be at most 100 characters")
+		}
+		if s.Row == "" {
+			add("row", "is required")
+		} else if len([]rune(s.Row)) > 10 {
+			add("row", "must be at most 10 characters")
+		}
+		if s.SeatNumber < 1 {
+			add("seat_number", "must be a number of at least 1")
+		}
+		if s.Price == nil {
+			add("price", "is required")
+		} else if *s.Price < 0 {
+			add("price", "cannot be negative")
+		}
+		if (s.X == nil) != (s.Y == nil) {
+			add("x", "x and y must be given together")
+		}
+		for _, attr := range s.Attributes {
+			if !validAttributes[attr] {
+				add("attributes", "unknown attribute "+attr)
+			}
+		}
+
+		if s.Section == "" || s.Row == "" || s.SeatNumber < 1 {
+			continue
+		}
+		key := seatKey(s.Section, s.Row, s.SeatNumber)
+		if first, ok := seen[key]; ok {
+			add("seat_number", fmt.Sprintf("duplicate of line %d", first))
+			continue
+		}
+		seen[key] = s.Line
+	}
+	return errs
+}
+
+func seatKey(section, row string, number int32) string {
+	return section + "\x00" + row + "\x00" + strconv.Itoa(int(number))
+}
@@ -0,0 +1,133 @@
+package seats
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestParseManifestCSV(t *testing.T) {
+	csv := "\ufeffSection, Row, Seat_Number, Price, Tier_ID, X, Y, Attributes\n" +
+		"Floor, A, 1, 50, 3, 10.5, 20, aisle; wheelchair\n" +
+		"Floor, A, x, , , , ,\n" +
+		"Balcony, B, 2\n"
+	seats, err := ParseManifest(strings.NewReader(csv), FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seats) != 3 {
+		t.Fatalf("got %d seats, want 3", len(seats))
+	}
+
+	first := seats[0]
+	if first.Line != 2 || first.Section != "Floor" || first.Row != "A" || first.SeatNumber != 1 {
+		t.Errorf("first seat = %+v", first)
+	}
+	if first.Price == nil || *first.Price != 50 || first.TierID == nil || *first.TierID != 3 {
+		t.Errorf("first seat price/tier = %v/%v", first.Price, first.TierID)
+	}
+	if first.X == nil || *first.X != 10.5 || first.Y == nil || *first.Y != 20 {
+		t.Errorf("first seat position = %v,%v", first.X, first.Y)
+	}
+	if strings.Join(first.Attributes, ",") != "aisle,wheelchair" {
+		t.Errorf("first seat attributes = %v", first.Attributes)
+	}
+
+	// Unparseable and missing cells stay unset for Validate to report
+	if seats[1].SeatNumber != 0 || seats[1].Price != nil {
+		t.Errorf("second seat = %+v, want number and price unset", seats[1])
+	}
+	if seats[2].Line != 4 || seats[2].Price != nil {
+		t.Errorf("short line = %+v", seats[2])
+	}
+}
+
+func TestParseManifestRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		format string
+	}{
+		{"missing price column", "section,row,seat_number\nA,1,1\n", FormatCSV},
+		{"empty csv", "", FormatCSV},
+		{"broken json", `[{"section":`, FormatJSON},
+		{"unknown format", "", "xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseManifest(strings.NewReader(tt.body), tt.format); !errors.Is(err, ErrManifestInvalid) {
+				t.Errorf("error = %v, want ErrManifestInvalid", err)
+			}
+		})
+	}
+}
+
+func TestParseManifestJSON(t *testing.T) {
+	body := `[{"section":" Floor ","row":"A","seat_number":1,"price":0},{"section":"Floor","row":"A","seat_number":2,"price":50,"x":1,"y":2,"attributes":["aisle"]}]`
+	seats, err := ParseManifest(strings.NewReader(body), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seats) != 2 || seats[0].Line != 1 || seats[1].Line != 2 {
+		t.Fatalf("seats = %+v", seats)
+	}
+	if seats[0].Section != "Floor" {
+		t.Errorf("section = %q, want it trimmed", seats[0].Section)
+	}
+	if seats[0].Price == nil || *seats[0].Price != 0 {
+		t.Errorf("free seat price = %v, want 0 (not missing)", seats[0].Price)
+	}
+	if errs := Validate(seats); len(errs) != 0 {
+		t.Errorf("Validate = %+v, want no errors", errs)
+	}
+}
+
+func TestValidateManifest(t *testing.T) {
+	price := int32(50)
+	negative := int32(-1)
+	x := 1.0
+	seats := []ManifestSeat{
+		{Line: 1, Section: "Floor", Row: "A", SeatNumber: 1, Price: &price},
+		{Line: 2, Section: "Floor", Row: "A", SeatNumber: 1, Price: &price},
+		{Line: 3, Section: "", Row: "A", SeatNumber: 0, Price: nil},
+		{Line: 4, Section: "Floor", Row: "ABCDEFGHIJK", SeatNumber: 2, Price: &negative, X: &x},
+		{Line: 5, Section: "Floor", Row: "B", SeatNumber: 1, Price: &price, Attributes: []string{"vip"}},
+	}
+	got := map[string]bool{}
+	for _, e := range Validate(seats) {
+		got[fmt.Sprintf("%d:%s", e.Line, e.Field)] = true
+	}
+	want := []string{
+		"2:seat_number", // duplicate of line 1
+		"3:section", "3:seat_number", "3:price",
+		"4:row", "4:price", "4:x",
+		"5:attributes",
+	}
+	for _, key := range want {
+		if !got[key] {
+			t.Errorf("missing error %s", key)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("errors = %v, want exactly %v", got, want)
+	}
+}
+
+func TestImportSeatsRejectsOversizedManifest(t *testing.T) {
+	router := chi.NewRouter()
+	router.Post("/admin/events/{id}/seats/import", NewHandler(nil, nil, nil).ImportSeats)
+
+	// One absurd line, so the size limit trips before the seat count does
+	body := "section,row,seat_number,price\n" + strings.Repeat("F", maxManifestBytes) + ",A,1,50\n"
+	req := httptest.NewRequest(http.MethodPost, "/admin/events/1/seats/import?format=csv", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 for a manifest over %d bytes", rec.Code, maxManifestBytes)
+	}
+}
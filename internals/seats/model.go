@@ -1,17 +1,48 @@
 package seats
 
+// Seat attributes shown on the seat map
+const (
+	AttrAisle          = "aisle"
+	AttrWheelchair     = "wheelchair"
+	AttrCompanion      = "companion" // Next to a wheelchair space
+	AttrObstructedView = "obstructed_view"
+)
+
+var validAttributes = map[string]bool{
+	AttrAisle:          true,
+	AttrWheelchair:     true,
+	AttrCompanion:      true,
+	AttrObstructedView: true,
+}
+
 type Seat struct {
-	ID         int32  `json:"id"`
-	RowNumber  string `json:"row_number"`
-	SeatNumber int32  `json:"seat_number"`
-	Status     string `json:"status"`
-	Price      int32  `json:"price"`
+	ID         int32    `json:"id"`
+	RowNumber  string   `json:"row_number"`
+	SeatNumber int32    `json:"seat_number"`
+	Status     string   `json:"status"`
+	Price      int32    `json:"price"`
+	EventID    *int32   `json:"event_id"`
+	Section    string   `json:"section"`
+	TierID     *int32   `json:"tier_id"`
+	RowID      *int32   `json:"row_id"`
+	X          *float64 `json:"x"`
+	Y          *float64 `json:"y"`
+	Attributes []string `json:"attributes"`
 }
 
+// SeatCreationRequest describes one seat of an event. When RowID points at a venue row,
+// Section and RowNumber are taken from the layout and may be left empty.
 type SeatCreationRequest struct {
-	RowNumber  string `json:"row_number"`
-	SeatNumber int32  `json:"seat_number"`
-	Price      int32  `json:"price"`
+	RowNumber  string   `json:"row_number"`
+	SeatNumber int32    `json:"seat_number"`
+	Price      int32    `json:"price"`
+	EventID    *int32   `json:"event_id"`
+	Section    string   `json:"section"`
+	TierID     *int32   `json:"tier_id"`
+	RowID      *int32   `json:"row_id"`
+	X          *float64 `json:"x"`
+	Y          *float64 `json:"y"`
+	Attributes []string `json:"attributes"`
 }
 
 type SeatCreationResponse struct {
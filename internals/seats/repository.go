@@ -2,9 +2,22 @@ package seats
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	database "ticketmaster/internals/db"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrRowNotFound    = errors.New("venue row or event not found")
+	ErrSeatExists     = errors.New("this seat already exists for the event")
+	ErrEventNotFound  = errors.New("event not found")
+	ErrEventCancelled = errors.New("event is cancelled")
 )
 
 type Repository struct {
@@ -15,26 +28,233 @@ func NewRepository(db *database.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) CreateSeat(ctx context.Context, rowNo string, seatNo int32, price int32) error {
-	query := `INSERT INTO seats (row_number, seat_number, status,price) VALUES ($1,$2,'AVAILABLE',$3)`
-	_, err := r.db.Pool.Exec(ctx, query, rowNo, seatNo, price)
+const seatColumns = `s.id, s.row_number, s.seat_number, s.status, s.price, s.event_id, s.section, s.tier_id, s.row_id, s.x, s.y, s.attributes`
+
+// faceValue is what /seats/{id}/price charges before fees, in minor units: the seat's tier,
+// then its section's tier, then the legacy whole-unit seats.price
+const faceValue = `COALESCE(t.face_value, s.price::BIGINT * 100)`
+
+func (r *Repository) CreateSeat(ctx context.Context, req SeatCreationRequest) error {
+	// Seats placed on a venue row take their section and row label from the layout
+	query := `INSERT INTO seats (row_number, seat_number, status, price, event_id, section, tier_id, row_id, x, y, attributes)
+	          SELECT COALESCE(vr.label, $1), $2, 'available', $3, $4, COALESCE(vs.name, $5), $6, $7, $8, $9, $10
+	          FROM (SELECT 1) AS one
+	          LEFT JOIN venue_rows vr ON vr.id = $7
+	          LEFT JOIN venue_sections vs ON vs.id = vr.section_id`
+	attributes := req.Attributes
+	if attributes == nil {
+		attributes = []string{}
+	}
+	_, err := r.db.Pool.Exec(ctx, query, req.RowNumber, req.SeatNumber, req.Price, req.EventID, req.Section, req.TierID,
+		req.RowID, req.X, req.Y, attributes)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23503":
+			return ErrRowNotFound
+		case "23505":
+			return ErrSeatExists
+		}
+	}
+	return err
+}
+
+// List returns one page of seats in (row_number, seat_number, id) order, and the
+// cursor of the next page if there is one.
+func (r *Repository) List(ctx context.Context, f ListFilter) ([]Seat, *Cursor, error) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if f.EventID != nil {
+		where = append(where, "s.event_id = "+arg(*f.EventID))
+	}
+	if len(f.Statuses) > 0 {
+		where = append(where, "s.status = ANY("+arg(f.Statuses)+")")
+	}
+	if len(f.Sections) > 0 {
+		where = append(where, "s.section = ANY("+arg(f.Sections)+")")
+	}
+	if f.MinPrice != nil {
+		where = append(where, faceValue+" >= "+arg(*f.MinPrice))
+	}
+	if f.MaxPrice != nil {
+		where = append(where, faceValue+" <= "+arg(*f.MaxPrice))
+	}
+	if f.After != nil {
+		where = append(where, "(s.row_number, s.seat_number, s.id) > ("+arg(f.After.RowNumber)+", "+arg(f.After.SeatNumber)+", "+arg(f.After.ID)+")")
+	}
+
+	query := `SELECT ` + seatColumns + ` FROM seats s`
+	if f.MinPrice != nil || f.MaxPrice != nil {
+		query += `
+			LEFT JOIN section_price_tiers st ON st.event_id = s.event_id AND st.section = s.section
+			LEFT JOIN price_tiers t ON t.id = COALESCE(s.tier_id, st.tier_id)`
+	}
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	// One extra row tells us whether there is a next page
+	query += ` ORDER BY s.row_number, s.seat_number, s.id LIMIT ` + arg(f.Limit+1)
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	seats, err := pgx.CollectRows(rows, pgx.RowToStructByName[Seat])
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+	if len(seats) <= f.Limit {
+		return seats, nil, nil
 	}
-	return nil
+	seats = seats[:f.Limit]
+	last := seats[len(seats)-1]
+	return seats, &Cursor{RowNumber: last.RowNumber, SeatNumber: last.SeatNumber, ID: last.ID}, nil
+}
 
+// InventoryVersion changes whenever any seat of the event (or any seat at all, for nil) is
+// inserted or updated. Both lookups are a single index probe.
+func (r *Repository) InventoryVersion(ctx context.Context, eventID *int32) (int64, error) {
+	var version int64
+	var err error
+	if eventID != nil {
+		err = r.db.Pool.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM seats WHERE event_id = $1`, *eventID).Scan(&version)
+	} else {
+		err = r.db.Pool.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM seats`).Scan(&version)
+	}
+	return version, err
 }
 
-func (r *Repository) GetAll(ctx context.Context) ([]Seat, error) {
-	// Query remains the same
-	query := `SELECT id, row_number, seat_number, status, price FROM seats ORDER BY row_number, seat_number ASC`
+// Import validates a manifest against the event and inserts all of it with COPY, or nothing.
+// With dryRun the checks run but nothing is written.
+func (r *Repository) Import(ctx context.Context, eventID int32, manifest []ManifestSeat, dryRun bool) (*ImportResult, error) {
+	result := &ImportResult{EventID: eventID, Seats: len(manifest), DryRun: dryRun, Errors: Validate(manifest)}
 
-	rows, err := r.db.Pool.Query(ctx, query)
+	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback(ctx)
 
-	// Use pgx to map to the Seat struct defined in this same package
-	return pgx.CollectRows(rows, pgx.RowToStructByName[Seat])
+	// 1. Lock the event so two imports for it can't interleave
+	var venueID *int32
+	var status string
+	err = tx.QueryRow(ctx, `SELECT venue_id, status FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&venueID, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == "cancelled" {
+		return nil, ErrEventCancelled
+	}
+
+	// 2. What the manifest is checked against
+	existing := make(map[string]bool)
+	rows, err := tx.Query(ctx, `SELECT section, row_number, seat_number FROM seats WHERE event_id = $1`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var section, row string
+		var number int32
+		if err := rows.Scan(&section, &row, &number); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing[seatKey(section, row, number)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(ctx, `SELECT id FROM price_tiers WHERE event_id = $1`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	tierIDs, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	if err != nil {
+		return nil, err
+	}
+	tiers := make(map[int32]bool, len(tierIDs))
+	for _, id := range tierIDs {
+		tiers[id] = true
+	}
+
+	// Seats of an event with a venue must sit on a row of its layout
+	var layout map[string]int32
+	if venueID != nil {
+		layout = make(map[string]int32)
+		rows, err := tx.Query(ctx, `
+			SELECT vs.name, vr.label, vr.id
+			FROM venue_rows vr JOIN venue_sections vs ON vs.id = vr.section_id
+			WHERE vs.venue_id = $1`, *venueID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var section, label string
+			var id int32
+			if err := rows.Scan(&section, &label, &id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			layout[section+"\x00"+label] = id
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. Per-line checks that need the database
+	rowIDs := make([]*int32, len(manifest))
+	for i, s := range manifest {
+		if s.SeatNumber >= 1 && existing[seatKey(s.Section, s.Row, s.SeatNumber)] {
+			result.Errors = append(result.Errors, LineError{Line: s.Line, Field: "seat_number", Message: "already exists for this event"})
+		}
+		if s.TierID != nil && !tiers[*s.TierID] {
+			result.Errors = append(result.Errors, LineError{Line: s.Line, Field: "tier_id", Message: "is not a price tier of this event"})
+		}
+		if layout != nil {
+			id, ok := layout[s.Section+"\x00"+s.Row]
+			if !ok {
+				result.Errors = append(result.Errors, LineError{Line: s.Line, Field: "row", Message: "is not a row of this section in the venue layout"})
+				continue
+			}
+			rowIDs[i] = &id
+		}
+	}
+	sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Line < result.Errors[j].Line })
+	if len(result.Errors) > 0 || dryRun {
+		return result, nil
+	}
+
+	// 4. Insert everything in one COPY
+	inserted, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"seats"},
+		[]string{"row_number", "seat_number", "status", "price", "event_id", "section", "tier_id", "row_id", "x", "y", "attributes"},
+		pgx.CopyFromSlice(len(manifest), func(i int) ([]any, error) {
+			s := manifest[i]
+			attributes := s.Attributes
+			if attributes == nil {
+				attributes = []string{}
+			}
+			return []any{s.Row, s.SeatNumber, "available", *s.Price, eventID, s.Section, s.TierID, rowIDs[i], s.X, s.Y, attributes}, nil
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy seats: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	result.Inserted = int(inserted)
+	return result, nil
 }
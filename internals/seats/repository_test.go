@@ -0,0 +1,65 @@
+package seats
+
+import (
+	"context"
+	"testing"
+	"ticketmaster/internals/db/dbtest"
+)
+
+func TestListFiltersOnResolvedPrice(t *testing.T) {
+	db := dbtest.New(t)
+
+	eventID := dbtest.ID(t, db, `INSERT INTO events (name, starts_at) VALUES ('Gig', NOW() + INTERVAL '1 day') RETURNING id`)
+	floor := dbtest.ID(t, db, `INSERT INTO price_tiers (event_id, name, currency, face_value) VALUES ($1, 'Floor', 'USD', 12000) RETURNING id`, eventID)
+	vip := dbtest.ID(t, db, `INSERT INTO price_tiers (event_id, name, currency, face_value) VALUES ($1, 'VIP', 'USD', 30000) RETURNING id`, eventID)
+	dbtest.Exec(t, db, `INSERT INTO section_price_tiers (event_id, section, tier_id) VALUES ($1, 'Floor', $2)`, eventID, floor)
+
+	// The legacy price column says 10 (units) everywhere; only the tiers tell the real price
+	seat := func(section string, number int, tierID *int32) int32 {
+		return dbtest.ID(t, db, `
+			INSERT INTO seats (row_number, seat_number, price, event_id, section, tier_id)
+			VALUES ('A', $1, 10, $2, $3, $4) RETURNING id`, number, eventID, section, tierID)
+	}
+	sectionPriced := seat("Floor", 1, nil) // 120.00 via the section
+	seatPriced := seat("Floor", 2, &vip)   // 300.00 via the seat override
+	legacy := seat("Balcony", 3, nil)      // 10.00 from seats.price
+
+	ids := func(min, max *int64) []int32 {
+		t.Helper()
+		page, _, err := NewRepository(db).List(context.Background(), ListFilter{EventID: &eventID, MinPrice: min, MaxPrice: max, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []int32
+		for _, s := range page {
+			out = append(out, s.ID)
+		}
+		return out
+	}
+	price := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name     string
+		min, max *int64
+		want     []int32
+	}{
+		{"no filter", nil, nil, []int32{sectionPriced, seatPriced, legacy}},
+		{"under 50.00", nil, price(5000), []int32{legacy}},
+		{"100.00 to 200.00", price(10000), price(20000), []int32{sectionPriced}},
+		{"exactly the override", price(30000), price(30000), []int32{seatPriced}},
+		{"whole units match nothing", price(10), price(20), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(tt.min, tt.max)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ids = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ids = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
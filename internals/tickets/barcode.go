@@ -0,0 +1,32 @@
+package tickets
+
+import (
+	"strings"
+	"ticketmaster/internals/totp"
+	"time"
+)
+
+// BarcodeSeparator joins a ticket token and its rotating code: "<token>~<code>".
+// It is outside the base64url alphabet, so the token can't contain it.
+const BarcodeSeparator = "~"
+
+// Barcode is what a ticket with a barcode secret shows at t: its token plus the TOTP code
+// of the current window. The code is only accepted for a window or so either side of t,
+// so a screenshot is worthless a minute later. Without a secret it is just the token.
+func Barcode(token string, secret *string, t time.Time) (string, error) {
+	if secret == nil {
+		return token, nil
+	}
+	code, err := totp.CodeAt(*secret, totp.Counter(t))
+	if err != nil {
+		return "", err
+	}
+	return token + BarcodeSeparator + code, nil
+}
+
+// ParseBarcode splits what a scanner read into the signed token and the rotating code,
+// which is empty for a static barcode
+func ParseBarcode(content string) (token, code string) {
+	token, code, _ = strings.Cut(strings.TrimSpace(content), BarcodeSeparator)
+	return token, code
+}
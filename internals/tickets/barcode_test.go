@@ -0,0 +1,82 @@
+package tickets
+
+import (
+	"strings"
+	"testing"
+	"ticketmaster/internals/totp"
+	"time"
+)
+
+func TestBarcode(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	if got, err := Barcode("tm1.abc.def", nil, now); err != nil || got != "tm1.abc.def" {
+		t.Errorf("Barcode without secret = %q, %v; want the bare token", got, err)
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := Barcode("tm1.abc.def", &secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// What the scanner does with it, padding from the reader included
+	token, code := ParseBarcode("  " + content + "\n")
+	if token != "tm1.abc.def" || len(code) != totp.Digits {
+		t.Fatalf("ParseBarcode(%q) = %q, %q", content, token, code)
+	}
+
+	// A screenshot is good for a window either side of the scan, not for minutes
+	tests := []struct {
+		offset time.Duration
+		wantOK bool
+	}{
+		{0, true},
+		{-30 * time.Second, true},
+		{30 * time.Second, true},
+		{-90 * time.Second, false},
+		{90 * time.Second, false},
+	}
+	for _, tt := range tests {
+		if _, ok := totp.Validate(secret, code, now.Add(tt.offset), 1); ok != tt.wantOK {
+			t.Errorf("code scanned at %+v: ok = %v, want %v", tt.offset, ok, tt.wantOK)
+		}
+	}
+
+	bad := "not base32!"
+	if _, err := Barcode("tm1.abc.def", &bad, now); err == nil {
+		t.Error("Barcode with an invalid secret succeeded")
+	}
+}
+
+func TestParseBarcode(t *testing.T) {
+	tests := []struct {
+		content     string
+		token, code string
+	}{
+		{"tm1.abc.def", "tm1.abc.def", ""},
+		{"tm1.abc.def~123456", "tm1.abc.def", "123456"},
+		{" tm1.abc.def~123456\r\n", "tm1.abc.def", "123456"},
+		{"tm1.abc.def~", "tm1.abc.def", ""},
+	}
+	for _, tt := range tests {
+		token, code := ParseBarcode(tt.content)
+		if token != tt.token || code != tt.code {
+			t.Errorf("ParseBarcode(%q) = %q, %q; want %q, %q", tt.content, token, code, tt.token, tt.code)
+		}
+	}
+
+	// A real token survives the round trip
+	token, err := newSigner(t).Sign(Payload{TicketID: 1, EventID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(token, BarcodeSeparator) {
+		t.Fatalf("token %q contains the separator", token)
+	}
+	if got, code := ParseBarcode(token + BarcodeSeparator + "123456"); got != token || code != "123456" {
+		t.Errorf("ParseBarcode of a signed token = %q, %q", got, code)
+	}
+}
@@ -0,0 +1,110 @@
+package tickets
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"ticketmaster/internals/middleware"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// GetTicket handles GET /bookings/{id}/ticket
+func (h *Handler) GetTicket(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.ticket(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-store")
+	json.NewEncoder(w).Encode(t)
+}
+
+// GetQRCode handles GET /bookings/{id}/ticket/qr: the ticket's barcode as a PNG QR code.
+// ?size= sets the width in pixels. With rotating barcodes the image is only good for the
+// current window; apps should render Barcode themselves from the secret.
+func (h *Handler) GetQRCode(w http.ResponseWriter, r *http.Request) {
+	size := DefaultQRSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < MinQRSize || n > MaxQRSize {
+			http.Error(w, "size must be between "+strconv.Itoa(MinQRSize)+" and "+strconv.Itoa(MaxQRSize), http.StatusBadRequest)
+			return
+		}
+		size = n
+	}
+
+	t, ok := h.ticket(w, r)
+	if !ok {
+		return
+	}
+	content, err := Barcode(t.Token, t.BarcodeSecret, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to render ticket", http.StatusInternalServerError)
+		return
+	}
+	png, err := qrcode.Encode(content, qrcode.Medium, size)
+	if err != nil {
+		http.Error(w, "Failed to render ticket", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(png)
+}
+
+// IssueTickets handles POST /admin/events/{id}/tickets/issue: a ticket for every confirmed
+// booking that has none yet, so the next scanner manifest covers attendees who never opened
+// theirs. Run it before the doors open; calling it again only issues what is still missing.
+func (h *Handler) IssueTickets(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid event id", http.StatusBadRequest)
+		return
+	}
+	n, err := h.repo.IssueMissing(r.Context(), int32(eventID))
+	if err != nil {
+		http.Error(w, "Failed to issue tickets", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"issued": n})
+}
+
+func (h *Handler) ticket(w http.ResponseWriter, r *http.Request) (*Ticket, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return nil, false
+	}
+	bookingID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid booking id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	t, err := h.repo.ForBooking(r.Context(), int32(bookingID), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrBookingNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrBookingInactive):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to issue ticket", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return t, true
+}
@@ -0,0 +1,139 @@
+package tickets
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"ticketmaster/internals/keyring"
+)
+
+// Signer holds the ticket keys: the active one signs, all of them verify.
+// Keep a retired key around (as kid.pub.pem is enough) until the events it signed for are over.
+type Signer struct {
+	activeID string
+	private  ed25519.PrivateKey
+	public   PublicKeys
+}
+
+// LoadSigner reads every *.pem file in dir, like keyring.Load: the file name (without .pem)
+// is the key ID, private keys are PKCS#8 Ed25519 and *.pub.pem files are verify-only.
+func LoadSigner(dir string, activeKID string) (*Signer, error) {
+	s := &Signer{public: make(PublicKeys)}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(raw)
+		if block == nil {
+			return nil, fmt.Errorf("failed to load key %s: no PEM block", path)
+		}
+
+		name := filepath.Base(path)
+		if strings.HasSuffix(name, ".pub.pem") {
+			pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load key %s: %w", path, err)
+			}
+			edPub, ok := pub.(ed25519.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("failed to load key %s: ticket keys must be Ed25519", path)
+			}
+			s.public[strings.TrimSuffix(name, ".pub.pem")] = edPub
+			continue
+		}
+
+		priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s: %w", path, err)
+		}
+		edPriv, ok := priv.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("failed to load key %s: ticket keys must be Ed25519", path)
+		}
+		id := strings.TrimSuffix(name, ".pem")
+		s.public[id] = edPriv.Public().(ed25519.PublicKey)
+		if id == activeKID {
+			s.activeID, s.private = id, edPriv
+		}
+	}
+
+	if s.private == nil {
+		return nil, fmt.Errorf("no private key %q in %s", activeKID, dir)
+	}
+	return s, nil
+}
+
+// EphemeralSigner signs with a key generated at startup, for development only:
+// tickets issued with it stop verifying once the process restarts.
+func EphemeralSigner() (*Signer, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := "ephemeral-" + base64.RawURLEncoding.EncodeToString(pub[:6])
+	return &Signer{activeID: id, private: priv, public: PublicKeys{id: pub}}, nil
+}
+
+// Sign stamps the active key ID into the payload and returns the ticket token
+func (s *Signer) Sign(p Payload) (string, error) {
+	if s.private == nil {
+		return "", errors.New("no active ticket key")
+	}
+	p.KeyID = s.activeID
+	return s.sign(tokenPrefix, p)
+}
+
+// sign encodes v as "<prefix><payload>.<signature>"; the prefix keeps a ticket from
+// passing as a manifest and the other way round.
+func (s *Signer) sign(prefix string, v interface{}) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	signed := prefix + b64.EncodeToString(raw)
+	return signed + "." + b64.EncodeToString(ed25519.Sign(s.private, []byte(signed))), nil
+}
+
+// PublicKeys is everything a ticket may have been signed with
+func (s *Signer) PublicKeys() PublicKeys {
+	return s.public
+}
+
+// JWKS publishes the public keys in the same form as /.well-known/jwks.json
+func (s *Signer) JWKS() keyring.JWKSet {
+	set := keyring.JWKSet{Keys: []keyring.JWK{}}
+	for id, pub := range s.public {
+		set.Keys = append(set.Keys, keyring.JWK{
+			KeyType:   "OKP",
+			KeyID:     id,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			Curve:     "Ed25519",
+			X:         b64.EncodeToString(pub),
+		})
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+// ServeKeys handles GET /tickets/keys; scanners download it before doors open
+func (s *Signer) ServeKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(s.JWKS())
+}
@@ -0,0 +1,100 @@
+package tickets
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	signer := newSigner(t)
+	generated := time.Date(2026, 6, 1, 18, 0, 0, 0, time.UTC)
+	token, err := signer.SignManifest(Manifest{
+		EventID:     3,
+		GeneratedAt: generated,
+		Valid:       []int32{40, 7, 1000000, 8},
+		CheckedIn:   []int32{8},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, manifestPrefix) {
+		t.Fatalf("manifest %q lacks the %s prefix", token, manifestPrefix)
+	}
+
+	m, err := VerifyManifest(token, signer.PublicKeys())
+	if err != nil {
+		t.Fatalf("VerifyManifest: %v", err)
+	}
+	if m.EventID != 3 || !m.GeneratedAt.Equal(generated) {
+		t.Errorf("manifest = event %d generated %s", m.EventID, m.GeneratedAt)
+	}
+	// Sorted on the way in, whatever order the caller had
+	if !slices.Equal(m.Valid, []int32{7, 8, 40, 1000000}) || !slices.Equal(m.CheckedIn, []int32{8}) {
+		t.Errorf("valid = %v, checked in = %v", m.Valid, m.CheckedIn)
+	}
+
+	_, sig, _ := strings.Cut(strings.TrimPrefix(token, manifestPrefix), ".")
+	other, _ := signer.SignManifest(Manifest{EventID: 3, Valid: []int32{1, 2, 3}})
+	otherPayload, _, _ := strings.Cut(strings.TrimPrefix(other, manifestPrefix), ".")
+	ticket, _ := signer.Sign(Payload{TicketID: 1, EventID: 3})
+
+	tests := []struct {
+		name  string
+		token string
+		keys  PublicKeys
+		want  error
+	}{
+		{"unknown key", token, newSigner(t).PublicKeys(), ErrUnknownKey},
+		{"tickets added under the signature", manifestPrefix + otherPayload + "." + sig, signer.PublicKeys(), ErrBadSignature},
+		{"ticket as manifest", ticket, signer.PublicKeys(), ErrMalformedManifest},
+		{"truncated", token[:len(token)/2], signer.PublicKeys(), ErrMalformedManifest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := VerifyManifest(tt.token, tt.keys); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyManifest = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestManifestAdmits(t *testing.T) {
+	m := Manifest{EventID: 3, Valid: []int32{7, 8, 40}, CheckedIn: []int32{8}}
+	tests := []struct {
+		name string
+		p    Payload
+		want bool
+	}{
+		{"live ticket", Payload{TicketID: 7, EventID: 3}, true},
+		{"checked in before the export", Payload{TicketID: 8, EventID: 3}, false},
+		{"reissued or cancelled", Payload{TicketID: 9, EventID: 3}, false},
+		{"other event", Payload{TicketID: 7, EventID: 4}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Admits(&tt.p); got != tt.want {
+				t.Errorf("Admits = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeltaEncoding(t *testing.T) {
+	ids := []int32{5, 1, 3, 1000}
+	encoded := deltaEncode(ids)
+	if !slices.Equal(encoded, []int32{1, 2, 2, 995}) {
+		t.Errorf("deltaEncode = %v, want [1 2 2 995]", encoded)
+	}
+	if !slices.Equal(ids, []int32{5, 1, 3, 1000}) {
+		t.Error("deltaEncode sorted the caller's slice")
+	}
+	if decoded := deltaDecode(encoded); !slices.Equal(decoded, []int32{1, 3, 5, 1000}) {
+		t.Errorf("deltaDecode = %v", decoded)
+	}
+	if len(deltaDecode(deltaEncode(nil))) != 0 {
+		t.Error("empty list did not round-trip")
+	}
+}
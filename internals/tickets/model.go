@@ -0,0 +1,24 @@
+package tickets
+
+import "time"
+
+const (
+	DefaultQRSize = 320 // Pixels
+	MinQRSize     = 128
+	MaxQRSize     = 1024
+)
+
+type Ticket struct {
+	ID           int32     `json:"id"`
+	BookingID    int32     `json:"booking_id"`
+	EventID      int32     `json:"event_id"`
+	SeatID       *int32    `json:"seat_id,omitempty"`
+	TicketTypeID *int32    `json:"ticket_type_id,omitempty"`
+	HolderID     int32     `json:"holder_id"`
+	Token        string    `json:"token"`
+	IssuedAt     time.Time `json:"issued_at"`
+
+	// Set for events with rotating barcodes: the client shows Barcode(token, secret, now)
+	// instead of the bare token, refreshing it every totp.Period.
+	BarcodeSecret *string `json:"barcode_secret,omitempty"`
+}
@@ -0,0 +1,178 @@
+package tickets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	database "ticketmaster/internals/db"
+	"ticketmaster/internals/totp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrBookingNotFound = errors.New("booking not found")
+	ErrBookingInactive = errors.New("booking is no longer valid")
+)
+
+type Repository struct {
+	db     *database.DB
+	signer *Signer
+}
+
+func NewRepository(db *database.DB, signer *Signer) *Repository {
+	return &Repository{db: db, signer: signer}
+}
+
+// ForBooking returns the live ticket of the user's booking, issuing it the first time.
+// Issuing locks the booking row, so two concurrent first requests get the same ticket.
+func (r *Repository) ForBooking(ctx context.Context, bookingID, userID int32) (*Ticket, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. The booking must be the user's and still confirmed
+	t := Ticket{BookingID: bookingID}
+	var status string
+	var rotating bool
+	err = tx.QueryRow(ctx, `
+		SELECT b.user_id, b.event_id, b.seat_id, b.ga_ticket_type_id, b.status, e.rotating_barcodes
+		FROM bookings b JOIN events e ON e.id = b.event_id
+		WHERE b.id = $1 FOR UPDATE OF b`, bookingID).
+		Scan(&t.HolderID, &t.EventID, &t.SeatID, &t.TicketTypeID, &status, &rotating)
+	if err == pgx.ErrNoRows || (err == nil && t.HolderID != userID) {
+		return nil, ErrBookingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load booking: %w", err)
+	}
+	if status != "confirmed" {
+		return nil, ErrBookingInactive
+	}
+
+	// 2. Already issued?
+	err = tx.QueryRow(ctx, `
+		SELECT id, token, issued_at, barcode_secret FROM tickets
+		WHERE booking_id = $1 AND revoked_at IS NULL`, bookingID).Scan(&t.ID, &t.Token, &t.IssuedAt, &t.BarcodeSecret)
+	if err == nil {
+		return &t, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to load ticket: %w", err)
+	}
+
+	// 3. Issue: the ID goes into the signed payload, so take it before inserting
+	if err := tx.QueryRow(ctx, `SELECT nextval(pg_get_serial_sequence('tickets', 'id'))`).Scan(&t.ID); err != nil {
+		return nil, fmt.Errorf("failed to allocate ticket: %w", err)
+	}
+	t.IssuedAt = time.Now().UTC().Truncate(time.Second)
+	if err := r.sign(&t, rotating); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO tickets (id, booking_id, holder_id, token, issued_at, barcode_secret) VALUES ($1, $2, $3, $4, $5, $6)`,
+		t.ID, t.BookingID, t.HolderID, t.Token, t.IssuedAt, t.BarcodeSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue ticket: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &t, nil
+}
+
+// IssueMissing issues a ticket for every confirmed booking of the event that has none yet,
+// so an exported scanner manifest covers attendees who never opened their ticket.
+func (r *Repository) IssueMissing(ctx context.Context, eventID int32) (int, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the bookings keeps ForBooking from issuing one of them at the same time
+	rows, err := tx.Query(ctx, `
+		SELECT b.id, b.user_id, b.event_id, b.seat_id, b.ga_ticket_type_id, e.rotating_barcodes
+		FROM bookings b JOIN events e ON e.id = b.event_id
+		WHERE b.event_id = $1 AND b.status = 'confirmed'
+		  AND NOT EXISTS (SELECT 1 FROM tickets t WHERE t.booking_id = b.id AND t.revoked_at IS NULL)
+		ORDER BY b.id
+		FOR UPDATE OF b`, eventID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load bookings: %w", err)
+	}
+	var rotating bool
+	missing, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Ticket, error) {
+		var t Ticket
+		err := row.Scan(&t.BookingID, &t.HolderID, &t.EventID, &t.SeatID, &t.TicketTypeID, &rotating)
+		return t, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to load bookings: %w", err)
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	rows, err = tx.Query(ctx, `SELECT nextval(pg_get_serial_sequence('tickets', 'id')) FROM generate_series(1, $1)`, len(missing))
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate tickets: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate tickets: %w", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	copyRows := make([][]any, len(missing))
+	for i, t := range missing {
+		t.ID, t.IssuedAt = int32(ids[i]), now
+		if err := r.sign(&t, rotating); err != nil {
+			return 0, err
+		}
+		copyRows[i] = []any{t.ID, t.BookingID, t.HolderID, t.Token, t.IssuedAt, t.BarcodeSecret}
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"tickets"},
+		[]string{"id", "booking_id", "holder_id", "token", "issued_at", "barcode_secret"}, pgx.CopyFromRows(copyRows))
+	if err != nil {
+		return 0, fmt.Errorf("failed to issue tickets: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(missing), nil
+}
+
+// sign fills in the token, and the barcode secret when the event has rotating barcodes
+func (r *Repository) sign(t *Ticket, rotating bool) error {
+	var err error
+	t.Token, err = r.signer.Sign(t.payload())
+	if err != nil {
+		return fmt.Errorf("failed to sign ticket: %w", err)
+	}
+	if rotating {
+		secret, err := totp.NewSecret()
+		if err != nil {
+			return fmt.Errorf("failed to create barcode secret: %w", err)
+		}
+		t.BarcodeSecret = &secret
+	}
+	return nil
+}
+
+func (t Ticket) payload() Payload {
+	return Payload{
+		TicketID:     t.ID,
+		BookingID:    t.BookingID,
+		EventID:      t.EventID,
+		SeatID:       t.SeatID,
+		TicketTypeID: t.TicketTypeID,
+		HolderID:     t.HolderID,
+		IssuedAt:     t.IssuedAt.Unix(),
+	}
+}
@@ -0,0 +1,105 @@
+// Package tickets issues the signed tickets attendees show at the door.
+//
+// A ticket is a compact token, "TM1.<payload>.<signature>" in base64url, where the
+// signature is Ed25519 over "TM1.<payload>". Verify needs nothing but the public keys,
+// so door scanners can check a ticket without contacting the server.
+package tickets
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"ticketmaster/internals/keyring"
+	"time"
+)
+
+const tokenPrefix = "TM1."
+
+var (
+	ErrMalformedTicket = errors.New("malformed ticket")
+	ErrUnknownKey      = errors.New("ticket signed with an unknown key")
+	ErrBadSignature    = errors.New("ticket signature is invalid")
+)
+
+var b64 = base64.RawURLEncoding
+
+// Payload is what a ticket asserts. Field names are short to keep the QR code small.
+type Payload struct {
+	TicketID     int32  `json:"tid"`
+	BookingID    int32  `json:"bid"`
+	EventID      int32  `json:"eid"`
+	SeatID       *int32 `json:"sid,omitempty"` // Reserved seating
+	TicketTypeID *int32 `json:"gid,omitempty"` // General admission
+	HolderID     int32  `json:"hid"`
+	IssuedAt     int64  `json:"iat"` // Unix seconds
+	KeyID        string `json:"kid"`
+}
+
+func (p Payload) Issued() time.Time {
+	return time.Unix(p.IssuedAt, 0).UTC()
+}
+
+// PublicKeys are the keys a scanner accepts, by key ID
+type PublicKeys map[string]ed25519.PublicKey
+
+// Verify checks the signature of a ticket and returns what it says. It never talks to
+// the server: whether the ticket was since revoked or already scanned is not its business.
+func Verify(token string, keys PublicKeys) (*Payload, error) {
+	var p Payload
+	if err := verify(tokenPrefix, token, keys, &p, func() string { return p.KeyID }); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// verify decodes the payload of a "<prefix><payload>.<signature>" token into v, then checks
+// the signature with the key kid names once v is decoded
+func verify(prefix, token string, keys PublicKeys, v interface{}, kid func() string) error {
+	rest, ok := strings.CutPrefix(token, prefix)
+	if !ok {
+		return ErrMalformedTicket
+	}
+	encoded, sigPart, ok := strings.Cut(rest, ".")
+	if !ok {
+		return ErrMalformedTicket
+	}
+	raw, err := b64.DecodeString(encoded)
+	if err != nil {
+		return ErrMalformedTicket
+	}
+	sig, err := b64.DecodeString(sigPart)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return ErrMalformedTicket
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return ErrMalformedTicket
+	}
+
+	pub, ok := keys[kid()]
+	if !ok {
+		return ErrUnknownKey
+	}
+	if !ed25519.Verify(pub, []byte(prefix+encoded), sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// KeysFromJWKS reads the set served at GET /tickets/keys, which scanners download ahead of time
+func KeysFromJWKS(set keyring.JWKSet) (PublicKeys, error) {
+	keys := make(PublicKeys)
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "OKP" || jwk.Curve != "Ed25519" {
+			continue
+		}
+		pub, err := b64.DecodeString(jwk.X)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key %q", jwk.KeyID)
+		}
+		keys[jwk.KeyID] = ed25519.PublicKey(pub)
+	}
+	return keys, nil
+}
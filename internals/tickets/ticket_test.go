@@ -0,0 +1,157 @@
+package tickets
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newSigner(t *testing.T) *Signer {
+	t.Helper()
+	s, err := EphemeralSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestVerify(t *testing.T) {
+	signer := newSigner(t)
+	seat := int32(7)
+	want := Payload{TicketID: 1, BookingID: 2, EventID: 3, SeatID: &seat, HolderID: 4, IssuedAt: 1700000000}
+	token, err := signer.Sign(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, tokenPrefix) {
+		t.Fatalf("token %q lacks the %s prefix", token, tokenPrefix)
+	}
+
+	got, err := Verify(token, signer.PublicKeys())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.TicketID != 1 || got.BookingID != 2 || got.EventID != 3 || got.SeatID == nil || *got.SeatID != 7 ||
+		got.HolderID != 4 || got.IssuedAt != 1700000000 || got.KeyID != signer.activeID {
+		t.Errorf("payload = %+v", got)
+	}
+
+	_, sig, _ := strings.Cut(strings.TrimPrefix(token, tokenPrefix), ".")
+	tests := []struct {
+		name  string
+		token string
+		keys  PublicKeys
+		want  error
+	}{
+		{"other signer's key", token, newSigner(t).PublicKeys(), ErrUnknownKey},
+		{"payload swapped under the signature", tokenPrefix + encodedWithKey(t, token, signer) + "." + sig, signer.PublicKeys(), ErrBadSignature},
+		{"no prefix", strings.TrimPrefix(token, tokenPrefix), signer.PublicKeys(), ErrMalformedTicket},
+		{"no signature", strings.SplitN(token, ".", 3)[0] + "." + strings.SplitN(token, ".", 3)[1], signer.PublicKeys(), ErrMalformedTicket},
+		{"short signature", token[:len(token)-4], signer.PublicKeys(), ErrMalformedTicket},
+		{"not base64", tokenPrefix + "!!!." + sig, signer.PublicKeys(), ErrMalformedTicket},
+		{"manifest as ticket", manifestToken(t, signer), signer.PublicKeys(), ErrMalformedTicket},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(tt.token, tt.keys); !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// encodedWithKey is another payload, claiming the same key as token, encoded as in a token
+func encodedWithKey(t *testing.T, token string, signer *Signer) string {
+	t.Helper()
+	other, err := signer.Sign(Payload{TicketID: 99, EventID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _, _ := strings.Cut(strings.TrimPrefix(other, tokenPrefix), ".")
+	if strings.Contains(token, encoded) {
+		t.Fatal("payloads should differ")
+	}
+	return encoded
+}
+
+func manifestToken(t *testing.T, signer *Signer) string {
+	t.Helper()
+	token, err := signer.SignManifest(Manifest{EventID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestKeysFromJWKS(t *testing.T) {
+	signer := newSigner(t)
+	token, _ := signer.Sign(Payload{TicketID: 1})
+
+	// What a scanner does: download the set, verify with it
+	keys, err := KeysFromJWKS(signer.JWKS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(token, keys); err != nil {
+		t.Errorf("Verify with keys from the JWKS: %v", err)
+	}
+
+	set := signer.JWKS()
+	set.Keys[0].X = "dG9vIHNob3J0"
+	if _, err := KeysFromJWKS(set); err == nil {
+		t.Error("a key of the wrong length was accepted")
+	}
+}
+
+func TestLoadSigner(t *testing.T) {
+	dir := t.TempDir()
+	writeKey := func(name string, der []byte, kind string) {
+		t.Helper()
+		data := pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newKey := func() ed25519.PrivateKey {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return priv
+	}
+
+	retired, active := newKey(), newKey()
+	der, _ := x509.MarshalPKCS8PrivateKey(active)
+	writeKey("2026-02.pem", der, "PRIVATE KEY")
+	der, _ = x509.MarshalPKIXPublicKey(retired.Public())
+	writeKey("2026-01.pub.pem", der, "PUBLIC KEY")
+
+	signer, err := LoadSigner(dir, "2026-02")
+	if err != nil {
+		t.Fatalf("LoadSigner: %v", err)
+	}
+	if len(signer.PublicKeys()) != 2 {
+		t.Errorf("loaded %d keys, want 2", len(signer.PublicKeys()))
+	}
+
+	// A ticket signed before the rotation still verifies
+	old := &Signer{activeID: "2026-01", private: retired, public: PublicKeys{"2026-01": retired.Public().(ed25519.PublicKey)}}
+	token, _ := old.Sign(Payload{TicketID: 1})
+	if _, err := Verify(token, signer.PublicKeys()); err != nil {
+		t.Errorf("ticket of the retired key: %v", err)
+	}
+	token, _ = signer.Sign(Payload{TicketID: 2})
+	if p, err := Verify(token, signer.PublicKeys()); err != nil || p.KeyID != "2026-02" {
+		t.Errorf("new ticket = %+v, %v; want signed with 2026-02", p, err)
+	}
+
+	if _, err := LoadSigner(dir, "2026-01"); err == nil {
+		t.Error("a verify-only key was accepted as the active key")
+	}
+}
@@ -0,0 +1,106 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The RFC 6238 appendix B key, "12345678901234567890", in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeAtRFCVectors(t *testing.T) {
+	// The RFC lists 8 digits; ours are the last 6
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := CodeAt(rfcSecret, Counter(time.Unix(tt.unix, 0)))
+		if err != nil || got != tt.want {
+			t.Errorf("CodeAt(%d) = %q, %v; want %q", tt.unix, got, err, tt.want)
+		}
+	}
+	// Secrets are read the way people type them
+	if got, _ := CodeAt(" "+strings.ToLower(rfcSecret)+" ", Counter(time.Unix(59, 0))); got != "287082" {
+		t.Errorf("lower-case secret gave %q", got)
+	}
+	if _, err := CodeAt("not base32!", 0); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestValidateWindows(t *testing.T) {
+	now := time.Unix(1111111111, 0) // 1 second into its step
+	code := func(offset int64) string {
+		c, _ := CodeAt(rfcSecret, Counter(now)+offset)
+		return c
+	}
+
+	tests := []struct {
+		name   string
+		code   string
+		skew   int
+		wantOK bool
+		wantAt int64
+	}{
+		{"current step", code(0), 1, true, 0},
+		{"previous step within skew", code(-1), 1, true, -1},
+		{"next step within skew", code(1), 1, true, 1},
+		{"two steps back", code(-2), 1, false, 0},
+		{"two steps ahead", code(2), 1, false, 0},
+		{"previous step without skew", code(-1), 0, false, 0},
+		{"two steps back, skew 2", code(-2), 2, true, -2},
+		{"wrong length", code(0)[:5], 1, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+			if ok != tt.wantOK || (ok && counter != Counter(now)+tt.wantAt) {
+				t.Errorf("Validate = %d, %v; want ok=%v at step %+d", counter, ok, tt.wantOK, tt.wantAt)
+			}
+		})
+	}
+
+	// The step boundary: the last second of a step and the first of the next
+	boundary := time.Unix((Counter(now)+1)*int64(Period.Seconds()), 0)
+	if Counter(boundary.Add(-time.Second)) != Counter(now) || Counter(boundary) != Counter(now)+1 {
+		t.Errorf("step boundary at %s is off", boundary)
+	}
+	if _, ok := Validate(rfcSecret, code(0), boundary, 0); ok {
+		t.Error("previous step's code accepted after the boundary without skew")
+	}
+}
+
+func TestNewSecretAndURI(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 { // 20 bytes in unpadded base32
+		t.Errorf("secret %q is %d characters, want 32", secret, len(secret))
+	}
+	if _, err := CodeAt(secret, 1); err != nil {
+		t.Errorf("new secret does not decode: %v", err)
+	}
+
+	u, err := url.Parse(URI("Ticket Master", "fan@example.com", secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Scheme != "otpauth" || u.Host != "totp" || q.Get("secret") != secret || q.Get("issuer") != "Ticket Master" ||
+		q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("URI = %s", u)
+	}
+	if u.Path != "/Ticket Master:fan@example.com" {
+		t.Errorf("label = %q", u.Path)
+	}
+}
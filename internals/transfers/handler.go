@@ -0,0 +1,222 @@
+package transfers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"ticketmaster/internals/bookings"
+	"ticketmaster/internals/cache"
+	"ticketmaster/internals/mailer"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/validation"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	repo       *Repository
+	redisStore *cache.RedisStore
+	mailer     mailer.Mailer
+	appURL     string
+}
+
+func NewHandler(repo *Repository, redisStore *cache.RedisStore, mail mailer.Mailer, appURL string) *Handler {
+	return &Handler{repo: repo, redisStore: redisStore, mailer: mail, appURL: appURL}
+}
+
+// CreateTransfer handles POST /bookings/{id}/transfer: offer the ticket to an email address.
+// The response is the same whether or not the address has an account.
+func (h *Handler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	bookingID, ok := idParam(w, r, "Invalid booking id")
+	if !ok {
+		return
+	}
+	var req TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Email = validation.NormalizeEmail(req.Email)
+	var errs validation.Errors
+	errs.Required("email", req.Email)
+	if !errs.Has("email") {
+		errs.Email("email", req.Email)
+	}
+	if errs.Respond(w) {
+		return
+	}
+
+	t, err := h.repo.Create(r.Context(), bookingID, userID, req.Email)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.notify(r.Context(), t.ToEmail, "A ticket is waiting for you",
+		fmt.Sprintf("%s wants to send you a ticket for %s. Sign in or create an account with this "+
+			"email address to accept or decline it here:\n\n%s", t.FromEmail, t.EventName, h.link(t)))
+	h.notify(r.Context(), t.FromEmail, "Your ticket transfer was sent",
+		fmt.Sprintf("We asked %s to accept your ticket for %s. It stays yours until they do, "+
+			"and you can cancel the transfer until then.", t.ToEmail, t.EventName))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+// GetTransfers handles GET /transfers: transfers the user sent or received
+func (h *Handler) GetTransfers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	list, err := h.repo.ListForUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch transfers", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// AcceptTransfer handles POST /transfers/{id}/accept (recipient only)
+func (h *Handler) AcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, h.accept, func(t *Transfer) {
+		h.notify(r.Context(), t.FromEmail, "Your ticket was transferred",
+			fmt.Sprintf("%s accepted your ticket for %s. Your copy of the ticket no longer works.", t.ToEmail, t.EventName))
+		h.notify(r.Context(), t.ToEmail, "You have a new ticket",
+			fmt.Sprintf("The ticket for %s from %s is now yours. Open it in your bookings to get your own QR code.",
+				t.EventName, t.FromEmail))
+	})
+}
+
+// DeclineTransfer handles POST /transfers/{id}/decline (recipient only)
+func (h *Handler) DeclineTransfer(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, h.repo.Decline, func(t *Transfer) {
+		h.notify(r.Context(), t.FromEmail, "Your ticket transfer was declined",
+			fmt.Sprintf("%s declined your ticket for %s. It is still yours.", t.ToEmail, t.EventName))
+		h.notify(r.Context(), t.ToEmail, "You declined a ticket",
+			fmt.Sprintf("You declined the ticket for %s from %s.", t.EventName, t.FromEmail))
+	})
+}
+
+// CancelTransfer handles POST /transfers/{id}/cancel (sender only)
+func (h *Handler) CancelTransfer(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, h.repo.Cancel, func(t *Transfer) {
+		h.notify(r.Context(), t.ToEmail, "A ticket transfer was cancelled",
+			fmt.Sprintf("%s cancelled the ticket for %s they had sent you.", t.FromEmail, t.EventName))
+		h.notify(r.Context(), t.FromEmail, "You cancelled a ticket transfer",
+			fmt.Sprintf("Your ticket for %s is no longer offered to %s.", t.EventName, t.ToEmail))
+	})
+}
+
+// accept moves one unit of the event's purchase counter from sender to recipient around
+// Repository.Accept: the recipient's is taken first, like a purchase would (seeded from
+// Postgres the first time), and given back if the transfer doesn't go through.
+func (h *Handler) accept(ctx context.Context, transferID, userID int32) (*Transfer, error) {
+	eventID, limit, err := h.repo.EventLimit(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if limit == nil {
+		return h.repo.Accept(ctx, transferID, userID)
+	}
+
+	countKey := bookings.PurchaseCountKey(eventID, userID)
+	exists, err := h.redisStore.CounterExists(ctx, countKey)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		held, err := h.repo.CountUserTickets(ctx, eventID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := h.redisStore.SeedCounter(ctx, countKey, held); err != nil {
+			return nil, err
+		}
+	}
+	if err := h.redisStore.ReserveCount(ctx, countKey, 1, int64(*limit)); err != nil {
+		if errors.Is(err, cache.ErrUserQuotaExhausted) {
+			return nil, bookings.ErrPurchaseLimitExceeded
+		}
+		return nil, err
+	}
+
+	t, err := h.repo.Accept(ctx, transferID, userID)
+	if err != nil {
+		h.redisStore.ReleaseCount(ctx, countKey, 1)
+		return nil, err
+	}
+	h.redisStore.ReleaseCount(ctx, bookings.PurchaseCountKey(eventID, t.FromUserID), 1)
+	return t, nil
+}
+
+func (h *Handler) respond(w http.ResponseWriter, r *http.Request,
+	action func(ctx context.Context, transferID, userID int32) (*Transfer, error), notify func(*Transfer)) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	transferID, ok := idParam(w, r, "Invalid transfer id")
+	if !ok {
+		return
+	}
+
+	t, err := action(r.Context(), transferID, userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	notify(t)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
+
+// notify emails one party. The transfer has already happened, so a mail that can't be sent is only logged.
+func (h *Handler) notify(ctx context.Context, to, subject, body string) {
+	if err := h.mailer.Send(ctx, mailer.Message{To: to, Subject: subject, Body: body}); err != nil {
+		log.Printf("failed to send transfer email to %s: %v", to, err)
+	}
+}
+
+func (h *Handler) link(t *Transfer) string {
+	return h.appURL + "/transfers/" + strconv.Itoa(int(t.ID))
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrBookingNotFound), errors.Is(err, ErrTransferNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrSelfTransfer):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrEmailNotVerified):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrBookingInactive), errors.Is(err, ErrAlreadyCheckedIn),
+		errors.Is(err, ErrTransferPending), errors.Is(err, ErrTransferNotPending),
+		errors.Is(err, bookings.ErrPurchaseLimitExceeded):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to transfer ticket", http.StatusInternalServerError)
+	}
+}
+
+func idParam(w http.ResponseWriter, r *http.Request, message string) (int32, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, message, http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
@@ -0,0 +1,128 @@
+package transfers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"ticketmaster/internals/bookings"
+	"ticketmaster/internals/cache"
+	"ticketmaster/internals/db/dbtest"
+	"ticketmaster/internals/mailer"
+	"ticketmaster/internals/middleware"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestCreateTransferDoesNotRevealAccounts(t *testing.T) {
+	db := dbtest.New(t)
+	h := NewHandler(NewRepository(db), nil, mailer.NewMemoryMailer(), "http://app.test")
+	router := chi.NewRouter()
+	router.Post("/bookings/{id}/transfer", h.CreateTransfer)
+
+	eventID := dbtest.ID(t, db, `INSERT INTO events (name, starts_at) VALUES ('Gig', NOW() + INTERVAL '1 day') RETURNING id`)
+	sender := dbtest.ID(t, db, `INSERT INTO users (email, password_hash) VALUES ('sender@example.com', 'x') RETURNING id`)
+	dbtest.Exec(t, db, `INSERT INTO users (email, password_hash) VALUES ('member@example.com', 'x')`)
+	book := func(number int) int32 {
+		seatID := dbtest.ID(t, db, `INSERT INTO seats (row_number, seat_number, price, event_id, section) VALUES ('A', $2, 40, $1, 'Floor') RETURNING id`, eventID, number)
+		return dbtest.ID(t, db, `
+			INSERT INTO bookings (seat_id, event_id, user_id, status, amount, currency)
+			VALUES ($1, $2, $3, 'confirmed', 4000, 'USD') RETURNING id`, seatID, eventID, sender)
+	}
+
+	send := func(bookingID int32, email string) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, "/bookings/"+strconv.Itoa(int(bookingID))+"/transfer",
+			strings.NewReader(`{"email": "`+email+`"}`))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, sender))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var body map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	memberStatus, member := send(book(1), "member@example.com")
+	unknownStatus, unknown := send(book(2), "nobody@example.com")
+	if memberStatus != http.StatusCreated || unknownStatus != http.StatusCreated {
+		t.Fatalf("status = %d for an account, %d for an unknown address; want 201 both", memberStatus, unknownStatus)
+	}
+	for _, field := range []string{"to_user_id", "to_email", "status"} {
+		if (field == "to_email") != (member[field] != unknown[field]) {
+			t.Errorf("%s = %v for an account, %v for an unknown address", field, member[field], unknown[field])
+		}
+	}
+	if member["to_user_id"] != nil {
+		t.Errorf("to_user_id = %v, want null until the recipient answers", member["to_user_id"])
+	}
+}
+
+func TestAcceptTransferRespectsTheRecipientsLimit(t *testing.T) {
+	db := dbtest.New(t)
+	mr := miniredis.RunT(t)
+	repo := NewRepository(db)
+	h := NewHandler(repo, cache.NewRedisStore(mr.Addr(), ""), mailer.NewMemoryMailer(), "http://app.test")
+	router := chi.NewRouter()
+	router.Post("/transfers/{id}/accept", h.AcceptTransfer)
+	ctx := context.Background()
+
+	// Two tickets per user; the recipient already bought one
+	eventID := dbtest.ID(t, db, `INSERT INTO events (name, starts_at, max_tickets_per_user) VALUES ('Gig', NOW() + INTERVAL '1 day', 2) RETURNING id`)
+	sender := dbtest.ID(t, db, `INSERT INTO users (email, password_hash, email_verified_at) VALUES ('sender@example.com', 'x', NOW()) RETURNING id`)
+	friend := dbtest.ID(t, db, `INSERT INTO users (email, password_hash, email_verified_at) VALUES ('friend@example.com', 'x', NOW()) RETURNING id`)
+	book := func(owner int32, number int) int32 {
+		seatID := dbtest.ID(t, db, `INSERT INTO seats (row_number, seat_number, price, event_id, section) VALUES ('A', $2, 40, $1, 'Floor') RETURNING id`, eventID, number)
+		return dbtest.ID(t, db, `
+			INSERT INTO bookings (seat_id, event_id, user_id, status, amount, currency)
+			VALUES ($1, $2, $3, 'confirmed', 4000, 'USD') RETURNING id`, seatID, eventID, owner)
+	}
+	book(friend, 1)
+	offer := func(number int) int32 {
+		tr, err := repo.Create(ctx, book(sender, number), sender, "friend@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tr.ID
+	}
+	accept := func(id int32) (int, string) {
+		req := httptest.NewRequest(http.MethodPost, "/transfers/"+strconv.Itoa(int(id))+"/accept", nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, friend))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+	senderKey := bookings.PurchaseCountKey(eventID, sender)
+	friendKey := bookings.PurchaseCountKey(eventID, friend)
+	first, second := offer(2), offer(3)
+	mr.Set(senderKey, "2")
+
+	if code, body := accept(first); code != http.StatusOK {
+		t.Fatalf("first transfer: %d %s", code, body)
+	}
+	if v, _ := mr.Get(friendKey); v != "2" {
+		t.Errorf("recipient counter = %s, want 2", v)
+	}
+	if v, _ := mr.Get(senderKey); v != "1" {
+		t.Errorf("sender counter = %s, want 1", v)
+	}
+
+	// At the limit in Redis
+	if code, _ := accept(second); code != http.StatusConflict {
+		t.Errorf("transfer past the limit: status %d, want 409", code)
+	}
+	if v, _ := mr.Get(friendKey); v != "2" {
+		t.Errorf("recipient counter = %s after a refused transfer, want 2", v)
+	}
+
+	// A counter that drifted low in Redis: Postgres still refuses, and the reservation is given back
+	mr.Set(friendKey, "0")
+	if code, _ := accept(second); code != http.StatusConflict {
+		t.Errorf("transfer past the limit in Postgres: status %d, want 409", code)
+	}
+	if v, _ := mr.Get(friendKey); v != "0" {
+		t.Errorf("recipient counter = %s, want the reservation given back", v)
+	}
+}
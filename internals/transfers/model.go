@@ -0,0 +1,29 @@
+package transfers
+
+import "time"
+
+// Transfer statuses
+const (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusDeclined  = "declined"
+	StatusCancelled = "cancelled"
+)
+
+type TransferRequest struct {
+	Email string `json:"email"` // The recipient's address; they need an account with it, verified, to accept
+}
+
+type Transfer struct {
+	ID          int32      `json:"id"`
+	BookingID   int32      `json:"booking_id"`
+	EventID     int32      `json:"event_id"`
+	EventName   string     `json:"event_name"`
+	FromUserID  int32      `json:"from_user_id"`
+	FromEmail   string     `json:"from_email"`
+	ToUserID    *int32     `json:"to_user_id"` // Set once the recipient has answered
+	ToEmail     string     `json:"to_email"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at"`
+}
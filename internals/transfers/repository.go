@@ -0,0 +1,311 @@
+package transfers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ticketmaster/internals/bookings"
+	database "ticketmaster/internals/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrBookingNotFound    = errors.New("booking not found")
+	ErrBookingInactive    = errors.New("booking is no longer valid")
+	ErrAlreadyCheckedIn   = errors.New("ticket has already been used to enter")
+	ErrEmailNotVerified   = errors.New("verify your email address before answering a transfer")
+	ErrSelfTransfer       = errors.New("cannot transfer a ticket to yourself")
+	ErrTransferPending    = errors.New("this ticket already has a transfer awaiting an answer")
+	ErrTransferNotFound   = errors.New("transfer not found")
+	ErrTransferNotPending = errors.New("transfer has already been answered or cancelled")
+)
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const transferSelect = `
+	SELECT tt.id, tt.booking_id, b.event_id, e.name AS event_name,
+	       tt.from_user_id, fu.email AS from_email, tt.to_user_id, tt.to_email,
+	       tt.status, tt.created_at, tt.responded_at
+	FROM ticket_transfers tt
+	JOIN bookings b ON b.id = tt.booking_id
+	JOIN events e ON e.id = b.event_id
+	JOIN users fu ON fu.id = tt.from_user_id`
+
+// Create offers the user's booking to the given (normalized) email address. Whether an
+// account has that address is not looked at, so the answer is the same either way; whoever
+// verifies it can accept. Nothing changes hands until then.
+func (r *Repository) Create(ctx context.Context, bookingID, userID int32, email string) (*Transfer, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. The booking must be the user's, still valid and not used yet
+	if err := checkBooking(ctx, tx, bookingID, userID); err != nil {
+		return nil, err
+	}
+
+	// 2. Not to the sender's own address
+	var own string
+	if err := tx.QueryRow(ctx, `SELECT email FROM users WHERE id = $1`, userID).Scan(&own); err != nil {
+		return nil, fmt.Errorf("failed to load sender: %w", err)
+	}
+	if email == own {
+		return nil, ErrSelfTransfer
+	}
+
+	var id int32
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ticket_transfers (booking_id, from_user_id, to_email) VALUES ($1, $2, $3) RETURNING id`,
+		bookingID, userID, email).Scan(&id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		return nil, ErrTransferPending
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transfer: %w", err)
+	}
+
+	t, err := getTransfer(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return t, nil
+}
+
+// EventLimit returns the event of the transfer's booking and its per-user ticket limit,
+// so the handler can check the recipient's purchase counter before accepting
+func (r *Repository) EventLimit(ctx context.Context, transferID int32) (int32, *int32, error) {
+	var eventID int32
+	var limit *int32
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT b.event_id, e.max_tickets_per_user
+		FROM ticket_transfers tt
+		JOIN bookings b ON b.id = tt.booking_id
+		JOIN events e ON e.id = b.event_id
+		WHERE tt.id = $1`, transferID).Scan(&eventID, &limit)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil, ErrTransferNotFound
+	}
+	return eventID, limit, err
+}
+
+// CountUserTickets seeds the shared purchase counter, see bookings.PurchaseCountKey
+func (r *Repository) CountUserTickets(ctx context.Context, eventID, userID int32) (int64, error) {
+	return bookings.CountUserTickets(ctx, r.db.Pool, eventID, userID)
+}
+
+// Accept hands the booking to the recipient. The sender's ticket is revoked in the same
+// transaction, so its QR code stops working at the door; the recipient is issued a fresh one.
+// A transferred ticket counts toward the recipient's per-user limit like a purchase.
+func (r *Repository) Accept(ctx context.Context, transferID, userID int32) (*Transfer, error) {
+	return r.respond(ctx, transferID, userID, true, func(tx pgx.Tx, t *Transfer) error {
+		// The booking may have been cancelled or used since the offer was made
+		if err := checkBooking(ctx, tx, t.BookingID, t.FromUserID); err != nil {
+			return err
+		}
+		if err := checkLimit(ctx, tx, t.BookingID, *t.ToUserID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE bookings SET user_id = $2 WHERE id = $1`, t.BookingID, *t.ToUserID); err != nil {
+			return fmt.Errorf("failed to transfer booking: %w", err)
+		}
+		_, err := tx.Exec(ctx, `
+			UPDATE tickets SET revoked_at = NOW() WHERE booking_id = $1 AND revoked_at IS NULL`, t.BookingID)
+		if err != nil {
+			return fmt.Errorf("failed to revoke ticket: %w", err)
+		}
+		return setStatus(ctx, tx, t.ID, StatusAccepted)
+	})
+}
+
+// Decline turns the offer down; the sender keeps the ticket
+func (r *Repository) Decline(ctx context.Context, transferID, userID int32) (*Transfer, error) {
+	return r.respond(ctx, transferID, userID, true, func(tx pgx.Tx, t *Transfer) error {
+		return setStatus(ctx, tx, t.ID, StatusDeclined)
+	})
+}
+
+// Cancel withdraws the offer before the recipient answers
+func (r *Repository) Cancel(ctx context.Context, transferID, userID int32) (*Transfer, error) {
+	return r.respond(ctx, transferID, userID, false, func(tx pgx.Tx, t *Transfer) error {
+		return setStatus(ctx, tx, t.ID, StatusCancelled)
+	})
+}
+
+// respond locks a pending transfer, checks the user is the recipient (or the sender, when
+// byRecipient is false), applies change and returns the transfer as it ends up. The recipient
+// is the account that has verified the address the transfer went to; answering records it.
+func (r *Repository) respond(ctx context.Context, transferID, userID int32, byRecipient bool, change func(pgx.Tx, *Transfer) error) (*Transfer, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var t Transfer
+	err = tx.QueryRow(ctx, `
+		SELECT id, booking_id, from_user_id, to_user_id, to_email, status
+		FROM ticket_transfers WHERE id = $1 FOR UPDATE`, transferID).
+		Scan(&t.ID, &t.BookingID, &t.FromUserID, &t.ToUserID, &t.ToEmail, &t.Status)
+	if err == pgx.ErrNoRows {
+		return nil, ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transfer: %w", err)
+	}
+	if byRecipient {
+		if err := checkRecipient(ctx, tx, &t, userID); err != nil {
+			return nil, err
+		}
+	} else if t.FromUserID != userID {
+		return nil, ErrTransferNotFound
+	}
+	if t.Status != StatusPending {
+		return nil, ErrTransferNotPending
+	}
+	if byRecipient {
+		if _, err := tx.Exec(ctx, `UPDATE ticket_transfers SET to_user_id = $2 WHERE id = $1`, t.ID, userID); err != nil {
+			return nil, fmt.Errorf("failed to update transfer: %w", err)
+		}
+		t.ToUserID = &userID
+	}
+
+	if err := change(tx, &t); err != nil {
+		return nil, err
+	}
+	updated, err := getTransfer(ctx, tx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return updated, nil
+}
+
+// ListForUser is the user's transfer history, sent and received, newest first. Offers still
+// waiting for an answer show up for the account that has verified their address.
+func (r *Repository) ListForUser(ctx context.Context, userID int32) ([]Transfer, error) {
+	rows, err := r.db.Pool.Query(ctx, transferSelect+`
+		WHERE tt.from_user_id = $1 OR tt.to_user_id = $1
+		   OR (tt.status = 'pending' AND tt.to_email = (
+		       SELECT email FROM users WHERE id = $1 AND email_verified_at IS NOT NULL AND deleted_at IS NULL))
+		ORDER BY tt.id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Transfer])
+}
+
+// checkRecipient makes sure the user may answer the transfer: the account it was answered by
+// before, or one that has verified the address it was sent to. Anyone else gets
+// ErrTransferNotFound, so transfer IDs can't be probed.
+func checkRecipient(ctx context.Context, tx pgx.Tx, t *Transfer, userID int32) error {
+	if t.ToUserID != nil {
+		if *t.ToUserID != userID {
+			return ErrTransferNotFound
+		}
+		return nil
+	}
+	var email string
+	var verified bool
+	err := tx.QueryRow(ctx, `
+		SELECT email, email_verified_at IS NOT NULL FROM users
+		WHERE id = $1 AND deleted_at IS NULL`, userID).Scan(&email, &verified)
+	if err == pgx.ErrNoRows || (err == nil && email != t.ToEmail) {
+		return ErrTransferNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load recipient: %w", err)
+	}
+	if !verified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// checkBooking locks the booking and makes sure the user may still hand it over
+func checkBooking(ctx context.Context, tx pgx.Tx, bookingID, userID int32) error {
+	var owner int32
+	var status string
+	var checkedIn bool
+	err := tx.QueryRow(ctx, `
+		SELECT user_id, status, EXISTS (SELECT 1 FROM checkins c WHERE c.booking_id = b.id)
+		FROM bookings b WHERE id = $1 FOR UPDATE`, bookingID).Scan(&owner, &status, &checkedIn)
+	if err == pgx.ErrNoRows || (err == nil && owner != userID) {
+		return ErrBookingNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load booking: %w", err)
+	}
+	if status != "confirmed" {
+		return ErrBookingInactive
+	}
+	if checkedIn {
+		return ErrAlreadyCheckedIn
+	}
+	return nil
+}
+
+// checkLimit makes sure the recipient has room for one more ticket to the booking's event.
+// It takes the advisory lock bookings take for the same user and event, so a purchase and
+// a transfer can't both slip under the limit.
+func checkLimit(ctx context.Context, tx pgx.Tx, bookingID, userID int32) error {
+	var eventID int32
+	var limit *int32
+	err := tx.QueryRow(ctx, `
+		SELECT b.event_id, e.max_tickets_per_user
+		FROM bookings b JOIN events e ON e.id = b.event_id
+		WHERE b.id = $1`, bookingID).Scan(&eventID, &limit)
+	if err != nil {
+		return fmt.Errorf("failed to load event: %w", err)
+	}
+	if limit == nil {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, eventID, userID); err != nil {
+		return fmt.Errorf("failed to lock purchase counter: %w", err)
+	}
+	held, err := bookings.CountUserTickets(ctx, tx, eventID, userID)
+	if err != nil {
+		return err
+	}
+	if held >= int64(*limit) {
+		return bookings.ErrPurchaseLimitExceeded
+	}
+	return nil
+}
+
+func setStatus(ctx context.Context, tx pgx.Tx, transferID int32, status string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE ticket_transfers SET status = $2, responded_at = NOW() WHERE id = $1`, transferID, status)
+	if err != nil {
+		return fmt.Errorf("failed to update transfer: %w", err)
+	}
+	return nil
+}
+
+func getTransfer(ctx context.Context, tx pgx.Tx, id int32) (*Transfer, error) {
+	rows, err := tx.Query(ctx, transferSelect+` WHERE tt.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	t, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[Transfer])
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transfer: %w", err)
+	}
+	return t, nil
+}
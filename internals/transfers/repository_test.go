@@ -0,0 +1,87 @@
+package transfers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"ticketmaster/internals/db/dbtest"
+	"ticketmaster/internals/tickets"
+)
+
+func TestAcceptRevokesTheSendersTicket(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+	repo := NewRepository(db)
+	signer, err := tickets.EphemeralSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticketRepo := tickets.NewRepository(db, signer)
+
+	eventID := dbtest.ID(t, db, `INSERT INTO events (name, starts_at) VALUES ('Gig', NOW() + INTERVAL '1 day') RETURNING id`)
+	sender := dbtest.ID(t, db, `INSERT INTO users (email, password_hash, email_verified_at) VALUES ('sender@example.com', 'x', NOW()) RETURNING id`)
+	unverified := dbtest.ID(t, db, `INSERT INTO users (email, password_hash) VALUES ('friend@example.com', 'x') RETURNING id`)
+	stranger := dbtest.ID(t, db, `INSERT INTO users (email, password_hash, email_verified_at) VALUES ('stranger@example.com', 'x', NOW()) RETURNING id`)
+	seatID := dbtest.ID(t, db, `INSERT INTO seats (row_number, seat_number, price, event_id, section) VALUES ('A', 1, 40, $1, 'Floor') RETURNING id`, eventID)
+	bookingID := dbtest.ID(t, db, `
+		INSERT INTO bookings (seat_id, event_id, user_id, status, amount, currency)
+		VALUES ($1, $2, $3, 'confirmed', 4000, 'USD') RETURNING id`, seatID, eventID, sender)
+	old, err := ticketRepo.ForBooking(ctx, bookingID, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Create(ctx, bookingID, sender, "sender@example.com"); !errors.Is(err, ErrSelfTransfer) {
+		t.Errorf("transfer to own address = %v, want ErrSelfTransfer", err)
+	}
+	tr, err := repo.Create(ctx, bookingID, sender, "friend@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.ToUserID != nil {
+		t.Errorf("pending transfer names recipient %d", *tr.ToUserID)
+	}
+	if _, err := repo.Create(ctx, bookingID, sender, "other@example.com"); !errors.Is(err, ErrTransferPending) {
+		t.Errorf("second transfer = %v, want ErrTransferPending", err)
+	}
+
+	// Only a verified owner of the address may answer
+	if _, err := repo.Accept(ctx, tr.ID, stranger); !errors.Is(err, ErrTransferNotFound) {
+		t.Errorf("accept by another account = %v, want ErrTransferNotFound", err)
+	}
+	if _, err := repo.Accept(ctx, tr.ID, unverified); !errors.Is(err, ErrEmailNotVerified) {
+		t.Errorf("accept before verifying = %v, want ErrEmailNotVerified", err)
+	}
+	if list, _ := repo.ListForUser(ctx, unverified); len(list) != 0 {
+		t.Errorf("unverified account sees %d offers, want none", len(list))
+	}
+	dbtest.Exec(t, db, `UPDATE users SET email_verified_at = NOW() WHERE id = $1`, unverified)
+	if list, _ := repo.ListForUser(ctx, unverified); len(list) != 1 {
+		t.Errorf("verified recipient sees %d offers, want 1", len(list))
+	}
+
+	accepted, err := repo.Accept(ctx, tr.ID, unverified)
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if accepted.Status != StatusAccepted || accepted.ToUserID == nil || *accepted.ToUserID != unverified {
+		t.Errorf("accepted transfer = %+v", accepted)
+	}
+
+	// The sender's ticket is void; the recipient gets a fresh one
+	var revoked bool
+	if err := db.Pool.QueryRow(ctx, `SELECT revoked_at IS NOT NULL FROM tickets WHERE id = $1`, old.ID).Scan(&revoked); err != nil || !revoked {
+		t.Errorf("sender's ticket revoked = %v (%v), want true", revoked, err)
+	}
+	if _, err := ticketRepo.ForBooking(ctx, bookingID, sender); !errors.Is(err, tickets.ErrBookingNotFound) {
+		t.Errorf("sender fetching the ticket = %v, want ErrBookingNotFound", err)
+	}
+	fresh, err := ticketRepo.ForBooking(ctx, bookingID, unverified)
+	if err != nil || fresh.ID == old.ID || fresh.HolderID != unverified {
+		t.Errorf("recipient's ticket = %+v, %v; want a new one held by %d", fresh, err, unverified)
+	}
+
+	if _, err := repo.Cancel(ctx, tr.ID, sender); !errors.Is(err, ErrTransferNotPending) {
+		t.Errorf("cancel after accept = %v, want ErrTransferNotPending", err)
+	}
+}
@@ -0,0 +1,104 @@
+package users
+
+import (
+	"context"
+	"log"
+	"strings"
+	"ticketmaster/internals/audit"
+	"ticketmaster/internals/validation"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Profile returns the logged-in user. Deleted accounts are gone as far as the API is concerned.
+func (s *Service) Profile(ctx context.Context, userID int) (*User, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// UpdateProfile changes name and phone. The email address is not editable here.
+func (s *Service) UpdateProfile(ctx context.Context, userID int, req ProfileUpdateRequest) (*User, error) {
+	var errs validation.Errors
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		req.Name = &name
+		errs.MaxLength("name", name, 200)
+	}
+	if req.Phone != nil {
+		phone := strings.TrimSpace(*req.Phone)
+		req.Phone = &phone
+		errs.Phone("phone", phone)
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateProfile(ctx, userID, req.Name, req.Phone)
+}
+
+// ExportData returns everything we hold about the user (GDPR art. 15 and 20) and audits the export
+func (s *Service) ExportData(ctx context.Context, userID int, ip string) (*DataExport, error) {
+	user, err := s.Profile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	export, err := s.repo.ExportData(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	s.recordAudit(ctx, audit.Entry{Action: audit.ActionDataExported, UserID: &user.ID, IP: ip})
+	return export, nil
+}
+
+// DeleteAccount anonymizes the user (GDPR art. 17). Bookings, refunds and ledger entries are
+// kept for accounting but no longer lead back to a person. The current access token is revoked;
+// other sessions lose their refresh tokens and die within accessTokenTTL.
+func (s *Service) DeleteAccount(ctx context.Context, userID int, req DeleteAccountRequest, jti string, expiresAt time.Time, ip string) error {
+	user, err := s.Profile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Re-authenticate: a stolen access token alone must not be able to destroy the account,
+	// nor guess its password or code any faster than a login could
+	if wait := s.loginLockedFor(ctx, user.Email, ip); wait > 0 {
+		return &LoginLockedError{RetryAfter: wait}
+	}
+	if user.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+			s.recordLoginFailure(ctx, user.Email, ip, &user.ID)
+			return ErrInvalidCredentials
+		}
+	}
+	mfa, err := s.MFAStatus(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if mfa.Enabled {
+		if err := s.verifySecondFactor(ctx, user, req.Code, ip); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.AnonymizeUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.redisStore.DenyToken(ctx, jti, time.Until(expiresAt)); err != nil {
+		log.Printf("failed to revoke access token of deleted user %d: %v", userID, err)
+	}
+	s.recordAudit(ctx, audit.Entry{Action: audit.ActionAccountDeleted, UserID: &user.ID, IP: ip})
+	return nil
+}
+
+// recordAudit writes an audit entry; a failure is logged, it never fails the user's request
+func (s *Service) recordAudit(ctx context.Context, entry audit.Entry) {
+	if err := s.audit.Record(ctx, entry); err != nil {
+		log.Printf("failed to record audit entry %s: %v", entry.Action, err)
+	}
+}
@@ -0,0 +1,115 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"ticketmaster/internals/db/dbtest"
+)
+
+func TestAnonymizeUserCancelsPendingTransfers(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+
+	eventID := dbtest.ID(t, db, `INSERT INTO events (name, starts_at) VALUES ('Gig', NOW() + INTERVAL '1 day') RETURNING id`)
+	leaving := dbtest.ID(t, db, `INSERT INTO users (email, password_hash) VALUES ('leaving@example.com', 'x') RETURNING id`)
+	friend := dbtest.ID(t, db, `INSERT INTO users (email, password_hash) VALUES ('friend@example.com', 'x') RETURNING id`)
+	booking := func(owner int32, number int) int32 {
+		seatID := dbtest.ID(t, db, `INSERT INTO seats (row_number, seat_number, price, event_id, section) VALUES ('A', $2, 40, $1, 'Floor') RETURNING id`, eventID, number)
+		return dbtest.ID(t, db, `
+			INSERT INTO bookings (seat_id, event_id, user_id, status, amount, currency)
+			VALUES ($1, $2, $3, 'confirmed', 4000, 'USD') RETURNING id`, seatID, eventID, owner)
+	}
+	transfer := func(bookingID, from int32, to string) int32 {
+		return dbtest.ID(t, db, `INSERT INTO ticket_transfers (booking_id, from_user_id, to_email) VALUES ($1, $2, $3) RETURNING id`, bookingID, from, to)
+	}
+	sent := transfer(booking(leaving, 1), leaving, "friend@example.com")
+	received := transfer(booking(friend, 2), friend, "leaving@example.com")
+	unrelated := transfer(booking(friend, 3), friend, "someone@example.com")
+
+	if err := NewRepository(db).AnonymizeUser(ctx, int(leaving)); err != nil {
+		t.Fatalf("AnonymizeUser: %v", err)
+	}
+
+	status := func(id int32) string {
+		var s string
+		if err := db.Pool.QueryRow(ctx, `SELECT status FROM ticket_transfers WHERE id = $1`, id).Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	if s := status(sent); s != "cancelled" {
+		t.Errorf("transfer sent by the deleted user is %s, want cancelled", s)
+	}
+	if s := status(received); s != "cancelled" {
+		t.Errorf("transfer sent to the deleted user is %s, want cancelled", s)
+	}
+	if s := status(unrelated); s != "pending" {
+		t.Errorf("unrelated transfer is %s, want pending", s)
+	}
+}
+
+func TestAnonymizeUserScrubsCopiesOfTheAddress(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+
+	eventID := dbtest.ID(t, db, `INSERT INTO events (name, starts_at) VALUES ('Gig', NOW() + INTERVAL '1 day') RETURNING id`)
+	leaving := dbtest.ID(t, db, `INSERT INTO users (email, password_hash) VALUES ('leaving@example.com', 'x') RETURNING id`)
+	friend := dbtest.ID(t, db, `INSERT INTO users (email, password_hash) VALUES ('friend@example.com', 'x') RETURNING id`)
+	booking := func(owner int32, number int) int32 {
+		seatID := dbtest.ID(t, db, `INSERT INTO seats (row_number, seat_number, price, event_id, section) VALUES ('A', $2, 40, $1, 'Floor') RETURNING id`, eventID, number)
+		return dbtest.ID(t, db, `
+			INSERT INTO bookings (seat_id, event_id, user_id, status, amount, currency)
+			VALUES ($1, $2, $3, 'confirmed', 4000, 'USD') RETURNING id`, seatID, eventID, owner)
+	}
+	accepted := dbtest.ID(t, db, `
+		INSERT INTO ticket_transfers (booking_id, from_user_id, to_user_id, to_email, status)
+		VALUES ($1, $2, $3, 'leaving@example.com', 'accepted') RETURNING id`, booking(leaving, 1), friend, leaving)
+	declined := dbtest.ID(t, db, `
+		INSERT INTO ticket_transfers (booking_id, from_user_id, to_email, status)
+		VALUES ($1, $2, 'stranger@example.com', 'declined') RETURNING id`, booking(leaving, 2), leaving)
+	given := dbtest.ID(t, db, `
+		INSERT INTO ticket_transfers (booking_id, from_user_id, to_user_id, to_email, status)
+		VALUES ($1, $2, $3, 'friend@example.com', 'accepted') RETURNING id`, booking(friend, 3), leaving, friend)
+
+	// A lockout from before the account existed, one after, and someone else's
+	dbtest.Exec(t, db, `INSERT INTO audit_log (action, subject, ip) VALUES ('login.lockout', 'leaving@example.com', '192.0.2.1')`)
+	dbtest.Exec(t, db, `INSERT INTO audit_log (action, user_id, subject, ip) VALUES ('login.lockout', $1, 'leaving@example.com', '192.0.2.1')`, leaving)
+	dbtest.Exec(t, db, `INSERT INTO audit_log (action, user_id, subject, ip) VALUES ('login.lockout', $1, 'friend@example.com', '192.0.2.1')`, friend)
+
+	if err := NewRepository(db).AnonymizeUser(ctx, int(leaving)); err != nil {
+		t.Fatalf("AnonymizeUser: %v", err)
+	}
+
+	var leftover int
+	if err := db.Pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM audit_log WHERE subject = 'leaving@example.com')
+		     + (SELECT COUNT(*) FROM ticket_transfers WHERE to_email IN ('leaving@example.com', 'stranger@example.com'))`).Scan(&leftover); err != nil {
+		t.Fatal(err)
+	}
+	if leftover != 0 {
+		t.Errorf("%d copies of the addresses left", leftover)
+	}
+
+	toEmail := func(id int32) string {
+		var email string
+		if err := db.Pool.QueryRow(ctx, `SELECT to_email FROM ticket_transfers WHERE id = $1`, id).Scan(&email); err != nil {
+			t.Fatal(err)
+		}
+		return email
+	}
+	if got, want := toEmail(accepted), fmt.Sprintf("deleted-%d@deleted.invalid", leaving); got != want {
+		t.Errorf("transfer to the deleted user has %s, want %s", got, want)
+	}
+	if got, want := toEmail(declined), fmt.Sprintf("transfer-%d@deleted.invalid", declined); got != want {
+		t.Errorf("unanswered offer has %s, want %s", got, want)
+	}
+	if got := toEmail(given); got != "friend@example.com" {
+		t.Errorf("ticket the friend accepted now shows %s", got)
+	}
+	var friendLockouts int
+	db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM audit_log WHERE subject = 'friend@example.com'`).Scan(&friendLockouts)
+	if friendLockouts != 1 {
+		t.Errorf("another user's audit entries changed")
+	}
+}
@@ -2,7 +2,18 @@ package users
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"ticketmaster/internals/cache"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/oidc"
+	"ticketmaster/internals/validation"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
@@ -21,12 +32,19 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.service.Register(r.Context(), req); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		var verr *validation.Error
+		switch {
+		case errors.As(err, &verr):
+			validation.WriteError(w, verr)
+		default:
+			http.Error(w, "Failed to register user", http.StatusInternalServerError)
+		}
 		return
 	}
 
+	// The same answer whether the address was new or already had an account
 	w.WriteHeader(http.StatusCreated)
-	w.Write([]byte(`{"message": "User registered successfully"}`))
+	w.Write([]byte(`{"message": "Check your email to finish signing up"}`))
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
@@ -36,12 +54,516 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.service.Login(r.Context(), req)
+	resp, err := h.service.Login(r.Context(), req, clientIP(r))
 	if err != nil {
+		var locked *LoginLockedError
+		switch {
+		case errors.As(err, &locked):
+			w.Header().Set("Retry-After", strconv.Itoa(int(locked.RetryAfter.Seconds())+1))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		case errors.Is(err, ErrInvalidCredentials):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		default:
+			http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Refresh handles POST /token/refresh
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Logout handles POST /logout. The refresh token in the body is optional.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	jti, _ := r.Context().Value(middleware.TokenIDKey).(string)
+	expiresAt, _ := r.Context().Value(middleware.TokenExpiryKey).(time.Time)
+	if !ok || jti == "" {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.service.Logout(r.Context(), int(userID), jti, expiresAt, req.RefreshToken); err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail handles POST /verify-email
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.VerifyEmail(r.Context(), req.Token); err != nil {
+		if errors.Is(err, ErrInvalidUserToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "Email verified"}`))
+}
+
+// ResendVerification handles POST /verify-email/resend
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.ResendVerification(r.Context(), int(userID)); err != nil {
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ForgotPassword handles POST /password/forgot. It always answers 202, account or not.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ForgotPassword(r.Context(), req.Email); err != nil {
+		http.Error(w, "Failed to send reset email", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword handles POST /password/reset
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ResetPassword(r.Context(), req); err != nil {
+		var verr *validation.Error
+		if errors.As(err, &verr) {
+			validation.WriteError(w, verr)
+			return
+		}
+		if errors.Is(err, ErrInvalidUserToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "Password updated, please log in again"}`))
+}
+
+// LoginMFA handles POST /login/mfa, the second step for accounts with MFA
+func (h *Handler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.CompleteMFALogin(r.Context(), req, clientIP(r))
+	if err != nil {
+		var locked *LoginLockedError
+		if errors.As(err, &locked) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(locked.RetryAfter.Seconds())+1))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		writeMFAError(w, err, "Failed to log in")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetMFA handles GET /mfa
+func (h *Handler) GetMFA(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.service.MFAStatus(r.Context(), int(userID))
+	if err != nil {
+		http.Error(w, "Failed to load MFA status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// EnrollTOTP handles POST /mfa/totp/enroll
+func (h *Handler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := h.service.EnrollTOTP(r.Context(), int(userID))
+	if err != nil {
+		writeMFAError(w, err, "Failed to start enrollment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// ConfirmTOTP handles POST /mfa/totp/confirm and returns the recovery codes
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	h.withMFACode(w, r, func(userID int, code string) {
+		codes, err := h.service.ConfirmTOTP(r.Context(), userID, code)
+		if err != nil {
+			writeMFAError(w, err, "Failed to enable MFA")
+			return
+		}
+		writeRecoveryCodes(w, codes)
+	})
+}
+
+// RegenerateRecoveryCodes handles POST /mfa/recovery-codes
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	h.withMFACode(w, r, func(userID int, code string) {
+		codes, err := h.service.RegenerateRecoveryCodes(r.Context(), userID, code, clientIP(r))
+		if err != nil {
+			writeMFAError(w, err, "Failed to regenerate recovery codes")
+			return
+		}
+		writeRecoveryCodes(w, codes)
+	})
+}
+
+// DisableMFA handles POST /mfa/totp/disable
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	h.withMFACode(w, r, func(userID int, code string) {
+		if err := h.service.DisableMFA(r.Context(), userID, code, clientIP(r)); err != nil {
+			writeMFAError(w, err, "Failed to disable MFA")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// withMFACode reads the caller and the {"code": ...} body shared by the MFA management endpoints
+func (h *Handler) withMFACode(w http.ResponseWriter, r *http.Request, next func(userID int, code string)) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+	next(int(userID), req.Code)
+}
+
+func writeRecoveryCodes(w http.ResponseWriter, codes []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func writeMFAError(w http.ResponseWriter, err error, fallback string) {
+	var locked *LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		w.Header().Set("Retry-After", strconv.Itoa(int(locked.RetryAfter.Seconds())+1))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, ErrInvalidMFACode):
 		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrMFANotEnrolled), errors.Is(err, ErrMFAAlreadyEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// oidcCookie carries the flow's browser binding from start to callback. __Host- keeps
+// sibling subdomains from planting one; Lax still sends it on the provider's redirect back.
+const oidcCookie = "__Host-oidc_state"
+
+func setOIDCCookie(w http.ResponseWriter, binding string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    binding,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// OIDCLogin handles GET /auth/oidc/{provider}/login by redirecting to the provider
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	url, binding, err := h.service.StartOIDC(r.Context(), chi.URLParam(r, "provider"), 0)
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+	setOIDCCookie(w, binding, int(oidcStateTTL.Seconds()))
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// OIDCLink handles GET /auth/oidc/{provider}/link. It needs the bearer token,
+// so it answers with the URL instead of redirecting.
+func (h *Handler) OIDCLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	url, binding, err := h.service.StartOIDC(r.Context(), chi.URLParam(r, "provider"), int(userID))
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+	setOIDCCookie(w, binding, int(oidcStateTTL.Seconds()))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(OIDCLinkResponse{AuthorizationURL: url})
+}
+
+// OIDCCallback handles GET /auth/oidc/{provider}/callback, where the provider sends the browser back
+// after a sign-in
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	h.finishOIDC(w, r, 0)
+}
+
+// OIDCLinkCallback handles GET /auth/oidc/{provider}/link/callback. A link only completes with
+// the bearer token of the user who started it, so the client forwards the provider's code here.
+func (h *Handler) OIDCLinkCallback(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	h.finishOIDC(w, r, int(userID))
+}
+
+func (h *Handler) finishOIDC(w http.ResponseWriter, r *http.Request, sessionUserID int) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "Sign-in was not completed: "+e, http.StatusUnauthorized)
+		return
+	}
+	if q.Get("code") == "" || q.Get("state") == "" {
+		http.Error(w, "Missing code or state", http.StatusBadRequest)
+		return
+	}
+
+	var binding string
+	if c, err := r.Cookie(oidcCookie); err == nil {
+		binding = c.Value
+	}
+	setOIDCCookie(w, "", -1) // One flow per cookie, whatever the outcome
+
+	resp, err := h.service.FinishOIDC(r.Context(), chi.URLParam(r, "provider"), q.Get("code"), q.Get("state"), binding, sessionUserID)
+	if err != nil {
+		writeOIDCError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeOIDCError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUnknownProvider):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, cache.ErrOIDCStateInvalid), errors.Is(err, ErrOIDCWrongBrowser):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrOIDCLinkSession):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, oidc.ErrInvalidIDToken):
+		http.Error(w, "The provider's response could not be verified", http.StatusUnauthorized)
+	case errors.Is(err, ErrIdentityConflict), errors.Is(err, ErrOIDCEmailUnverified):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrOIDCEmailRequired):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		log.Printf("oidc sign-in failed: %v", err)
+		http.Error(w, "Failed to sign in with provider", http.StatusBadGateway)
+	}
+}
+
+// GetMe handles GET /me
+func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.service.Profile(r.Context(), int(userID))
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(AuthResponse{Token: token})
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateMe handles PATCH /me
+func (h *Handler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var req ProfileUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.UpdateProfile(r.Context(), int(userID), req)
+	if err != nil {
+		var verr *validation.Error
+		switch {
+		case errors.As(err, &verr):
+			validation.WriteError(w, verr)
+		case errors.Is(err, ErrUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// ExportMe handles GET /me/export, served as a download
+func (h *Handler) ExportMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.service.ExportData(r.Context(), int(userID), clientIP(r))
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to export data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="my-data.json"`)
+	w.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(export)
+}
+
+// DeleteMe handles DELETE /me
+func (h *Handler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	jti, _ := r.Context().Value(middleware.TokenIDKey).(string)
+	expiresAt, _ := r.Context().Value(middleware.TokenExpiryKey).(time.Time)
+	if !ok || jti == "" {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteAccount(r.Context(), int(userID), req, jti, expiresAt, clientIP(r)); err != nil {
+		var locked *LoginLockedError
+		switch {
+		case errors.As(err, &locked):
+			w.Header().Set("Retry-After", strconv.Itoa(int(locked.RetryAfter.Seconds())+1))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		case errors.Is(err, ErrInvalidCredentials), errors.Is(err, ErrInvalidMFACode):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, ErrUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientIP is the caller's address without the port. Behind a trusted proxy, RemoteAddr
+// has already been replaced with X-Real-IP by the RealIP middleware.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
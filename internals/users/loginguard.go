@@ -0,0 +1,101 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"ticketmaster/internals/audit"
+	"ticketmaster/internals/cache"
+	"time"
+)
+
+// Failed logins are throttled per account and per client IP. The IP budget is larger
+// because many users can share one address (offices, mobile carriers).
+var (
+	accountBackoff = cache.LoginBackoff{Window: 15 * time.Minute, FreeAttempts: 5, BaseDelay: 2 * time.Second, MaxDelay: 15 * time.Minute}
+	ipBackoff      = cache.LoginBackoff{Window: 15 * time.Minute, FreeAttempts: 20, BaseDelay: time.Second, MaxDelay: 15 * time.Minute}
+)
+
+const (
+	scopeAccount = "account"
+	scopeIP      = "ip"
+)
+
+// LoginLockedError is returned while an account or IP has to wait before trying again
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts; try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// loginLockedFor checks both scopes before we spend a bcrypt comparison on the attempt.
+// The guard fails open: if Redis is down, logins still work, they are just not throttled.
+func (s *Service) loginLockedFor(ctx context.Context, email, ip string) time.Duration {
+	wait, err := s.redisStore.LoginLockedFor(ctx, map[string]string{scopeAccount: email, scopeIP: ip})
+	if err != nil {
+		log.Printf("login guard unavailable: %v", err)
+		return 0
+	}
+	return wait
+}
+
+// recordLoginFailure counts the failure in both scopes and audits any lockout it causes.
+// userID is nil when the email has no account; it is counted all the same so the
+// response doesn't reveal which emails exist.
+func (s *Service) recordLoginFailure(ctx context.Context, email, ip string, userID *int) {
+	for _, scope := range []struct {
+		name, id string
+		backoff  cache.LoginBackoff
+	}{
+		{scopeAccount, email, accountBackoff},
+		{scopeIP, ip, ipBackoff},
+	} {
+		failures, delay, err := s.redisStore.RecordLoginFailure(ctx, scope.name, scope.id, scope.backoff)
+		if err != nil {
+			log.Printf("login guard unavailable: %v", err)
+			return
+		}
+		if delay < scope.backoff.MaxDelay {
+			continue
+		}
+
+		entry := audit.Entry{
+			Action:  audit.ActionLoginLockout,
+			Subject: scope.id,
+			IP:      ip,
+			Details: map[string]any{"scope": scope.name, "failures": failures, "locked_seconds": int64(delay.Seconds())},
+		}
+		if scope.name == scopeAccount {
+			entry.UserID = userID
+		}
+		if err := s.audit.Record(ctx, entry); err != nil {
+			log.Printf("failed to audit login lockout of %s %s: %v", scope.name, scope.id, err)
+		}
+	}
+}
+
+// clearLoginFailures resets the account after a successful login. The IP counter is left
+// alone, otherwise an attacker could reset it by logging into an account of their own.
+func (s *Service) clearLoginFailures(ctx context.Context, email string) {
+	if err := s.redisStore.ClearLoginFailures(ctx, scopeAccount, email); err != nil {
+		log.Printf("login guard unavailable: %v", err)
+	}
+}
+
+// verifySecondFactor checks a signed-in user's TOTP or recovery code under the same budget as
+// a login, so a stolen access token can't be used to guess its way past the second factor
+func (s *Service) verifySecondFactor(ctx context.Context, user *User, code, ip string) error {
+	if wait := s.loginLockedFor(ctx, user.Email, ip); wait > 0 {
+		return &LoginLockedError{RetryAfter: wait}
+	}
+	if err := s.checkSecondFactor(ctx, user.ID, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.recordLoginFailure(ctx, user.Email, ip, &user.ID)
+		}
+		return err
+	}
+	return nil
+}
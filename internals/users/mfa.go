@@ -0,0 +1,204 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"ticketmaster/internals/cache"
+	"ticketmaster/internals/totp"
+	"time"
+)
+
+const (
+	// Name shown next to the account in authenticator apps
+	mfaIssuer = "Ticketmaster"
+
+	// The window between password and code: long enough to find the phone, short enough to be useless if leaked
+	mfaChallengeTTL      = 5 * time.Minute
+	mfaChallengeAttempts = 5
+
+	// Accept the previous and next 30s step too, for clock drift and slow typing
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+// EnrollTOTP starts enrollment with a new secret. MFA stays off until ConfirmTOTP.
+func (s *Service) EnrollTOTP(ctx context.Context, userID int) (*TOTPEnrollment, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SavePendingTOTP(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollment{Secret: secret, URI: totp.URI(mfaIssuer, user.Email, secret)}, nil
+}
+
+// ConfirmTOTP turns MFA on once the user shows a valid code from their app,
+// and returns the recovery codes. They are never shown again.
+func (s *Service) ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	mfa, err := s.repo.GetMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	step, ok := totp.Validate(mfa.TOTPSecret, normalizeMFACode(code), time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableMFA(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// MFAStatus reports whether MFA is on and how many recovery codes are left
+func (s *Service) MFAStatus(ctx context.Context, userID int) (*MFAStatus, error) {
+	mfa, err := s.repo.GetMFA(ctx, userID)
+	if errors.Is(err, ErrMFANotEnrolled) {
+		return &MFAStatus{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mfa.EnabledAt == nil {
+		return &MFAStatus{}, nil
+	}
+	left, err := s.repo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAStatus{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes; it needs a current code
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int, code, ip string) ([]string, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, user, code, ip); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableMFA turns MFA off; it needs a current code so a hijacked session can't strip it
+func (s *Service) DisableMFA(ctx context.Context, userID int, code, ip string) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifySecondFactor(ctx, user, code, ip); err != nil {
+		return err
+	}
+	return s.repo.DisableMFA(ctx, userID)
+}
+
+// CompleteMFALogin is the second login step: challenge token plus a TOTP or recovery code
+func (s *Service) CompleteMFALogin(ctx context.Context, req MFALoginRequest, ip string) (*AuthResponse, error) {
+	challenge := hashToken(req.MFAToken)
+	userID, err := s.redisStore.MFAChallengeUser(ctx, challenge, mfaChallengeAttempts)
+	if err != nil {
+		if errors.Is(err, cache.ErrChallengeInvalid) {
+			return nil, ErrInvalidMFACode
+		}
+		return nil, err
+	}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrong codes count against the same budget as wrong passwords
+	if err := s.verifySecondFactor(ctx, user, req.Code, ip); err != nil {
+		return nil, err
+	}
+
+	if err := s.redisStore.DeleteMFAChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+	s.clearLoginFailures(ctx, user.Email)
+	return s.startSession(ctx, userID)
+}
+
+// mfaChallenge is the first-step response for accounts with MFA: an opaque token, no JWT
+func (s *Service) mfaChallenge(ctx context.Context, userID int) (*AuthResponse, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redisStore.StoreMFAChallenge(ctx, hashToken(token), userID, mfaChallengeTTL); err != nil {
+		return nil, err
+	}
+	return &AuthResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int64(mfaChallengeTTL.Seconds()),
+	}, nil
+}
+
+// checkSecondFactor accepts a 6-digit TOTP code or, failing that shape, a recovery code
+func (s *Service) checkSecondFactor(ctx context.Context, userID int, code string) error {
+	mfa, err := s.repo.GetMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if mfa.EnabledAt == nil {
+		return ErrMFANotEnrolled
+	}
+
+	code = normalizeMFACode(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(mfa.TOTPSecret, code, time.Now(), totpSkew)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		return s.repo.UseTOTPStep(ctx, userID, step)
+	}
+	return s.repo.UseRecoveryCode(ctx, userID, hashToken(code))
+}
+
+// normalizeMFACode drops the spaces and dashes people type, and lower-cases recovery codes
+func normalizeMFACode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns codes formatted for humans ("abcde-fghij") and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7) // 56 bits, 10 base32 characters after trimming
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
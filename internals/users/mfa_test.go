@@ -0,0 +1,50 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"ticketmaster/internals/db/dbtest"
+	"ticketmaster/internals/totp"
+	"time"
+)
+
+func TestMFAManagementIsThrottled(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	userID := int(dbtest.ID(t, s.db, `INSERT INTO users (email, password_hash, email_verified_at) VALUES ('mfa@example.com', 'x', NOW()) RETURNING id`))
+
+	enrollment, err := s.EnrollTOTP(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := totp.CodeAt(enrollment.Secret, totp.Counter(time.Now()))
+	recovery, err := s.ConfirmTOTP(ctx, userID, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A stolen access token guessing codes runs into the login budget
+	var locked *LoginLockedError
+	for i := 0; i < 10 && locked == nil; i++ {
+		err = s.DisableMFA(ctx, userID, "000000", "198.51.100.7")
+		if !errors.Is(err, ErrInvalidMFACode) && !errors.As(err, &locked) {
+			t.Fatalf("guess %d = %v", i+1, err)
+		}
+	}
+	if locked == nil {
+		t.Fatal("ten wrong codes and no lockout")
+	}
+	if _, err := s.RegenerateRecoveryCodes(ctx, userID, recovery[0], "198.51.100.7"); !errors.As(err, &locked) {
+		t.Errorf("right code while locked = %v, want LoginLockedError", err)
+	}
+	if status, _ := s.MFAStatus(ctx, userID); !status.Enabled {
+		t.Fatal("MFA was turned off")
+	}
+
+	// Once the delay has passed the right code works again
+	s.redis.FastForward(locked.RetryAfter + time.Second)
+	if err := s.DisableMFA(ctx, userID, recovery[0], "198.51.100.7"); err != nil {
+		t.Fatalf("DisableMFA after the delay: %v", err)
+	}
+}
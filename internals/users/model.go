@@ -4,10 +4,14 @@ import "time"
 
 // User represents the database entity
 type User struct {
-	ID           int       `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"` // Never export this
-	CreatedAt    time.Time `json:"created_at"`
+	ID              int        `json:"id"`
+	Email           string     `json:"email"`
+	Name            string     `json:"name"`
+	Phone           *string    `json:"phone"`
+	PasswordHash    string     `json:"-"` // Never export this
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	DeletedAt       *time.Time `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
 }
 
 // RegisterRequest defines the payload for registration
@@ -22,7 +26,163 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
-// AuthResponse is what we send back on successful login
+// AuthResponse is what we send back on successful login or refresh.
+// Token is the short-lived access token; RefreshToken gets a new pair from POST /token/refresh.
+// When the account has MFA, login instead returns only MFARequired and MFAToken,
+// which is exchanged together with a code at POST /login/mfa.
 type AuthResponse struct {
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in"` // Seconds until Token (or MFAToken) expires
+}
+
+// RefreshToken is a stored (hashed) refresh token
+type RefreshToken struct {
+	ID         int64
+	UserID     int
+	FamilyID   string
+	TokenHash  string
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	ReplacedBy *int64
+	CreatedAt  time.Time
+}
+
+// RefreshRequest is the payload for POST /token/refresh and POST /logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Purposes of the single-use tokens we email out
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+// VerifyEmailRequest is the payload for POST /verify-email
+type VerifyEmailRequest struct {
 	Token string `json:"token"`
 }
+
+// ForgotPasswordRequest is the payload for POST /password/forgot
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest is the payload for POST /password/reset
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// MFA is a user's TOTP second factor. It only guards login once EnabledAt is set.
+type MFA struct {
+	UserID       int
+	TOTPSecret   string
+	EnabledAt    *time.Time
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// TOTPEnrollment is returned by POST /mfa/totp/enroll; the app scans URI as a QR code
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// MFACodeRequest carries a TOTP code or a recovery code
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// MFALoginRequest is the payload for POST /login/mfa
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// RecoveryCodesResponse shows freshly issued recovery codes, the only time they are visible
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAStatus is returned by GET /mfa
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// Identity is an external OpenID Connect account linked to a user
+type Identity struct {
+	Provider string
+	Subject  string
+	Email    string
+}
+
+// OIDCLinkResponse is returned by GET /auth/oidc/{provider}/link; the client navigates to the URL
+type OIDCLinkResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// ProfileUpdateRequest is the payload for PATCH /me. Omitted fields are left alone;
+// an empty phone clears it.
+type ProfileUpdateRequest struct {
+	Name  *string `json:"name"`
+	Phone *string `json:"phone"`
+}
+
+// DeleteAccountRequest confirms DELETE /me. Password is required if the account has one,
+// Code if it has MFA.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// DataExport is everything we hold about a user, returned by GET /me/export
+type DataExport struct {
+	ExportedAt time.Time          `json:"exported_at"`
+	Profile    User               `json:"profile"`
+	Identities []ExportedIdentity `json:"linked_identities"`
+	Bookings   []ExportedBooking  `json:"bookings"`
+	Payments   []ExportedPayment  `json:"payments"`
+}
+
+type ExportedIdentity struct {
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+type ExportedBooking struct {
+	ID            int32      `json:"id"`
+	EventName     *string    `json:"event_name"`
+	EventStartsAt *time.Time `json:"event_starts_at"`
+	Section       *string    `json:"section"`
+	RowNumber     *string    `json:"row_number"`
+	SeatNumber    *int32     `json:"seat_number"`
+	TicketType    *string    `json:"ticket_type"` // General admission bookings have this instead of a seat
+	Status        string     `json:"status"`
+	Currency      string     `json:"currency"`
+	FaceValue     int64      `json:"face_value"`
+	Discount      int64      `json:"discount"`
+	ServiceFee    int64      `json:"service_fee"`
+	FacilityFee   int64      `json:"facility_fee"`
+	Tax           int64      `json:"tax"`
+	Amount        int64      `json:"amount"`
+	PromoCode     *string    `json:"promo_code"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ExportedPayment is money that moved: the charge for a booking, or a refund of it
+type ExportedPayment struct {
+	Type        string    `json:"type"` // 'charge' or 'refund'
+	BookingID   int32     `json:"booking_id"`
+	Amount      int64     `json:"amount"`
+	Currency    string    `json:"currency"`
+	Status      string    `json:"status"`
+	ProviderRef *string   `json:"provider_ref"`
+	CreatedAt   time.Time `json:"created_at"`
+}
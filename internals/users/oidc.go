@@ -0,0 +1,171 @@
+package users
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"ticketmaster/internals/oidc"
+	"ticketmaster/internals/validation"
+	"time"
+)
+
+// How long the user has at the provider's sign-in page before the state expires
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownProvider     = errors.New("unknown sign-in provider")
+	ErrOIDCEmailRequired   = errors.New("the provider did not share an email address")
+	ErrOIDCEmailUnverified = errors.New("an account with this email already exists; log in with your password and link this provider from your account")
+	ErrOIDCWrongBrowser    = errors.New("sign-in was started in another browser; please start again")
+	ErrOIDCLinkSession     = errors.New("finish linking while signed in to the account that started it")
+)
+
+// oidcState is what we remember between sending the browser away and it coming back
+type oidcState struct {
+	Provider   string `json:"provider"`
+	Nonce      string `json:"nonce"`
+	Verifier   string `json:"verifier"`
+	LinkUserID int    `json:"link_user_id,omitempty"` // Set when a logged-in user is adding a provider
+}
+
+// oidcBinding ties a flow to the browser that started it: the handler keeps it in a cookie,
+// so a victim sent someone else's authorization URL arrives at the callback without it
+func oidcBinding(state, nonce string) string {
+	return hashToken(state + "." + nonce)
+}
+
+// StartOIDC returns the provider URL to send the browser to, and the binding the browser must
+// present at the callback. linkUserID is 0 for a sign-in, or the logged-in user who wants this
+// provider linked to their account.
+func (s *Service) StartOIDC(ctx context.Context, providerName string, linkUserID int) (authURL, binding string, err error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	payload, err := json.Marshal(oidcState{Provider: providerName, Nonce: nonce, Verifier: verifier, LinkUserID: linkUserID})
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.redisStore.SaveOIDCState(ctx, hashToken(state), payload, oidcStateTTL); err != nil {
+		return "", "", err
+	}
+	authURL, err = provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, oidcBinding(state, nonce), nil
+}
+
+// FinishOIDC handles the provider's redirect: it checks state and the browser's binding, swaps
+// the code for tokens, validates the ID token and logs the linked user in, exactly like a password
+// login would. sessionUserID is the caller's own session: 0 for a sign-in, and for a link it must
+// be the user who started it, so nobody can finish a link into their account in someone else's browser.
+func (s *Service) FinishOIDC(ctx context.Context, providerName, code, state, binding string, sessionUserID int) (*AuthResponse, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	raw, err := s.redisStore.TakeOIDCState(ctx, hashToken(state))
+	if err != nil {
+		return nil, err
+	}
+	var saved oidcState
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		return nil, err
+	}
+	if saved.Provider != providerName {
+		return nil, oidc.ErrInvalidIDToken
+	}
+	if subtle.ConstantTimeCompare([]byte(binding), []byte(oidcBinding(state, saved.Nonce))) != 1 {
+		return nil, ErrOIDCWrongBrowser
+	}
+	if saved.LinkUserID != sessionUserID {
+		return nil, ErrOIDCLinkSession
+	}
+
+	tokens, err := provider.Exchange(ctx, code, saved.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, saved.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := Identity{Provider: providerName, Subject: claims.Subject, Email: validation.NormalizeEmail(claims.Email)}
+	userID, err := s.resolveIdentity(ctx, identity, claims.EmailVerified, saved.LinkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Same second-factor rule as a password login
+	mfa, err := s.repo.GetMFA(ctx, userID)
+	if err != nil && !errors.Is(err, ErrMFANotEnrolled) {
+		return nil, err
+	}
+	if mfa != nil && mfa.EnabledAt != nil {
+		return s.mfaChallenge(ctx, userID)
+	}
+	return s.startSession(ctx, userID)
+}
+
+// resolveIdentity finds or creates the local account for an external identity:
+// an existing link wins; otherwise a provider-verified email joins the matching account,
+// if that account proved it owns the address too; otherwise a new account is created.
+// An unverified email on either side never takes over an existing account: someone who
+// registered the address without confirming it would keep their password on the merged account.
+func (s *Service) resolveIdentity(ctx context.Context, identity Identity, emailVerified bool, linkUserID int) (int, error) {
+	user, err := s.repo.GetUserByIdentity(ctx, identity.Provider, identity.Subject)
+	switch {
+	case err == nil:
+		if linkUserID != 0 && user.ID != linkUserID {
+			return 0, ErrIdentityConflict
+		}
+		return user.ID, nil
+	case !errors.Is(err, ErrUserNotFound):
+		return 0, err
+	}
+
+	// Explicit linking from a logged-in session
+	if linkUserID != 0 {
+		if err := s.repo.LinkIdentity(ctx, linkUserID, identity, false); err != nil {
+			return 0, err
+		}
+		return linkUserID, nil
+	}
+
+	if identity.Email == "" {
+		return 0, ErrOIDCEmailRequired
+	}
+	existing, err := s.repo.GetUserByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		if !emailVerified || existing.EmailVerifiedAt == nil {
+			return 0, ErrOIDCEmailUnverified
+		}
+		if err := s.repo.LinkIdentity(ctx, existing.ID, identity, true); err != nil {
+			return 0, err
+		}
+		return existing.ID, nil
+	case !errors.Is(err, ErrUserNotFound):
+		return 0, err
+	}
+
+	return s.repo.CreateUserWithIdentity(ctx, identity.Email, emailVerified, identity)
+}
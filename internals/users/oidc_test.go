@@ -0,0 +1,200 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"ticketmaster/internals/audit"
+	"ticketmaster/internals/cache"
+	"ticketmaster/internals/db/dbtest"
+	"ticketmaster/internals/keyring"
+	"ticketmaster/internals/mailer"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/oidc"
+	"ticketmaster/internals/oidc/oidctest"
+	"ticketmaster/internals/validation"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+)
+
+const callbackURL = "http://app.test/auth/oidc/mock/callback"
+
+func TestOIDCCallbackAccountLinking(t *testing.T) {
+	db := dbtest.New(t)
+	ctx := context.Background()
+
+	issuer, server, err := oidctest.NewServer("ticketmaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	keys, err := keyring.Load("", "", "test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redisStore := cache.NewRedisStore(miniredis.RunT(t).Addr(), "")
+	provider := oidc.NewProvider(oidc.Config{Name: "mock", Issuer: issuer.URL, ClientID: issuer.ClientID, RedirectURL: callbackURL})
+	service, err := NewService(NewRepository(db), keys, redisStore, mailer.NewMemoryMailer(), "http://app.test",
+		validation.DefaultPasswordPolicy(), audit.NewRepository(db), []*oidc.Provider{provider})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := chi.NewRouter()
+	handler := NewHandler(service)
+	router.Get("/auth/oidc/{provider}/callback", handler.OIDCCallback)
+	router.With(func(next http.Handler) http.Handler {
+		// Stands in for the auth middleware: the session is whoever X-Test-User says
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, _ := strconv.Atoi(r.Header.Get("X-Test-User"))
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, int32(id))))
+		})
+	}).Get("/auth/oidc/{provider}/link/callback", handler.OIDCLinkCallback)
+
+	// complete walks a browser through the provider for a flow started by linkUserID (0 for a
+	// sign-in) and returns our callback's response. cookie says whether the browser carries the
+	// flow's cookie; sessionUserID is whose bearer token reaches the link callback.
+	complete := func(email string, linkUserID int, callback string, sessionUserID int, cookie bool) *httptest.ResponseRecorder {
+		t.Helper()
+		authURL, binding, err := service.StartOIDC(ctx, "mock", linkUserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, _ := url.Parse(authURL)
+		q := u.Query()
+		q.Set("login_hint", email)
+		u.RawQuery = q.Encode()
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.Get(u.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		back, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil || back.Query().Get("code") == "" {
+			t.Fatalf("provider did not redirect back with a code: %d %q", resp.StatusCode, resp.Header.Get("Location"))
+		}
+
+		req := httptest.NewRequest(http.MethodGet, callback+"?"+back.RawQuery, nil)
+		if cookie {
+			req.AddCookie(&http.Cookie{Name: oidcCookie, Value: binding})
+		}
+		req.Header.Set("X-Test-User", strconv.Itoa(sessionUserID))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	signIn := func(email string) *httptest.ResponseRecorder {
+		t.Helper()
+		return complete(email, 0, "/auth/oidc/mock/callback", 0, true)
+	}
+	linkedTo := func(email string) int {
+		t.Helper()
+		user, err := service.repo.GetUserByIdentity(ctx, "mock", "mock|"+email)
+		if err == ErrUserNotFound {
+			return 0
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user.ID
+	}
+
+	verifiedID := int(dbtest.ID(t, db, `INSERT INTO users (email, password_hash, email_verified_at) VALUES ('owner@example.com', 'hash', NOW()) RETURNING id`))
+	dbtest.ID(t, db, `INSERT INTO users (email, password_hash) VALUES ('squatted@example.com', 'hash') RETURNING id`)
+
+	t.Run("new email creates an account", func(t *testing.T) {
+		rec := signIn("new@example.com")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		var resp AuthResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Token == "" || resp.RefreshToken == "" {
+			t.Errorf("response = %+v, %v; want a session", resp, err)
+		}
+		if linkedTo("new@example.com") == 0 {
+			t.Error("identity not linked to the new account")
+		}
+	})
+
+	t.Run("verified local account is linked", func(t *testing.T) {
+		if rec := signIn("owner@example.com"); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		if got := linkedTo("owner@example.com"); got != verifiedID {
+			t.Errorf("identity linked to %d, want %d", got, verifiedID)
+		}
+	})
+
+	t.Run("unverified local account is not taken over", func(t *testing.T) {
+		if rec := signIn("squatted@example.com"); rec.Code != http.StatusConflict {
+			t.Fatalf("status = %d, want 409: %s", rec.Code, rec.Body)
+		}
+		if got := linkedTo("squatted@example.com"); got != 0 {
+			t.Errorf("identity linked to %d, want no link", got)
+		}
+	})
+
+	t.Run("unverified provider email is not linked", func(t *testing.T) {
+		dbtest.Exec(t, db, `INSERT INTO users (email, password_hash, email_verified_at) VALUES ('other@example.com', 'hash', NOW())`)
+		issuer.EmailVerified = false
+		defer func() { issuer.EmailVerified = true }()
+		if rec := signIn("other@example.com"); rec.Code != http.StatusConflict {
+			t.Fatalf("status = %d, want 409: %s", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("flow started in another browser", func(t *testing.T) {
+		if rec := complete("elsewhere@example.com", 0, "/auth/oidc/mock/callback", 0, false); rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want 400 without the flow's cookie: %s", rec.Code, rec.Body)
+		}
+		if got := linkedTo("elsewhere@example.com"); got != 0 {
+			t.Errorf("identity linked to %d, want no account", got)
+		}
+	})
+
+	t.Run("link needs the linking user's session", func(t *testing.T) {
+		attacker := int(dbtest.ID(t, db, `INSERT INTO users (email, password_hash, email_verified_at) VALUES ('attacker@example.com', 'hash', NOW()) RETURNING id`))
+		// The victim's browser finishes a link the attacker started: as a sign-in, or under the victim's session
+		if rec := complete("victim@example.com", attacker, "/auth/oidc/mock/callback", 0, true); rec.Code != http.StatusForbidden {
+			t.Errorf("link through the sign-in callback: status = %d, want 403", rec.Code)
+		}
+		if rec := complete("victim@example.com", attacker, "/auth/oidc/mock/link/callback", verifiedID, true); rec.Code != http.StatusForbidden {
+			t.Errorf("link under another session: status = %d, want 403", rec.Code)
+		}
+		if got := linkedTo("victim@example.com"); got != 0 {
+			t.Fatalf("victim's identity linked to %d", got)
+		}
+
+		if rec := complete("second@example.com", attacker, "/auth/oidc/mock/link/callback", attacker, true); rec.Code != http.StatusOK {
+			t.Fatalf("own link: status = %d: %s", rec.Code, rec.Body)
+		}
+		if got := linkedTo("second@example.com"); got != attacker {
+			t.Errorf("identity linked to %d, want %d", got, attacker)
+		}
+	})
+
+	t.Run("callback can't be replayed", func(t *testing.T) {
+		authURL, _, err := service.StartOIDC(ctx, "mock", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, _ := url.Parse(authURL)
+		state := u.Query().Get("state")
+		if _, err := redisStore.TakeOIDCState(ctx, hashToken(state)); err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/oidc/mock/callback?code=x&state="+url.QueryEscape(state), nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400 for a used state", rec.Code)
+		}
+	})
+}
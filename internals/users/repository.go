@@ -2,10 +2,26 @@ package users
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	database "ticketmaster/internals/db"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrEmailTaken          = errors.New("an account with this email already exists")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrMFANotEnrolled      = errors.New("two-factor authentication is not set up")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrIdentityConflict    = errors.New("this sign-in is linked to a different account")
+	ErrInvalidUserToken    = errors.New("invalid or expired token")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected; session revoked")
 )
 
 type Repository struct {
@@ -16,20 +32,522 @@ func NewRepository(db *database.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) CreateUser(ctx context.Context, email, passwordHash string) error {
-	_, err := r.db.Pool.Exec(ctx, "INSERT INTO users (email, password_hash) VALUES ($1, $2)", email, passwordHash)
-	return err
+func (r *Repository) CreateUser(ctx context.Context, email, passwordHash string) (int, error) {
+	var id int
+	err := r.db.Pool.QueryRow(ctx, "INSERT INTO users (email, password_hash) VALUES ($1, $2) RETURNING id", email, passwordHash).Scan(&id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		return 0, ErrEmailTaken
+	}
+	return id, err
 }
 
 func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return r.getUser(ctx, "email = $1", email)
+}
+
+func (r *Repository) GetUserByID(ctx context.Context, id int) (*User, error) {
+	return r.getUser(ctx, "id = $1", id)
+}
+
+const userColumns = "id, email, name, phone, password_hash, email_verified_at, deleted_at, created_at"
+
+func scanUser(row pgx.Row, u *User) error {
+	return row.Scan(&u.ID, &u.Email, &u.Name, &u.Phone, &u.PasswordHash, &u.EmailVerifiedAt, &u.DeletedAt, &u.CreatedAt)
+}
+
+func (r *Repository) getUser(ctx context.Context, where string, arg any) (*User, error) {
+	var u User
+	query := "SELECT " + userColumns + " FROM users WHERE " + where
+	err := scanUser(r.db.Pool.QueryRow(ctx, query, arg), &u)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *Repository) CreateUserToken(ctx context.Context, userID int, purpose, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		userID, purpose, tokenHash, expiresAt)
+	return err
+}
+
+// VerifyEmail consumes a verification token and marks the address verified, atomically
+func (r *Repository) VerifyEmail(ctx context.Context, tokenHash string) (int, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	userID, err := consumeUserToken(ctx, tx, PurposeVerifyEmail, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(ctx, `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to verify email: %w", err)
+	}
+	return userID, tx.Commit(ctx)
+}
+
+// ResetPassword consumes a reset token, stores the new hash and logs out every session
+func (r *Repository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	userID, err := consumeUserToken(ctx, tx, PurposeResetPassword, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET password_hash = $2 WHERE id = $1`, userID, passwordHash); err != nil {
+		return 0, fmt.Errorf("failed to update password: %w", err)
+	}
+	// Whoever knew the old password may hold a refresh token too
+	_, err = tx.Exec(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	// Any other outstanding reset links die with this one
+	_, err = tx.Exec(ctx,
+		`UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		userID, PurposeResetPassword)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire reset tokens: %w", err)
+	}
+	return userID, tx.Commit(ctx)
+}
+
+// consumeUserToken marks a token used. Expired, used or unknown tokens all look the same to the caller.
+func consumeUserToken(ctx context.Context, tx pgx.Tx, purpose, tokenHash string) (int, error) {
+	var userID int
+	err := tx.QueryRow(ctx, `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`, tokenHash, purpose).Scan(&userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrInvalidUserToken
+		}
+		return 0, fmt.Errorf("failed to consume token: %w", err)
+	}
+	return userID, nil
+}
+
+func (r *Repository) CreateRefreshToken(ctx context.Context, t RefreshToken) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		t.UserID, t.FamilyID, t.TokenHash, t.ExpiresAt)
+	return err
+}
+
+func (r *Repository) GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	var t RefreshToken
+	query := `SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, replaced_by, created_at
+	          FROM refresh_tokens WHERE token_hash = $1`
+	err := r.db.Pool.QueryRow(ctx, query, tokenHash).Scan(
+		&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash, &t.ExpiresAt, &t.RevokedAt, &t.ReplacedBy, &t.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RotateRefreshToken swaps old for next in one transaction.
+// If old was already revoked (a concurrent refresh won the race) it reports reuse.
+func (r *Repository) RotateRefreshToken(ctx context.Context, oldID int64, next RefreshToken) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var nextID int64
+	err = tx.QueryRow(ctx,
+		`INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+		next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt,
+	).Scan(&nextID)
+	if err != nil {
+		return fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = $2 WHERE id = $1 AND revoked_at IS NULL`,
+		oldID, nextID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RevokeRefreshFamily kills every token descended from the same login
+func (r *Repository) RevokeRefreshFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.Pool.Exec(ctx,
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+	return err
+}
+
+// GetMFA returns the user's second-factor settings, pending or enabled
+func (r *Repository) GetMFA(ctx context.Context, userID int) (*MFA, error) {
+	var m MFA
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT user_id, totp_secret, enabled_at, last_used_step, created_at FROM user_mfa WHERE user_id = $1`, userID,
+	).Scan(&m.UserID, &m.TOTPSecret, &m.EnabledAt, &m.LastUsedStep, &m.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SavePendingTOTP stores a new, not yet confirmed secret. Restarting enrollment replaces
+// a pending secret, but an enabled one has to be disabled first.
+func (r *Repository) SavePendingTOTP(ctx context.Context, userID int, secret string) error {
+	tag, err := r.db.Pool.Exec(ctx, `
+		INSERT INTO user_mfa (user_id, totp_secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET totp_secret = EXCLUDED.totp_secret, last_used_step = 0, created_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL`, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save totp secret: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+// EnableMFA switches a pending secret on and issues the first set of recovery codes
+func (r *Repository) EnableMFA(ctx context.Context, userID int, step int64, recoveryHashes []string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE user_mfa SET enabled_at = NOW(), last_used_step = $2 WHERE user_id = $1 AND enabled_at IS NULL`,
+		userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep accepts a code's time step only if it is newer than the last one used,
+// so a code seen over someone's shoulder can't be replayed within its window
+func (r *Repository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to record totp use: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// UseRecoveryCode burns one recovery code
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	tag, err := r.db.Pool.Exec(ctx,
+		`UPDATE mfa_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes invalidates every old recovery code and stores a new set
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID int, recoveryHashes []string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int, recoveryHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range recoveryHashes {
+		_, err := tx.Exec(ctx, `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func (r *Repository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	var n int
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+// DisableMFA removes the secret and all recovery codes
+func (r *Repository) DisableMFA(ctx context.Context, userID int) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to disable mfa: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// GetUserByIdentity finds the account linked to an external identity
+func (r *Repository) GetUserByIdentity(ctx context.Context, provider, subject string) (*User, error) {
+	var userID int
+	err := r.db.Pool.QueryRow(ctx,
+		`UPDATE user_identities SET last_login_at = NOW() WHERE provider = $1 AND subject = $2 RETURNING user_id`,
+		provider, subject).Scan(&userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return r.GetUserByID(ctx, userID)
+}
+
+// LinkIdentity attaches an external identity to an existing account.
+// markVerified records that the provider vouched for the account's email address.
+func (r *Repository) LinkIdentity(ctx context.Context, userID int, identity Identity, markVerified bool) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertIdentity(ctx, tx, userID, identity); err != nil {
+		return err
+	}
+	if markVerified {
+		_, err = tx.Exec(ctx, `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to verify email: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// CreateUserWithIdentity registers a password-less account for a first-time external sign-in
+func (r *Repository) CreateUserWithIdentity(ctx context.Context, email string, verified bool, identity Identity) (int, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash, email_verified_at)
+		VALUES ($1, '', CASE WHEN $2 THEN NOW() END) RETURNING id`, email, verified).Scan(&userID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return 0, ErrEmailTaken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to create user: %w", err)
+	}
+	if err := insertIdentity(ctx, tx, userID, identity); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit(ctx)
+}
+
+func insertIdentity(ctx context.Context, tx pgx.Tx, userID int, identity Identity) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO user_identities (user_id, provider, subject, email, last_login_at) VALUES ($1, $2, $3, $4, NOW())`,
+		userID, identity.Provider, identity.Subject, identity.Email)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrIdentityConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+// UpdateProfile changes the fields that are set; an empty phone removes the number
+func (r *Repository) UpdateProfile(ctx context.Context, userID int, name, phone *string) (*User, error) {
 	var u User
-	query := "SELECT id, email, password_hash, created_at FROM users WHERE email = $1"
-	err := r.db.Pool.QueryRow(ctx, query, email).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt)
+	err := scanUser(r.db.Pool.QueryRow(ctx, `
+		UPDATE users SET
+			name = COALESCE($2, name),
+			phone = CASE WHEN $3::text IS NULL THEN phone ELSE NULLIF($3, '') END
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING `+userColumns, userID, name, phone), &u)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 	return &u, nil
 }
+
+// ExportData collects the user's linked identities, bookings and payments
+func (r *Repository) ExportData(ctx context.Context, user *User) (*DataExport, error) {
+	export := &DataExport{ExportedAt: time.Now().UTC(), Profile: *user}
+
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT provider, email, created_at, last_login_at FROM user_identities WHERE user_id = $1 ORDER BY id`, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	export.Identities, err = pgx.CollectRows(rows, pgx.RowToStructByName[ExportedIdentity])
+	if err != nil {
+		return nil, fmt.Errorf("failed to export identities: %w", err)
+	}
+
+	rows, err = r.db.Pool.Query(ctx, `
+		SELECT b.id, e.name AS event_name, e.starts_at AS event_starts_at, s.section, s.row_number, s.seat_number,
+		       gt.name AS ticket_type,
+		       b.status, b.currency, b.face_value, b.discount, b.service_fee, b.facility_fee, b.tax, b.amount,
+		       pc.code AS promo_code, b.created_at
+		FROM bookings b
+		LEFT JOIN seats s ON s.id = b.seat_id
+		LEFT JOIN ga_ticket_types gt ON gt.id = b.ga_ticket_type_id
+		LEFT JOIN events e ON e.id = b.event_id
+		LEFT JOIN promo_redemptions pr ON pr.booking_id = b.id
+		LEFT JOIN promo_codes pc ON pc.id = pr.promo_code_id
+		WHERE b.user_id = $1 ORDER BY b.id`, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	export.Bookings, err = pgx.CollectRows(rows, pgx.RowToStructByName[ExportedBooking])
+	if err != nil {
+		return nil, fmt.Errorf("failed to export bookings: %w", err)
+	}
+
+	rows, err = r.db.Pool.Query(ctx, `
+		SELECT 'charge' AS type, id AS booking_id, amount, currency, 'succeeded' AS status, NULL::text AS provider_ref, created_at
+		FROM bookings WHERE user_id = $1 AND amount > 0
+		UNION ALL
+		SELECT 'refund', rf.booking_id, rf.amount, rf.currency, rf.status, rf.provider_ref, rf.created_at
+		FROM refunds rf JOIN bookings b ON b.id = rf.booking_id
+		WHERE b.user_id = $1
+		ORDER BY created_at`, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	export.Payments, err = pgx.CollectRows(rows, pgx.RowToStructByName[ExportedPayment])
+	if err != nil {
+		return nil, fmt.Errorf("failed to export payments: %w", err)
+	}
+	return export, nil
+}
+
+// AnonymizeUser is account deletion: personal data goes, credentials and sessions go,
+// the row stays so bookings, refunds and the ledger still add up. The address is also
+// replaced wherever it was copied: audit_log subjects and ticket transfers.
+func (r *Repository) AnonymizeUser(ctx context.Context, userID int) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var email, placeholder string
+	err = tx.QueryRow(ctx, `
+		SELECT email, 'deleted-' || id || '@deleted.invalid' FROM users
+		WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, userID).Scan(&email, &placeholder)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	// Offers still waiting for an answer die with the account, both ways. Incoming
+	// offers are found by address as well, since that is what they are sent to.
+	_, err = tx.Exec(ctx, `
+		UPDATE ticket_transfers SET status = 'cancelled', responded_at = NOW()
+		WHERE status = 'pending' AND (from_user_id = $1 OR to_user_id = $1 OR to_email = $2)`, userID, email)
+	if err != nil {
+		return fmt.Errorf("failed to cancel transfers: %w", err)
+	}
+
+	// Transfers to the user, whether or not they had accepted, keep the placeholder
+	if _, err := tx.Exec(ctx, `UPDATE ticket_transfers SET to_email = $3 WHERE to_user_id = $1 OR to_email = $2`,
+		userID, email, placeholder); err != nil {
+		return fmt.Errorf("failed to scrub transfers: %w", err)
+	}
+	// Offers the user sent that nobody took up would only tie them to someone's address
+	if _, err := tx.Exec(ctx, `
+		UPDATE ticket_transfers SET to_email = 'transfer-' || id || '@deleted.invalid'
+		WHERE from_user_id = $1 AND to_user_id IS NULL`, userID); err != nil {
+		return fmt.Errorf("failed to scrub transfers: %w", err)
+	}
+	// Lockouts name the account by address, also when it was attacked before it existed
+	if _, err := tx.Exec(ctx, `UPDATE audit_log SET subject = $3 WHERE subject = $2 OR (user_id = $1 AND subject <> '')`,
+		userID, email, placeholder); err != nil {
+		return fmt.Errorf("failed to scrub audit log: %w", err)
+	}
+
+	// The placeholder keeps email unique and NOT NULL; .invalid can never receive mail (RFC 2606)
+	_, err = tx.Exec(ctx, `
+		UPDATE users SET
+			email = $2, name = '', phone = NULL, password_hash = '',
+			email_verified_at = NULL, deleted_at = NOW()
+		WHERE id = $1`, userID, placeholder)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
+	for _, stmt := range []string{
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM user_mfa WHERE user_id = $1`,
+		`DELETE FROM user_tokens WHERE user_id = $1`,
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`,
+	} {
+		if _, err := tx.Exec(ctx, stmt, userID); err != nil {
+			return fmt.Errorf("failed to delete account data: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -2,53 +2,378 @@ package users
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"ticketmaster/internals/audit"
+	"ticketmaster/internals/cache"
+	"ticketmaster/internals/keyring"
+	"ticketmaster/internals/mailer"
+	"ticketmaster/internals/oidc"
+	"ticketmaster/internals/validation"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	// Access tokens are short-lived; a stolen one is only useful for minutes.
+	accessTokenTTL = 15 * time.Minute
+
+	// Refresh tokens rotate on every use and expire if the user stays away this long.
+	refreshTokenTTL = 30 * 24 * time.Hour
+
+	// Lifetime of the links we email out
+	verifyEmailTTL   = 48 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
 type Service struct {
-	repo   *Repository
-	jwtKey string
+	repo       *Repository
+	keys       *keyring.Keyring
+	redisStore *cache.RedisStore
+	mailer     mailer.Mailer
+	appURL     string // Base URL of the frontend, used to build links in emails
+	policy     validation.PasswordPolicy
+	audit      *audit.Repository
+	dummyHash  []byte // Compared against when the email is unknown, so both paths cost one bcrypt
+	providers  map[string]*oidc.Provider
 }
 
-func NewService(repo *Repository, jwt string) *Service {
-	return &Service{repo: repo, jwtKey: jwt}
+func NewService(repo *Repository, keys *keyring.Keyring, redisStore *cache.RedisStore, mail mailer.Mailer, appURL string, policy validation.PasswordPolicy, auditRepo *audit.Repository, providers []*oidc.Provider) (*Service, error) {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+	return &Service{
+		repo:       repo,
+		keys:       keys,
+		redisStore: redisStore,
+		mailer:     mail,
+		appURL:     appURL,
+		policy:     policy,
+		audit:      auditRepo,
+		dummyHash:  dummyHash,
+		providers:  byName,
+	}, nil
 }
 
-// Register handles hashing and saving
+// Register handles validation, hashing and saving
 func (s *Service) Register(ctx context.Context, req RegisterRequest) error {
+	// 0. Validate. The email is normalized first so "Bob@x.com" can't sign up next to "bob@x.com".
+	req.Email = validation.NormalizeEmail(req.Email)
+	var errs validation.Errors
+	errs.Email("email", req.Email)
+	errs.Password("password", req.Password, s.policy, req.Email)
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
 	// 1. Hash Password
 	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
-	// 2. Save to Repo
-	return s.repo.CreateUser(ctx, req.Email, string(hashed))
+	// 2. Save to Repo. A taken address gets the same answer as a new one, so the endpoint
+	// can't be used to find out who has an account; its owner hears about it by email.
+	userID, err := s.repo.CreateUser(ctx, req.Email, string(hashed))
+	if errors.Is(err, ErrEmailTaken) {
+		if err := s.sendAlreadyRegistered(ctx, req.Email); err != nil {
+			log.Printf("failed to send already-registered email: %v", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// 3. Ask them to verify the address. A mail failure must not fail the registration;
+	// they can ask for a new link with POST /verify-email/resend.
+	if err := s.sendVerification(ctx, userID, req.Email); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", userID, err)
+	}
+	return nil
+}
+
+// VerifyEmail consumes the token from the verification link
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	_, err := s.repo.VerifyEmail(ctx, hashToken(token))
+	return err
+}
+
+// ResendVerification mails a fresh verification link to a logged-in, unverified user
+func (s *Service) ResendVerification(ctx context.Context, userID int) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	return s.sendVerification(ctx, user.ID, user.Email)
+}
+
+// ForgotPassword mails a reset link if the account exists.
+// It never tells the caller whether it did, so it can't be used to probe for accounts.
+func (s *Service) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.repo.GetUserByEmail(ctx, validation.NormalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := s.issueUserToken(ctx, user.ID, PurposeResetPassword, resetPasswordTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset your password. If it was you, open this link within an hour:\n\n%s\n\n"+
+			"If it wasn't, you can ignore this email.", s.link("/reset-password", token)),
+	})
+}
+
+// ResetPassword sets a new password using the emailed token. All existing sessions are revoked.
+func (s *Service) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	var errs validation.Errors
+	errs.Password("password", req.Password, s.policy)
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	_, err = s.repo.ResetPassword(ctx, hashToken(req.Token), string(hashed))
+	return err
+}
+
+func (s *Service) sendVerification(ctx context.Context, userID int, email string) error {
+	token, err := s.issueUserToken(ctx, userID, PurposeVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body:    fmt.Sprintf("Welcome! Confirm your email address by opening this link:\n\n%s", s.link("/verify-email", token)),
+	})
+}
+
+// sendAlreadyRegistered tells the owner of an address that someone tried to sign up with it
+func (s *Service) sendAlreadyRegistered(ctx context.Context, email string) error {
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "You already have an account",
+		Body: fmt.Sprintf("Someone tried to create an account with this email address, but you already have one. "+
+			"Sign in at %s, or reset your password at %s if you forgot it.\n\n"+
+			"If it wasn't you, you can ignore this email.", s.appURL+"/login", s.appURL+"/forgot-password"),
+	})
+}
+
+func (s *Service) issueUserToken(ctx context.Context, userID int, purpose string, ttl time.Duration) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.CreateUserToken(ctx, userID, purpose, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *Service) link(path, token string) string {
+	return s.appURL + path + "?token=" + url.QueryEscape(token)
 }
 
-// Login handles verification and token generation
-func (s *Service) Login(ctx context.Context, req LoginRequest) (string, error) {
+// Login handles verification and token generation. ip is the client address, used for throttling.
+func (s *Service) Login(ctx context.Context, req LoginRequest, ip string) (*AuthResponse, error) {
+	email := validation.NormalizeEmail(req.Email)
+
+	// 0. Throttle: a locked account or IP doesn't even get a bcrypt comparison
+	if wait := s.loginLockedFor(ctx, email, ip); wait > 0 {
+		return nil, &LoginLockedError{RetryAfter: wait}
+	}
+
 	// 1. Find User
-	user, err := s.repo.GetUserByEmail(ctx, req.Email)
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			return nil, err
+		}
+		// Same work and same counters as a wrong password, so timing doesn't reveal the account exists
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(req.Password))
+		s.recordLoginFailure(ctx, email, ip, nil)
+		return nil, ErrInvalidCredentials
+	}
+
+	// 2. Verify Password (accounts created through a provider have none until they set one)
+	hash := []byte(user.PasswordHash)
+	if len(hash) == 0 {
+		hash = s.dummyHash
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(req.Password)); err != nil || len(user.PasswordHash) == 0 {
+		s.recordLoginFailure(ctx, email, ip, &user.ID)
+		return nil, ErrInvalidCredentials
+	}
+
+	// 3. Second factor: hand out a challenge instead of the tokens.
+	// Failures are only cleared once the code is right too, so guessing codes stays throttled.
+	mfa, err := s.repo.GetMFA(ctx, user.ID)
+	if err != nil && !errors.Is(err, ErrMFANotEnrolled) {
+		return nil, err
+	}
+	if mfa != nil && mfa.EnabledAt != nil {
+		return s.mfaChallenge(ctx, user.ID)
+	}
+	s.clearLoginFailures(ctx, email)
+
+	return s.startSession(ctx, user.ID)
+}
+
+// startSession issues the token pair for a fully authenticated user (a fresh refresh-token family per login)
+func (s *Service) startSession(ctx context.Context, userID int) (*AuthResponse, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.newRefreshToken(userID, familyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateRefreshToken(ctx, refreshToken.stored); err != nil {
+		return nil, err
+	}
+	return s.authResponse(userID, refreshToken.plain)
+}
+
+// Refresh rotates a refresh token: the old one dies, a new pair is issued.
+// Presenting an already-rotated token means it was stolen, so the whole family is revoked.
+func (s *Service) Refresh(ctx context.Context, token string) (*AuthResponse, error) {
+	// 1. Look it up by hash
+	current, err := s.repo.GetRefreshToken(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Reuse detection
+	if current.RevokedAt != nil {
+		if err := s.repo.RevokeRefreshFamily(ctx, current.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 3. Rotate
+	next, err := s.newRefreshToken(current.UserID, current.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RotateRefreshToken(ctx, current.ID, next.stored); err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			// Lost a race against another refresh with the same token: same treatment as reuse
+			s.repo.RevokeRefreshFamily(ctx, current.FamilyID)
+		}
+		return nil, err
+	}
+	return s.authResponse(current.UserID, next.plain)
+}
+
+// Logout denylists the current access token and, if given, revokes the refresh token's family
+func (s *Service) Logout(ctx context.Context, userID int, jti string, expiresAt time.Time, refreshToken string) error {
+	if err := s.redisStore.DenyToken(ctx, jti, time.Until(expiresAt)); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	current, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
 	if err != nil {
-		return "", errors.New("invalid credentials")
+		return err
+	}
+	if current.UserID != userID {
+		return ErrInvalidRefreshToken
 	}
+	return s.repo.RevokeRefreshFamily(ctx, current.FamilyID)
+}
 
-	// 2. Verify Password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return "", errors.New("invalid credentials")
+func (s *Service) authResponse(userID int, refreshToken string) (*AuthResponse, error) {
+	accessToken, err := s.signAccessToken(userID)
+	if err != nil {
+		return nil, err
 	}
+	return &AuthResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+	}, nil
+}
 
-	// 3. Generate JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+func (s *Service) signAccessToken(userID int) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	return s.keys.Sign(jwt.MapClaims{
+		"user_id": userID,
+		"jti":     jti, // Lets us revoke this exact token on logout
+		"iat":     now.Unix(),
+		"exp":     now.Add(accessTokenTTL).Unix(),
 	})
+}
+
+type issuedRefreshToken struct {
+	plain  string // Handed to the client once, never stored
+	stored RefreshToken
+}
+
+func (s *Service) newRefreshToken(userID int, familyID string) (*issuedRefreshToken, error) {
+	plain, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	return &issuedRefreshToken{
+		plain: plain,
+		stored: RefreshToken{
+			UserID:    userID,
+			FamilyID:  familyID,
+			TokenHash: hashToken(plain),
+			ExpiresAt: time.Now().Add(refreshTokenTTL),
+		},
+	}, nil
+}
+
+// randomToken returns n random bytes, URL-safe encoded
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	// Note: In production, move "MY_SUPER_SECRET_KEY" to .env
-	return token.SignedString([]byte(s.jwtKey))
+// hashToken is SHA-256, not bcrypt: tokens are already high-entropy and we look them up by hash
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
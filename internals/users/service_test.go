@@ -0,0 +1,307 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"ticketmaster/internals/audit"
+	"ticketmaster/internals/cache"
+	database "ticketmaster/internals/db"
+	"ticketmaster/internals/db/dbtest"
+	"ticketmaster/internals/keyring"
+	"ticketmaster/internals/mailer"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/validation"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testService is a Service on the test database, a fresh Redis and a mailer that keeps its messages
+type testService struct {
+	*Service
+	db    *database.DB
+	redis *miniredis.Miniredis
+	mail  *mailer.MemoryMailer
+}
+
+func newTestService(t *testing.T) *testService {
+	t.Helper()
+	db := dbtest.New(t)
+	keys, err := keyring.Load("", "", "test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := miniredis.RunT(t)
+	mail := mailer.NewMemoryMailer()
+	service, err := NewService(NewRepository(db), keys, cache.NewRedisStore(mr.Addr(), ""), mail, "http://app.test",
+		validation.DefaultPasswordPolicy(), audit.NewRepository(db), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testService{Service: service, db: db, redis: mr, mail: mail}
+}
+
+func TestRefreshRotationAndReuse(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	userID := int(dbtest.ID(t, s.db, `INSERT INTO users (email, password_hash) VALUES ('refresh@example.com', 'x') RETURNING id`))
+
+	first, err := s.startSession(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.Token == "" {
+		t.Fatalf("refresh did not rotate: %+v", second)
+	}
+	third, err := s.Refresh(ctx, second.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh of the rotated token: %v", err)
+	}
+
+	// Another login is another family and survives what follows
+	other, err := s.startSession(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first token again: it was stolen, so the whole family goes
+	if _, err := s.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reused token = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := s.Refresh(ctx, third.RefreshToken); err == nil {
+		t.Error("the family's live token still works after reuse")
+	}
+	if _, err := s.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("another session's token: %v", err)
+	}
+
+	if _, err := s.Refresh(ctx, "never-issued"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("unknown token = %v, want ErrInvalidRefreshToken", err)
+	}
+	expired, _ := s.startSession(ctx, userID)
+	dbtest.Exec(t, s.db, `UPDATE refresh_tokens SET expires_at = NOW() - INTERVAL '1 minute' WHERE token_hash = $1`, hashToken(expired.RefreshToken))
+	if _, err := s.Refresh(ctx, expired.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expired token = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestLogoutRevokesAccessAndRefreshTokens(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	userID := int(dbtest.ID(t, s.db, `INSERT INTO users (email, password_hash) VALUES ('logout@example.com', 'x') RETURNING id`))
+	strangerID := int(dbtest.ID(t, s.db, `INSERT INTO users (email, password_hash) VALUES ('stranger@example.com', 'x') RETURNING id`))
+
+	auth, err := middleware.NewMiddleware(s.keys, s.redisStore, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jti string
+	var expiresAt time.Time
+	protected := auth.Auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jti, _ = r.Context().Value(middleware.TokenIDKey).(string)
+		expiresAt, _ = r.Context().Value(middleware.TokenExpiryKey).(time.Time)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	call := func(token string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		protected.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	session, err := s.startSession(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strangers, _ := s.startSession(ctx, strangerID)
+	if code := call(session.Token); code != http.StatusNoContent {
+		t.Fatalf("fresh token: status %d", code)
+	}
+
+	// Someone else's refresh token is refused and left alone
+	if err := s.Logout(ctx, userID, jti, expiresAt, strangers.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("logout with another user's refresh token = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := s.Refresh(ctx, strangers.RefreshToken); err != nil {
+		t.Errorf("the other user's session was revoked: %v", err)
+	}
+
+	if err := s.Logout(ctx, userID, jti, expiresAt, session.RefreshToken); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if code := call(session.Token); code != http.StatusUnauthorized {
+		t.Errorf("access token after logout: status %d, want 401", code)
+	}
+	if _, err := s.Refresh(ctx, session.RefreshToken); err == nil {
+		t.Error("refresh token still works after logout")
+	}
+
+	// The denylist entry lives exactly as long as the token would have
+	if ttl := s.redis.TTL("jwt_denylist:" + jti); ttl <= 0 || ttl > accessTokenTTL {
+		t.Errorf("denylist TTL = %s", ttl)
+	}
+}
+
+// mailedToken pulls the token out of the link in the last message sent to address
+func (s *testService) mailedToken(t *testing.T, address string) string {
+	t.Helper()
+	messages := s.mail.Messages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].To != address {
+			continue
+		}
+		for _, field := range strings.Fields(messages[i].Body) {
+			if link, err := url.Parse(field); err == nil && link.Query().Get("token") != "" {
+				return link.Query().Get("token")
+			}
+		}
+		t.Fatalf("no link in %q", messages[i].Body)
+	}
+	t.Fatalf("no mail to %s", address)
+	return ""
+}
+
+func TestVerifyEmail(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	if err := s.Register(ctx, RegisterRequest{Email: "Verify@Example.com", Password: "long enough password"}); err != nil {
+		t.Fatal(err)
+	}
+	token := s.mailedToken(t, "verify@example.com")
+
+	// A reset token is not a verification token, even for the same user
+	if err := s.ForgotPassword(ctx, "verify@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.VerifyEmail(ctx, s.mailedToken(t, "verify@example.com")); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("reset token = %v, want ErrInvalidUserToken", err)
+	}
+
+	if err := s.VerifyEmail(ctx, token); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	user, err := s.repo.GetUserByEmail(ctx, "verify@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Error("email not marked verified")
+	}
+	if err := s.VerifyEmail(ctx, token); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("second use = %v, want ErrInvalidUserToken", err)
+	}
+	if err := s.VerifyEmail(ctx, "never-issued"); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("unknown token = %v, want ErrInvalidUserToken", err)
+	}
+
+	if err := s.Register(ctx, RegisterRequest{Email: "late@example.com", Password: "long enough password"}); err != nil {
+		t.Fatal(err)
+	}
+	expired := s.mailedToken(t, "late@example.com")
+	dbtest.Exec(t, s.db, `UPDATE user_tokens SET expires_at = NOW() - INTERVAL '1 minute' WHERE token_hash = $1`, hashToken(expired))
+	if err := s.VerifyEmail(ctx, expired); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("expired token = %v, want ErrInvalidUserToken", err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	if err := s.Register(ctx, RegisterRequest{Email: "reset@example.com", Password: "old password here"}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.repo.GetUserByEmail(ctx, "reset@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := s.startSession(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No account, no mail, and no error either
+	sent := len(s.mail.Messages())
+	if err := s.ForgotPassword(ctx, "nobody@example.com"); err != nil {
+		t.Errorf("ForgotPassword for an unknown address: %v", err)
+	}
+	if len(s.mail.Messages()) != sent {
+		t.Error("mail sent for an unknown address")
+	}
+
+	if err := s.ForgotPassword(ctx, "Reset@Example.com"); err != nil {
+		t.Fatal(err)
+	}
+	older := s.mailedToken(t, "reset@example.com")
+	if err := s.ForgotPassword(ctx, "reset@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	token := s.mailedToken(t, "reset@example.com")
+
+	if err := s.ResetPassword(ctx, ResetPasswordRequest{Token: token, Password: "short"}); err == nil {
+		t.Error("weak password accepted")
+	}
+	if err := s.ResetPassword(ctx, ResetPasswordRequest{Token: token, Password: "new password here"}); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+
+	if _, err := s.Refresh(ctx, session.RefreshToken); err == nil {
+		t.Error("session from before the reset still refreshes")
+	}
+	if _, err := s.Login(ctx, LoginRequest{Email: "reset@example.com", Password: "old password here"}, "192.0.2.1"); err == nil {
+		t.Error("old password still works")
+	}
+	if _, err := s.Login(ctx, LoginRequest{Email: "reset@example.com", Password: "new password here"}, "192.0.2.1"); err != nil {
+		t.Errorf("new password: %v", err)
+	}
+
+	for name, used := range map[string]string{"same link again": token, "older link": older} {
+		if err := s.ResetPassword(ctx, ResetPasswordRequest{Token: used, Password: "another password"}); !errors.Is(err, ErrInvalidUserToken) {
+			t.Errorf("%s = %v, want ErrInvalidUserToken", name, err)
+		}
+	}
+
+	if err := s.ForgotPassword(ctx, "reset@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	expired := s.mailedToken(t, "reset@example.com")
+	dbtest.Exec(t, s.db, `UPDATE user_tokens SET expires_at = NOW() - INTERVAL '1 minute' WHERE token_hash = $1`, hashToken(expired))
+	if err := s.ResetPassword(ctx, ResetPasswordRequest{Token: expired, Password: "another password"}); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("expired token = %v, want ErrInvalidUserToken", err)
+	}
+}
+
+func TestRegisterDoesNotRevealAccounts(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	if err := s.Register(ctx, RegisterRequest{Email: "taken@example.com", Password: "long enough password"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Register(ctx, RegisterRequest{Email: "Taken@Example.com", Password: "another password"}); err != nil {
+		t.Fatalf("second registration = %v, want the same answer as the first", err)
+	}
+
+	messages := s.mail.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("%d messages sent, want 2", len(messages))
+	}
+	if m := messages[1]; m.To != "taken@example.com" || m.Subject != "You already have an account" || strings.Contains(m.Body, "token=") {
+		t.Errorf("second message = %+v, want an already-registered notice without a token", m)
+	}
+	var accounts int
+	s.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE email = 'taken@example.com'`).Scan(&accounts)
+	if accounts != 1 {
+		t.Errorf("%d accounts with the address, want 1", accounts)
+	}
+}
@@ -0,0 +1,38 @@
+package validation
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// maxEmailLength is the longest address SMTP will carry (RFC 5321)
+const maxEmailLength = 254
+
+// NormalizeEmail trims and lower-cases an address so "Bob@Example.com " and
+// "bob@example.com" are the same account. Every lookup and insert goes through it.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Email checks a (normalized) address: a bare addr-spec with a dotted domain,
+// no display name, no angle brackets.
+func (e *Errors) Email(field, email string) {
+	if email == "" {
+		e.Add(field, "is required")
+		return
+	}
+	if len(email) > maxEmailLength {
+		e.Add(field, "is too long")
+		return
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		e.Add(field, "must be a valid email address")
+		return
+	}
+	at := strings.LastIndexByte(email, '@')
+	domain := email[at+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		e.Add(field, "must be a valid email address")
+	}
+}
@@ -0,0 +1,136 @@
+package validation
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bcryptMaxBytes is where bcrypt silently stops reading. Anything longer is rejected
+// rather than truncated, otherwise two different passwords would hash the same.
+const bcryptMaxBytes = 72
+
+// PasswordPolicy decides what a new password must look like
+type PasswordPolicy struct {
+	MinLength      int           // In characters
+	MinCharClasses int           // Of lower, upper, digit, symbol; 0 disables the check
+	Breached       *BreachedList // Optional; nil skips the breach check
+}
+
+// DefaultPasswordPolicy follows NIST 800-63B: length over composition rules
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{MinLength: 10}
+}
+
+// Password checks a new password. related holds values the password must not equal,
+// such as the account's email address.
+func (e *Errors) Password(field, password string, policy PasswordPolicy, related ...string) {
+	switch {
+	case password == "":
+		e.Add(field, "is required")
+		return
+	case utf8.RuneCountInString(password) < policy.MinLength:
+		e.Add(field, fmt.Sprintf("must be at least %d characters", policy.MinLength))
+		return
+	case len(password) > bcryptMaxBytes:
+		e.Add(field, fmt.Sprintf("must be at most %d bytes", bcryptMaxBytes))
+		return
+	}
+
+	if policy.MinCharClasses > 0 && charClasses(password) < policy.MinCharClasses {
+		e.Add(field, fmt.Sprintf("must mix at least %d of lowercase, uppercase, digits and symbols", policy.MinCharClasses))
+		return
+	}
+	for _, value := range related {
+		if value != "" && strings.EqualFold(password, value) {
+			e.Add(field, "must not be the same as your email address")
+			return
+		}
+	}
+	if policy.Breached.Contains(password) {
+		e.Add(field, "has appeared in a data breach; please choose a different one")
+	}
+}
+
+func charClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// BreachedList is a set of SHA-1 hashes of known-compromised passwords
+type BreachedList struct {
+	hashes map[[sha1.Size]byte]struct{}
+}
+
+// LoadBreachedList reads one upper- or lower-case SHA-1 hex digest per line.
+// The "HASH:COUNT" format of the Have I Been Pwned downloads is accepted as-is;
+// blank lines and lines starting with # are skipped.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := &BreachedList{hashes: make(map[[sha1.Size]byte]struct{})}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		digest, _, _ := strings.Cut(line, ":")
+		var sum [sha1.Size]byte
+		if len(digest) != hex.EncodedLen(sha1.Size) {
+			return nil, fmt.Errorf("%s:%d: not a SHA-1 hex digest", path, n)
+		}
+		if _, err := hex.Decode(sum[:], []byte(digest)); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		list.hashes[sum] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Contains reports whether the password is on the list. A nil list contains nothing.
+func (b *BreachedList) Contains(password string) bool {
+	if b == nil {
+		return false
+	}
+	_, ok := b.hashes[sha1.Sum([]byte(password))]
+	return ok
+}
+
+// Len is the number of hashes loaded
+func (b *BreachedList) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.hashes)
+}
@@ -0,0 +1,24 @@
+package validation
+
+import "strings"
+
+// Phone checks a phone number loosely: an optional leading +, then 6 to 15 digits,
+// allowing the spaces, dashes, dots and brackets people type. An empty value is allowed.
+func (e *Errors) Phone(field, phone string) {
+	if phone == "" {
+		return
+	}
+	digits := 0
+	for i, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r == '+' && i == 0:
+		case strings.ContainsRune(" -.()", r):
+		default:
+			e.Add(field, "must be a phone number")
+			return
+		}
+	}
+	e.Check(digits >= 6 && digits <= 15, field, "must be a phone number")
+}
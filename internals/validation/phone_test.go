@@ -0,0 +1,26 @@
+package validation
+
+import "testing"
+
+func TestPhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		ok    bool
+	}{
+		{"", true},
+		{"+41 44 668 18 00", true},
+		{"(555) 123-4567", true},
+		{"555.123.4567", true},
+		{"12345", false},
+		{"+1234567890123456", false},
+		{"44+123456", false},
+		{"call me", false},
+	}
+	for _, tt := range tests {
+		var errs Errors
+		errs.Phone("phone", tt.phone)
+		if ok := errs.Err() == nil; ok != tt.ok {
+			t.Errorf("Phone(%q) ok = %v, want %v", tt.phone, ok, tt.ok)
+		}
+	}
+}
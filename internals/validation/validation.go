@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes what is wrong with one input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is returned when a request fails validation. It carries every problem found,
+// not just the first, so a form can highlight all of its bad fields at once.
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Errors collects field errors while a request is checked. The zero value is ready to use.
+type Errors struct {
+	fields []FieldError
+}
+
+// Add records a problem with a field
+func (e *Errors) Add(field, message string) {
+	e.fields = append(e.fields, FieldError{Field: field, Message: message})
+}
+
+// Check records message against field unless ok holds
+func (e *Errors) Check(ok bool, field, message string) {
+	if !ok {
+		e.Add(field, message)
+	}
+}
+
+// Has reports whether field already has an error, so dependent checks can be skipped
+func (e *Errors) Has(field string) bool {
+	for _, f := range e.fields {
+		if f.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Required checks that a string field is not blank
+func (e *Errors) Required(field, value string) {
+	e.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// MaxLength checks a string field's length in characters
+func (e *Errors) MaxLength(field, value string, max int) {
+	e.Check(utf8.RuneCountInString(value) <= max, field, "must be at most "+strconv.Itoa(max)+" characters")
+}
+
+// Err returns nil if nothing was recorded, otherwise an *Error with every field error
+func (e *Errors) Err() error {
+	if len(e.fields) == 0 {
+		return nil
+	}
+	return &Error{Fields: e.fields}
+}
+
+// Respond writes the 422 response and returns true if anything was recorded.
+// Handlers that validate in place use it as: if errs.Respond(w) { return }
+func (e *Errors) Respond(w http.ResponseWriter) bool {
+	if len(e.fields) == 0 {
+		return false
+	}
+	WriteError(w, &Error{Fields: e.fields})
+	return true
+}
+
+// Response is the JSON body sent for a failed validation
+type Response struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields"`
+}
+
+// CodeValidationFailed is the error code clients can switch on
+const CodeValidationFailed = "VALIDATION_FAILED"
+
+// WriteError answers 422 with the structured field errors
+func WriteError(w http.ResponseWriter, err *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(Response{
+		Code:    CodeValidationFailed,
+		Message: "One or more fields are invalid",
+		Fields:  err.Fields,
+	})
+}
@@ -0,0 +1,119 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorsCollectEveryField(t *testing.T) {
+	var errs Errors
+	if errs.Err() != nil || errs.Respond(httptest.NewRecorder()) {
+		t.Fatal("empty Errors should pass")
+	}
+
+	errs.Required("name", "   ")
+	errs.MaxLength("city", "Zürich", 5)
+	errs.MaxLength("country", "Zürich", 6) // Characters, not bytes
+	errs.Check(false, "seat_id", "is required")
+	if !errs.Has("city") || errs.Has("country") {
+		t.Errorf("Has: city %v, country %v", errs.Has("city"), errs.Has("country"))
+	}
+
+	var verr *Error
+	if err := errs.Err(); !errors.As(err, &verr) || len(verr.Fields) != 3 {
+		t.Fatalf("Err = %v, want 3 field errors", err)
+	}
+
+	rec := httptest.NewRecorder()
+	if !errs.Respond(rec) {
+		t.Fatal("Respond wrote nothing")
+	}
+	var body Response
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusUnprocessableEntity || body.Code != CodeValidationFailed || len(body.Fields) != 3 ||
+		body.Fields[0] != (FieldError{Field: "name", Message: "is required"}) {
+		t.Errorf("response %d %+v", rec.Code, body)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	if got := NormalizeEmail("  Bob@Example.COM "); got != "bob@example.com" {
+		t.Errorf("NormalizeEmail = %q", got)
+	}
+
+	tests := []struct {
+		email string
+		ok    bool
+	}{
+		{"bob@example.com", true},
+		{"bob+tickets@mail.example.co.uk", true},
+		{"", false},
+		{"bob", false},
+		{"bob@localhost", false},
+		{"bob@example.", false},
+		{"bob@.example.com", false},
+		{"Bob <bob@example.com>", false},
+		{"<bob@example.com>", false},
+		{strings.Repeat("a", 250) + "@example.com", false},
+	}
+	for _, tt := range tests {
+		var errs Errors
+		errs.Email("email", tt.email)
+		if ok := errs.Err() == nil; ok != tt.ok {
+			t.Errorf("Email(%q) ok = %v, want %v", tt.email, ok, tt.ok)
+		}
+	}
+}
+
+func TestPassword(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "pwned.txt")
+	// sha1("password") as Have I Been Pwned ships it, then a bare lower-case digest
+	content := "# sample\n\n5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n1e9a5bf7b1da04bcfe10c6a0a96a4ff6b5cc3b1a\n"
+	if err := os.WriteFile(list, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	breached, err := LoadBreachedList(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if breached.Len() != 2 || !breached.Contains("password") || breached.Contains("not-on-the-list") {
+		t.Errorf("breached list: len %d", breached.Len())
+	}
+
+	strict := PasswordPolicy{MinLength: 8, MinCharClasses: 3, Breached: breached}
+	tests := []struct {
+		name     string
+		password string
+		policy   PasswordPolicy
+		ok       bool
+	}{
+		{"long passphrase", "staple battery horse", DefaultPasswordPolicy(), true},
+		{"empty", "", DefaultPasswordPolicy(), false},
+		{"too short", "short", DefaultPasswordPolicy(), false},
+		{"counted in characters", "pässwörtér", DefaultPasswordPolicy(), true},
+		{"past bcrypt's 72 bytes", strings.Repeat("x", 73), DefaultPasswordPolicy(), false},
+		{"same as the email", "Bob@Example.com", DefaultPasswordPolicy(), false},
+		{"one character class", "abcdefghij", strict, false},
+		{"three classes", "Abcdefgh1", strict, true},
+		{"breached", "password", PasswordPolicy{MinLength: 8, Breached: breached}, false},
+	}
+	for _, tt := range tests {
+		var errs Errors
+		errs.Password("password", tt.password, tt.policy, "bob@example.com")
+		if ok := errs.Err() == nil; ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v (%v)", tt.name, ok, tt.ok, errs.Err())
+		}
+	}
+
+	os.WriteFile(list, []byte("not-a-hash\n"), 0o600)
+	if _, err := LoadBreachedList(list); err == nil {
+		t.Error("a malformed list was loaded")
+	}
+}
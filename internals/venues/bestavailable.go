@@ -0,0 +1,156 @@
+package venues
+
+import (
+	"math"
+	"sort"
+	"ticketmaster/internals/seats"
+	"time"
+)
+
+// Best-available limits
+const (
+	MaxBestAvailableQuantity = 10
+	HoldTTL                  = 5 * time.Minute // How long a found block stays held for checkout
+	maxHoldAttempts          = 5               // Candidate blocks tried before giving up
+)
+
+// BestAvailableRequest is the body of POST /events/{id}/best-available.
+// Prices are face values in minor units.
+type BestAvailableRequest struct {
+	Quantity        int      `json:"quantity"`
+	MinPrice        *int64   `json:"min_price"`
+	MaxPrice        *int64   `json:"max_price"`
+	Sections        []string `json:"sections"`         // Only these sections (any if empty)
+	Accessible      bool     `json:"accessible"`       // The block must include a wheelchair space
+	PreferAisle     bool     `json:"prefer_aisle"`     // Rank blocks that start or end at an aisle first
+	AllowObstructed bool     `json:"allow_obstructed"` // Obstructed-view seats are skipped unless set
+}
+
+// HeldBlock is a set of adjacent seats held for the caller until HeldUntil
+type HeldBlock struct {
+	EventID   int32         `json:"event_id"`
+	Section   string        `json:"section"`
+	Row       string        `json:"row"`
+	Score     float64       `json:"score"`
+	HeldUntil time.Time     `json:"held_until"`
+	Seats     []OfferedSeat `json:"seats"`
+}
+
+type OfferedSeat struct {
+	ID         int32    `json:"id"`
+	Number     int32    `json:"number"`
+	Attributes []string `json:"attributes"`
+	Currency   string   `json:"currency"`
+	FaceValue  int64    `json:"face_value"`
+}
+
+// HoldRequest is the body of POST /holds/release
+type HoldRequest struct {
+	EventID int32   `json:"event_id"`
+	SeatIDs []int32 `json:"seat_ids"`
+}
+
+// candidateSeat is one seat of the event with everything the finder ranks on
+type candidateSeat struct {
+	ID           int32
+	SeatNumber   int32
+	Section      string
+	RowNumber    string
+	Attributes   []string
+	Status       string
+	Quality      int32
+	SectionOrder int32
+	RowOrder     int32
+	Currency     string
+	FaceValue    int64
+}
+
+func (s candidateSeat) has(attr string) bool {
+	for _, a := range s.Attributes {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// block is a run of adjacent seats in one row
+type block struct {
+	seats []candidateSeat
+	score float64
+}
+
+// findBlocks returns every block of req.Quantity adjacent, matching seats, best first.
+// candidates must be sorted by section, row and seat number, and include taken seats so
+// that gaps are not mistaken for neighbours.
+func findBlocks(candidates []candidateSeat, req BestAvailableRequest) []block {
+	sections := make(map[string]bool, len(req.Sections))
+	for _, name := range req.Sections {
+		sections[name] = true
+	}
+	usable := func(s candidateSeat) bool {
+		switch {
+		case s.Status != SeatAvailable:
+			return false
+		case len(sections) > 0 && !sections[s.Section]:
+			return false
+		case req.MinPrice != nil && s.FaceValue < *req.MinPrice:
+			return false
+		case req.MaxPrice != nil && s.FaceValue > *req.MaxPrice:
+			return false
+		case !req.AllowObstructed && s.has(seats.AttrObstructedView):
+			return false
+		}
+		return true
+	}
+
+	var blocks []block
+	for start := 0; start < len(candidates); {
+		// 1. One row at a time
+		end := start
+		for end < len(candidates) && candidates[end].Section == candidates[start].Section && candidates[end].RowNumber == candidates[start].RowNumber {
+			end++
+		}
+		row := candidates[start:end]
+		start = end
+
+		// 2. Slide a window over the row. Two aisle seats next to each other are on
+		// opposite sides of the aisle, so a block can't span them.
+		center := float64(len(row)-1) / 2
+		for i := 0; i+req.Quantity <= len(row); i++ {
+			window := row[i : i+req.Quantity]
+			ok, accessible := true, false
+			for j, s := range window {
+				if !usable(s) || (j > 0 && s.has(seats.AttrAisle) && window[j-1].has(seats.AttrAisle)) {
+					ok = false
+					break
+				}
+				if s.has(seats.AttrWheelchair) {
+					accessible = true
+				}
+			}
+			if !ok || (req.Accessible && !accessible) {
+				continue
+			}
+
+			blocks = append(blocks, block{
+				seats: window,
+				score: scoreBlock(window, center, float64(i)+float64(req.Quantity-1)/2, req.PreferAisle),
+			})
+		}
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].score > blocks[j].score })
+	return blocks
+}
+
+// scoreBlock ranks a block: the section's quality dominates, then rows nearer the
+// front, then blocks nearer the middle of the row.
+func scoreBlock(window []candidateSeat, rowCenter, blockCenter float64, preferAisle bool) float64 {
+	first := window[0]
+	score := float64(first.Quality)*100 - float64(first.RowOrder) - math.Abs(rowCenter-blockCenter)/10
+	if preferAisle && (first.has(seats.AttrAisle) || window[len(window)-1].has(seats.AttrAisle)) {
+		score += 5
+	}
+	return math.Round(score*100) / 100
+}
@@ -0,0 +1,144 @@
+package venues
+
+import (
+	"testing"
+	"ticketmaster/internals/seats"
+)
+
+// layout builds candidates the way candidateSeats returns them: sorted, taken seats included
+func layout() []candidateSeat {
+	var out []candidateSeat
+	id := int32(0)
+	add := func(section, row string, quality, rowOrder int32, price int64, numbers int, taken map[int32]bool, attrs map[int32][]string) {
+		for n := int32(1); n <= int32(numbers); n++ {
+			id++
+			status := SeatAvailable
+			if taken[n] {
+				status = SeatBooked
+			}
+			out = append(out, candidateSeat{
+				ID: id, SeatNumber: n, Section: section, RowNumber: row, Attributes: attrs[n], Status: status,
+				Quality: quality, RowOrder: rowOrder, Currency: "USD", FaceValue: price,
+			})
+		}
+	}
+	add("Floor", "A", 80, 0, 9000, 6, map[int32]bool{3: true}, map[int32][]string{1: {seats.AttrAisle}, 6: {seats.AttrAisle}})
+	add("Floor", "B", 80, 1, 9000, 4, nil, map[int32][]string{2: {seats.AttrWheelchair}})
+	add("Balcony", "A", 40, 0, 3000, 4, nil, map[int32][]string{2: {seats.AttrAisle}, 3: {seats.AttrAisle}, 4: {seats.AttrObstructedView}})
+	return out
+}
+
+func numbers(b block) (section, row string, nums []int32) {
+	for _, s := range b.seats {
+		nums = append(nums, s.SeatNumber)
+	}
+	return b.seats[0].Section, b.seats[0].RowNumber, nums
+}
+
+func TestFindBlocksRanking(t *testing.T) {
+	blocks := findBlocks(layout(), BestAvailableRequest{Quantity: 2})
+	if len(blocks) == 0 {
+		t.Fatal("no blocks found")
+	}
+
+	// Best section, front row, nearest the middle of the row
+	if section, row, nums := numbers(blocks[0]); section != "Floor" || row != "A" || nums[0] != 4 || nums[1] != 5 {
+		t.Errorf("best block = %s %s %v, want Floor A [4 5]", section, row, nums)
+	}
+
+	seenBalcony := false
+	for _, b := range blocks {
+		section, row, nums := numbers(b)
+		if section == "Floor" && row == "A" && (nums[0] == 3 || nums[1] == 3) {
+			t.Errorf("block %v includes the booked seat", nums)
+		}
+		if section == "Balcony" {
+			seenBalcony = true
+			if nums[0] == 2 && nums[1] == 3 {
+				t.Error("block spans two aisle seats, i.e. crosses the aisle")
+			}
+			if nums[1] == 4 {
+				t.Error("obstructed seat offered without allow_obstructed")
+			}
+		} else if seenBalcony {
+			t.Errorf("Floor block %v ranked after a Balcony block", nums)
+		}
+	}
+}
+
+func TestFindBlocksFilters(t *testing.T) {
+	minFloor, maxBalcony := int64(5000), int64(5000)
+	tests := []struct {
+		name  string
+		req   BestAvailableRequest
+		check func(t *testing.T, blocks []block)
+	}{
+		{"prefer aisle", BestAvailableRequest{Quantity: 2, PreferAisle: true}, func(t *testing.T, blocks []block) {
+			if section, row, nums := numbers(blocks[0]); section != "Floor" || row != "A" || (nums[0] != 1 && nums[1] != 6) {
+				t.Errorf("best block = %s %s %v, want one at an aisle of Floor A", section, row, nums)
+			}
+		}},
+		{"accessible", BestAvailableRequest{Quantity: 2, Accessible: true}, func(t *testing.T, blocks []block) {
+			if len(blocks) != 2 {
+				t.Fatalf("got %d blocks, want the two around B2", len(blocks))
+			}
+			for _, b := range blocks {
+				if _, row, _ := numbers(b); row != "B" {
+					t.Errorf("block in row %s has no wheelchair space", row)
+				}
+			}
+		}},
+		{"max price", BestAvailableRequest{Quantity: 2, MaxPrice: &maxBalcony}, func(t *testing.T, blocks []block) {
+			for _, b := range blocks {
+				if section, _, _ := numbers(b); section != "Balcony" {
+					t.Errorf("block in %s is over the max price", section)
+				}
+			}
+		}},
+		{"min price and sections", BestAvailableRequest{Quantity: 2, MinPrice: &minFloor, Sections: []string{"Balcony"}}, func(t *testing.T, blocks []block) {
+			if len(blocks) != 0 {
+				t.Errorf("got %d blocks, want none", len(blocks))
+			}
+		}},
+		{"allow obstructed", BestAvailableRequest{Quantity: 2, Sections: []string{"Balcony"}, AllowObstructed: true}, func(t *testing.T, blocks []block) {
+			found := false
+			for _, b := range blocks {
+				if _, _, nums := numbers(b); nums[1] == 4 {
+					found = true
+				}
+			}
+			if !found {
+				t.Error("obstructed seat not offered although allowed")
+			}
+		}},
+		{"too many", BestAvailableRequest{Quantity: 7}, func(t *testing.T, blocks []block) {
+			if len(blocks) != 0 {
+				t.Errorf("got %d blocks, no row has 7 seats", len(blocks))
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, findBlocks(layout(), tt.req))
+		})
+	}
+}
+
+func TestScoreBlock(t *testing.T) {
+	front := []candidateSeat{{Quality: 80, RowOrder: 0}, {Quality: 80, RowOrder: 0}}
+	back := []candidateSeat{{Quality: 80, RowOrder: 9}, {Quality: 80, RowOrder: 9}}
+	worse := []candidateSeat{{Quality: 79, RowOrder: 0}, {Quality: 79, RowOrder: 0}}
+
+	if scoreBlock(front, 5, 5, false) <= scoreBlock(back, 5, 5, false) {
+		t.Error("front row should beat a back row")
+	}
+	if scoreBlock(back, 5, 5, false) <= scoreBlock(worse, 5, 5, false) {
+		t.Error("section quality should dominate the row")
+	}
+	if scoreBlock(front, 5, 5, false) <= scoreBlock(front, 5, 1, false) {
+		t.Error("the middle of the row should beat the side")
+	}
+	if got := scoreBlock(front, 5, 4, false); got != 7999.9 {
+		t.Errorf("score = %v, want 7999.9", got)
+	}
+}
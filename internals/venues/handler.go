@@ -0,0 +1,394 @@
+package venues
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"ticketmaster/internals/bookings"
+	"ticketmaster/internals/cache"
+	"ticketmaster/internals/middleware"
+	"ticketmaster/internals/validation"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	repo       *Repository
+	redisStore *cache.RedisStore
+}
+
+func NewHandler(repo *Repository, redisStore *cache.RedisStore) *Handler {
+	return &Handler{repo: repo, redisStore: redisStore}
+}
+
+// CreateVenue handles POST /admin/venues
+func (h *Handler) CreateVenue(w http.ResponseWriter, r *http.Request) {
+	var req VenueCreationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	var errs validation.Errors
+	errs.Required("name", req.Name)
+	errs.MaxLength("name", req.Name, 200)
+	validateSections(&errs, req.Sections)
+	if errs.Respond(w) {
+		return
+	}
+
+	venue, err := h.repo.CreateVenue(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, ErrNameTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create venue", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(venue)
+}
+
+func validateSections(errs *validation.Errors, sections []SectionCreationRequest) {
+	names := make(map[string]bool)
+	for i := range sections {
+		s := &sections[i]
+		s.Name = strings.TrimSpace(s.Name)
+		field := "sections[" + strconv.Itoa(i) + "]"
+		errs.Required(field+".name", s.Name)
+		errs.MaxLength(field+".name", s.Name, 100)
+		errs.MaxLength(field+".level", s.Level, 100)
+		errs.Check(s.Quality == nil || (*s.Quality >= 0 && *s.Quality <= 100), field+".quality", "must be between 0 and 100")
+		errs.Check(!names[s.Name], field+".name", "is used by another section")
+		names[s.Name] = true
+
+		labels := make(map[string]bool)
+		for j, label := range s.Rows {
+			label = strings.TrimSpace(label)
+			s.Rows[j] = label
+			rowField := field + ".rows[" + strconv.Itoa(j) + "]"
+			errs.Required(rowField, label)
+			errs.MaxLength(rowField, label, 10)
+			errs.Check(!labels[label], rowField, "is used by another row of the section")
+			labels[label] = true
+		}
+	}
+}
+
+// GetVenues handles GET /venues
+func (h *Handler) GetVenues(w http.ResponseWriter, r *http.Request) {
+	venues, err := h.repo.GetAll(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch venues", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(venues)
+}
+
+// GetVenue handles GET /venues/{id}
+func (h *Handler) GetVenue(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "Invalid venue id")
+	if !ok {
+		return
+	}
+
+	venue, err := h.repo.GetVenue(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrVenueNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch venue", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(venue)
+}
+
+// GetSeatmap handles GET /events/{id}/seatmap
+func (h *Handler) GetSeatmap(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	eventID, ok := idParam(w, r, "Invalid event id")
+	if !ok {
+		return
+	}
+
+	// 1. Geometry and stored status
+	seatmap, err := h.repo.Seatmap(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, ErrEventNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch seat map", http.StatusInternalServerError)
+		return
+	}
+
+	// 2. Overlay seats that are locked by a booking in progress
+	var available []int32
+	for _, section := range seatmap.Sections {
+		for _, row := range section.Rows {
+			for _, seat := range row.Seats {
+				if seat.Status == SeatAvailable {
+					available = append(available, seat.ID)
+				}
+			}
+		}
+	}
+	held, err := h.redisStore.HeldSeats(ctx, available)
+	if err != nil {
+		http.Error(w, "Failed to fetch seat status", http.StatusServiceUnavailable)
+		return
+	}
+
+	seatmap.Counts = map[string]int{SeatAvailable: 0, SeatHeld: 0, SeatBooked: 0}
+	for i := range seatmap.Sections {
+		for j := range seatmap.Sections[i].Rows {
+			seats := seatmap.Sections[i].Rows[j].Seats
+			for k := range seats {
+				if seats[k].Status == SeatAvailable && held[seats[k].ID] {
+					seats[k].Status = SeatHeld
+				}
+				seatmap.Counts[seats[k].Status]++
+			}
+		}
+	}
+
+	// 3. Status changes by the second: never serve a cached copy
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(seatmap)
+}
+
+// FindBestAvailable handles POST /events/{id}/best-available.
+// It picks the best block of adjacent seats that matches the request and holds it for the caller.
+func (h *Handler) FindBestAvailable(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	eventID, ok := idParam(w, r, "Invalid event id")
+	if !ok {
+		return
+	}
+	userID, ok := ctx.Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var req BestAvailableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var errs validation.Errors
+	errs.Check(req.Quantity >= 1 && req.Quantity <= MaxBestAvailableQuantity, "quantity",
+		"must be between 1 and "+strconv.Itoa(MaxBestAvailableQuantity))
+	errs.Check(req.MinPrice == nil || *req.MinPrice >= 0, "min_price", "cannot be negative")
+	errs.Check(req.MinPrice == nil || req.MaxPrice == nil || *req.MinPrice <= *req.MaxPrice, "max_price", "must not be below min_price")
+	if errs.Respond(w) {
+		return
+	}
+
+	// 1. The event must be on sale and allow this many tickets per buyer
+	limit, err := h.repo.EventForSale(ctx, eventID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrEventNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrEventNotOnSale):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to load event", http.StatusInternalServerError)
+		}
+		return
+	}
+	if limit != nil && int32(req.Quantity) > *limit {
+		errs.Add("quantity", "exceeds the limit of "+strconv.Itoa(int(*limit))+" tickets per buyer")
+		errs.Respond(w)
+		return
+	}
+
+	// 2. Every seat, with the ones locked in Redis right now marked as held
+	candidates, err := h.repo.candidateSeats(ctx, eventID)
+	if err != nil {
+		http.Error(w, "Failed to load seats", http.StatusInternalServerError)
+		return
+	}
+	var available []int32
+	for _, s := range candidates {
+		if s.Status == SeatAvailable {
+			available = append(available, s.ID)
+		}
+	}
+	held, err := h.redisStore.HeldSeats(ctx, available)
+	if err != nil {
+		http.Error(w, "Failed to fetch seat status", http.StatusServiceUnavailable)
+		return
+	}
+	for i := range candidates {
+		if held[candidates[i].ID] {
+			candidates[i].Status = SeatHeld
+		}
+	}
+
+	// 3. Held seats count toward the per-buyer limit along with the ones already bought,
+	// so holds can't be used to keep more seats off sale than the buyer may purchase
+	hold, err := h.holder(ctx, eventID, userID, limit)
+	if err != nil {
+		http.Error(w, "Failed to check the purchase limit", http.StatusServiceUnavailable)
+		return
+	}
+
+	// 4. Hold the best block. Someone may beat us to a seat between the read and the hold:
+	// then skip every block containing it and try the next one.
+	taken := make(map[int32]bool)
+	attempts := 0
+	for _, b := range findBlocks(candidates, req) {
+		if attempts == maxHoldAttempts {
+			break
+		}
+		if containsAny(b.seats, taken) {
+			continue
+		}
+		attempts++
+
+		ids := make([]int32, len(b.seats))
+		for i, s := range b.seats {
+			ids[i] = s.ID
+		}
+		seatID, err := hold(ids)
+		if errors.Is(err, cache.ErrSeatTaken) {
+			taken[seatID] = true
+			continue
+		}
+		if errors.Is(err, cache.ErrUserQuotaExhausted) {
+			http.Error(w, "Holding "+strconv.Itoa(req.Quantity)+" more seats would exceed the limit of "+
+				strconv.Itoa(int(*limit))+" tickets per buyer", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to hold seats", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(heldBlock(eventID, b))
+		return
+	}
+
+	http.Error(w, "No "+strconv.Itoa(req.Quantity)+" adjacent seats match the request", http.StatusConflict)
+}
+
+// holder returns the function that holds a block for the user: a plain hold, or one checked
+// against the user's purchase counter when the event has a limit. The counter is seeded from
+// Postgres the first time, as the booking handlers do.
+func (h *Handler) holder(ctx context.Context, eventID, userID int32, limit *int32) (func(ids []int32) (int32, error), error) {
+	ttl := int(HoldTTL.Seconds())
+	if limit == nil {
+		return func(ids []int32) (int32, error) {
+			return h.redisStore.HoldSeats(ctx, eventID, ids, userID, ttl)
+		}, nil
+	}
+
+	countKey := bookings.PurchaseCountKey(eventID, userID)
+	exists, err := h.redisStore.CounterExists(ctx, countKey)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		bought, err := h.repo.CountUserTickets(ctx, eventID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := h.redisStore.SeedCounter(ctx, countKey, bought); err != nil {
+			return nil, err
+		}
+	}
+	return func(ids []int32) (int32, error) {
+		return h.redisStore.HoldSeatsWithLimit(ctx, eventID, ids, userID, ttl, countKey, int64(*limit))
+	}, nil
+}
+
+func containsAny(seats []candidateSeat, ids map[int32]bool) bool {
+	for _, s := range seats {
+		if ids[s.ID] {
+			return true
+		}
+	}
+	return false
+}
+
+func heldBlock(eventID int32, b block) HeldBlock {
+	held := HeldBlock{
+		EventID:   eventID,
+		Section:   b.seats[0].Section,
+		Row:       b.seats[0].RowNumber,
+		Score:     b.score,
+		HeldUntil: time.Now().Add(HoldTTL).UTC(),
+		Seats:     make([]OfferedSeat, len(b.seats)),
+	}
+	for i, s := range b.seats {
+		held.Seats[i] = OfferedSeat{ID: s.ID, Number: s.SeatNumber, Attributes: s.Attributes, Currency: s.Currency, FaceValue: s.FaceValue}
+	}
+	return held
+}
+
+// ReleaseHolds handles POST /holds/release. Only the caller's own holds are dropped.
+func (h *Handler) ReleaseHolds(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int32)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var req HoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var errs validation.Errors
+	errs.Check(req.EventID > 0, "event_id", "is required")
+	errs.Check(len(req.SeatIDs) > 0, "seat_ids", "is required")
+	errs.Check(len(req.SeatIDs) <= MaxBestAvailableQuantity, "seat_ids", "too many seats")
+	if errs.Respond(w) {
+		return
+	}
+
+	// The seats' availability bits are looked up in the event's bitmap
+	ok, err := h.repo.SeatsOfEvent(r.Context(), req.EventID, req.SeatIDs)
+	if err != nil {
+		http.Error(w, "Failed to load seats", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		errs.Add("seat_ids", "must all be seats of the event")
+		errs.Respond(w)
+		return
+	}
+
+	released, err := h.redisStore.ReleaseSeats(r.Context(), req.EventID, req.SeatIDs, userID)
+	if err != nil {
+		http.Error(w, "Failed to release seats", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"released": released})
+}
+
+func idParam(w http.ResponseWriter, r *http.Request, message string) (int32, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		http.Error(w, message, http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(id), true
+}
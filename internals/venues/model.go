@@ -0,0 +1,90 @@
+package venues
+
+import "time"
+
+// Seat map statuses. "held" is not stored: it is derived from the in-flight booking lock in Redis.
+const (
+	SeatAvailable = "available"
+	SeatHeld      = "held"
+	SeatBooked    = "booked"
+	SeatCancelled = "cancelled"
+)
+
+// DefaultQuality is the score of sections created without one, and of seats outside any layout
+const DefaultQuality = 50
+
+type Venue struct {
+	ID        int32     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Sections  []Section `json:"sections,omitempty" db:"-"`
+}
+
+type Section struct {
+	ID        int32  `json:"id"`
+	VenueID   int32  `json:"venue_id"`
+	Name      string `json:"name"`
+	Level     string `json:"level"`
+	Quality   int32  `json:"quality"` // 0 (worst) to 100 (best)
+	SortOrder int32  `json:"sort_order"`
+	Rows      []Row  `json:"rows" db:"-"`
+}
+
+type Row struct {
+	ID        int32  `json:"id"`
+	SectionID int32  `json:"section_id"`
+	Label     string `json:"label"`
+	SortOrder int32  `json:"sort_order"`
+}
+
+// VenueCreationRequest creates a venue with its whole layout. Sections and rows are
+// ordered as given.
+type VenueCreationRequest struct {
+	Name     string                   `json:"name"`
+	Sections []SectionCreationRequest `json:"sections"`
+}
+
+type SectionCreationRequest struct {
+	Name    string   `json:"name"`
+	Level   string   `json:"level"`
+	Quality *int32   `json:"quality"` // Defaults to DefaultQuality
+	Rows    []string `json:"rows"`    // Row labels, front to back
+}
+
+// Seatmap is returned by GET /events/{id}/seatmap
+type Seatmap struct {
+	EventID   int32            `json:"event_id"`
+	EventName string           `json:"event_name"`
+	Venue     *VenueSummary    `json:"venue"`
+	Sections  []SeatmapSection `json:"sections"`
+	Counts    map[string]int   `json:"counts"` // Seats per status
+}
+
+type VenueSummary struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+}
+
+// SeatmapSection and SeatmapRow carry no id for seats that were created without a venue row
+type SeatmapSection struct {
+	ID    *int32       `json:"id"`
+	Name  string       `json:"name"`
+	Level string       `json:"level"`
+	Rows  []SeatmapRow `json:"rows"`
+}
+
+type SeatmapRow struct {
+	ID    *int32        `json:"id"`
+	Label string        `json:"label"`
+	Seats []SeatmapSeat `json:"seats"`
+}
+
+type SeatmapSeat struct {
+	ID         int32    `json:"id"`
+	Number     int32    `json:"number"`
+	X          *float64 `json:"x"`
+	Y          *float64 `json:"y"`
+	Attributes []string `json:"attributes"`
+	TierID     *int32   `json:"tier_id"`
+	Status     string   `json:"status"`
+}
@@ -0,0 +1,275 @@
+package venues
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ticketmaster/internals/bookings"
+	database "ticketmaster/internals/db"
+	"ticketmaster/internals/pricing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrVenueNotFound  = errors.New("venue not found")
+	ErrEventNotFound  = errors.New("event not found")
+	ErrNameTaken      = errors.New("a venue with this name already exists")
+	ErrEventNotOnSale = errors.New("event is not on sale")
+)
+
+type Repository struct {
+	db *database.DB
+}
+
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateVenue writes the venue, its sections and their rows in one transaction
+func (r *Repository) CreateVenue(ctx context.Context, req VenueCreationRequest) (*Venue, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	venue := &Venue{Name: req.Name, Sections: []Section{}}
+	err = tx.QueryRow(ctx, `INSERT INTO venues (name) VALUES ($1) RETURNING id, created_at`, req.Name).
+		Scan(&venue.ID, &venue.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrNameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert venue: %w", err)
+	}
+
+	for i, s := range req.Sections {
+		section := Section{VenueID: venue.ID, Name: s.Name, Level: s.Level, Quality: DefaultQuality, SortOrder: int32(i), Rows: []Row{}}
+		if s.Quality != nil {
+			section.Quality = *s.Quality
+		}
+		err := tx.QueryRow(ctx,
+			`INSERT INTO venue_sections (venue_id, name, level, quality, sort_order) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			venue.ID, s.Name, s.Level, section.Quality, section.SortOrder,
+		).Scan(&section.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert section %q: %w", s.Name, err)
+		}
+		for j, label := range s.Rows {
+			row := Row{SectionID: section.ID, Label: label, SortOrder: int32(j)}
+			err := tx.QueryRow(ctx,
+				`INSERT INTO venue_rows (section_id, label, sort_order) VALUES ($1, $2, $3) RETURNING id`,
+				section.ID, label, row.SortOrder,
+			).Scan(&row.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to insert row %q of section %q: %w", label, s.Name, err)
+			}
+			section.Rows = append(section.Rows, row)
+		}
+		venue.Sections = append(venue.Sections, section)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return venue, nil
+}
+
+func (r *Repository) GetAll(ctx context.Context) ([]Venue, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT id, name, created_at FROM venues ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[Venue])
+}
+
+// GetVenue loads a venue with its full layout
+func (r *Repository) GetVenue(ctx context.Context, id int32) (*Venue, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT id, name, created_at FROM venues WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	venue, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[Venue])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVenueNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.Pool.Query(ctx,
+		`SELECT id, venue_id, name, level, quality, sort_order FROM venue_sections WHERE venue_id = $1 ORDER BY sort_order, id`, id)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := pgx.CollectRows(rows, pgx.RowToStructByName[Section])
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.Pool.Query(ctx, `
+		SELECT vr.id, vr.section_id, vr.label, vr.sort_order
+		FROM venue_rows vr JOIN venue_sections vs ON vs.id = vr.section_id
+		WHERE vs.venue_id = $1
+		ORDER BY vr.sort_order, vr.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	venueRows, err := pgx.CollectRows(rows, pgx.RowToStructByName[Row])
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[int32]int, len(sections))
+	for i := range sections {
+		sections[i].Rows = []Row{}
+		index[sections[i].ID] = i
+	}
+	for _, row := range venueRows {
+		i := index[row.SectionID]
+		sections[i].Rows = append(sections[i].Rows, row)
+	}
+	venue.Sections = sections
+	return venue, nil
+}
+
+// seatmapSeat is one seat joined with its place in the layout
+type seatmapSeat struct {
+	ID         int32
+	SeatNumber int32
+	X          *float64
+	Y          *float64
+	Attributes []string
+	TierID     *int32
+	Status     string
+	Section    string
+	RowNumber  string
+	SectionID  *int32
+	Level      *string
+	RowID      *int32
+}
+
+// Seatmap loads every seat of the event grouped by section and row. Seats that were
+// created before the event had a layout are grouped by their section and row_number text.
+func (r *Repository) Seatmap(ctx context.Context, eventID int32) (*Seatmap, error) {
+	seatmap := &Seatmap{EventID: eventID, Sections: []SeatmapSection{}}
+
+	var venueID *int32
+	var venueName *string
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT e.name, e.venue_id, v.name
+		FROM events e LEFT JOIN venues v ON v.id = e.venue_id
+		WHERE e.id = $1`, eventID).Scan(&seatmap.EventName, &venueID, &venueName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if venueID != nil {
+		seatmap.Venue = &VenueSummary{ID: *venueID, Name: *venueName}
+	}
+
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT s.id, s.seat_number, s.x, s.y, s.attributes, s.tier_id, s.status, s.section, s.row_number,
+		       vs.id AS section_id, vs.level, vr.id AS row_id
+		FROM seats s
+		LEFT JOIN venue_rows vr ON vr.id = s.row_id
+		LEFT JOIN venue_sections vs ON vs.id = vr.section_id
+		WHERE s.event_id = $1
+		ORDER BY vs.sort_order NULLS LAST, s.section, vr.sort_order NULLS LAST, s.row_number, s.seat_number`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	seats, err := pgx.CollectRows(rows, pgx.RowToStructByName[seatmapSeat])
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows arrive sorted, so a new section or row starts whenever the name changes
+	for _, s := range seats {
+		n := len(seatmap.Sections)
+		if n == 0 || seatmap.Sections[n-1].Name != s.Section {
+			section := SeatmapSection{ID: s.SectionID, Name: s.Section, Rows: []SeatmapRow{}}
+			if s.Level != nil {
+				section.Level = *s.Level
+			}
+			seatmap.Sections = append(seatmap.Sections, section)
+			n++
+		}
+		section := &seatmap.Sections[n-1]
+
+		m := len(section.Rows)
+		if m == 0 || section.Rows[m-1].Label != s.RowNumber {
+			section.Rows = append(section.Rows, SeatmapRow{ID: s.RowID, Label: s.RowNumber, Seats: []SeatmapSeat{}})
+			m++
+		}
+		row := &section.Rows[m-1]
+		row.Seats = append(row.Seats, SeatmapSeat{
+			ID:         s.ID,
+			Number:     s.SeatNumber,
+			X:          s.X,
+			Y:          s.Y,
+			Attributes: s.Attributes,
+			TierID:     s.TierID,
+			Status:     s.Status,
+		})
+	}
+	return seatmap, nil
+}
+
+// EventForSale returns the per-buyer ticket limit of an event that can still be sold
+func (r *Repository) EventForSale(ctx context.Context, eventID int32) (*int32, error) {
+	var status string
+	var limit *int32
+	err := r.db.Pool.QueryRow(ctx, `SELECT status, max_tickets_per_user FROM events WHERE id = $1`, eventID).Scan(&status, &limit)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == "cancelled" {
+		return nil, ErrEventNotOnSale
+	}
+	return limit, nil
+}
+
+// CountUserTickets seeds the shared purchase counter, see bookings.PurchaseCountKey
+func (r *Repository) CountUserTickets(ctx context.Context, eventID, userID int32) (int64, error) {
+	return bookings.CountUserTickets(ctx, r.db.Pool, eventID, userID)
+}
+
+// candidateSeats loads every seat of the event in row order, taken ones included, with
+// its section quality and face value (seat tier, then section tier, then the legacy price).
+func (r *Repository) candidateSeats(ctx context.Context, eventID int32) ([]candidateSeat, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT s.id, s.seat_number, s.section, s.row_number, s.attributes, s.status,
+		       COALESCE(vs.quality, $2) AS quality,
+		       COALESCE(vs.sort_order, 0) AS section_order,
+		       COALESCE(vr.sort_order, 0) AS row_order,
+		       COALESCE(t.currency, $3) AS currency,
+		       COALESCE(t.face_value, s.price::BIGINT * 100) AS face_value
+		FROM seats s
+		LEFT JOIN venue_rows vr ON vr.id = s.row_id
+		LEFT JOIN venue_sections vs ON vs.id = vr.section_id
+		LEFT JOIN section_price_tiers st ON st.event_id = s.event_id AND st.section = s.section
+		LEFT JOIN price_tiers t ON t.id = COALESCE(s.tier_id, st.tier_id)
+		WHERE s.event_id = $1 AND s.status <> 'cancelled'
+		ORDER BY vs.sort_order NULLS LAST, s.section, vr.sort_order NULLS LAST, s.row_number, s.seat_number`,
+		eventID, DefaultQuality, pricing.DefaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[candidateSeat])
+}
+
+// SeatsOfEvent reports whether every one of the seats belongs to the event
+func (r *Repository) SeatsOfEvent(ctx context.Context, eventID int32, seatIDs []int32) (bool, error) {
+	var n int
+	err := r.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM seats WHERE event_id = $1 AND id = ANY($2)`, eventID, seatIDs).Scan(&n)
+	return n == len(seatIDs), err
+}
@@ -0,0 +1,68 @@
+package venues
+
+import (
+	"context"
+	"testing"
+	"ticketmaster/internals/db/dbtest"
+)
+
+func TestCandidateSeats(t *testing.T) {
+	db := dbtest.New(t)
+
+	venueID := dbtest.ID(t, db, `INSERT INTO venues (name) VALUES ('Arena') RETURNING id`)
+	balcony := dbtest.ID(t, db, `INSERT INTO venue_sections (venue_id, name, sort_order, quality) VALUES ($1, 'Balcony', 1, 40) RETURNING id`, venueID)
+	floor := dbtest.ID(t, db, `INSERT INTO venue_sections (venue_id, name, sort_order, quality) VALUES ($1, 'Floor', 0, 90) RETURNING id`, venueID)
+	floorA := dbtest.ID(t, db, `INSERT INTO venue_rows (section_id, label, sort_order) VALUES ($1, 'A', 0) RETURNING id`, floor)
+	balconyA := dbtest.ID(t, db, `INSERT INTO venue_rows (section_id, label, sort_order) VALUES ($1, 'A', 0) RETURNING id`, balcony)
+	eventID := dbtest.ID(t, db, `INSERT INTO events (name, starts_at, venue_id) VALUES ('Gig', NOW() + INTERVAL '1 day', $1) RETURNING id`, venueID)
+	tierID := dbtest.ID(t, db, `INSERT INTO price_tiers (event_id, name, currency, face_value) VALUES ($1, 'Floor', 'EUR', 12000) RETURNING id`, eventID)
+	dbtest.Exec(t, db, `INSERT INTO section_price_tiers (event_id, section, tier_id) VALUES ($1, 'Floor', $2)`, eventID, tierID)
+
+	seat := func(section, row string, rowID int32, number int, status string) {
+		dbtest.Exec(t, db, `
+			INSERT INTO seats (row_number, seat_number, status, price, event_id, section, row_id, attributes)
+			VALUES ($1, $2, $3, 40, $4, $5, $6, '{aisle}')`, row, number, status, eventID, section, rowID)
+	}
+	seat("Balcony", "A", balconyA, 2, "available")
+	seat("Balcony", "A", balconyA, 1, "booked")
+	seat("Floor", "A", floorA, 2, "available")
+	seat("Floor", "A", floorA, 1, "available")
+	seat("Floor", "A", floorA, 3, "cancelled")
+	// A seat outside any layout still gets the defaults
+	dbtest.Exec(t, db, `INSERT INTO seats (row_number, seat_number, price, event_id, section) VALUES ('Z', 1, 40, $1, 'Standing')`, eventID)
+
+	got, err := NewRepository(db).candidateSeats(context.Background(), eventID)
+	if err != nil {
+		t.Fatalf("candidateSeats: %v", err)
+	}
+
+	type want struct {
+		section      string
+		number       int32
+		status       string
+		quality      int32
+		sectionOrder int32
+		currency     string
+		faceValue    int64
+	}
+	wants := []want{
+		{"Floor", 1, SeatAvailable, 90, 0, "EUR", 12000},
+		{"Floor", 2, SeatAvailable, 90, 0, "EUR", 12000},
+		{"Balcony", 1, SeatBooked, 40, 1, "USD", 4000},
+		{"Balcony", 2, SeatAvailable, 40, 1, "USD", 4000},
+		{"Standing", 1, SeatAvailable, DefaultQuality, 0, "USD", 4000},
+	}
+	if len(got) != len(wants) {
+		t.Fatalf("got %d seats, want %d (cancelled seats left out): %+v", len(got), len(wants), got)
+	}
+	for i, w := range wants {
+		s := got[i]
+		if s.Section != w.section || s.SeatNumber != w.number || s.Status != w.status || s.Quality != w.quality ||
+			s.SectionOrder != w.sectionOrder || s.Currency != w.currency || s.FaceValue != w.faceValue {
+			t.Errorf("seat %d = %+v, want %+v", i, s, w)
+		}
+	}
+	if len(got[0].Attributes) != 1 || got[0].Attributes[0] != "aisle" {
+		t.Errorf("attributes = %v, want [aisle]", got[0].Attributes)
+	}
+}